@@ -0,0 +1,376 @@
+// contest は競技で想定されるアクセスパターンを再現してバックエンドに送り、
+// スコアとレイテンシの集計を表示するトラフィック生成ツール。
+//
+// loadtest が同時接続数を固定して最大スループットを測るのに対し、こちらは
+// 到着レートを指定して送る (オープンループ)。一覧のページは Zipf 分布で先頭ほど多く、
+// 検索キーワードは重み付きで選び、一定間隔でバーストを挟む。
+//
+// スコアは成功したリクエストの点数 (一覧・詳細 1 点、検索 3 点) の合計から
+// 失敗 1 件につき 10 点を引いたもので、エラー率が -max-error-rate を超えると失格とする。
+// ウォームアップ中のリクエストは集計しない。
+//
+//	go run ./cmd/contest -url http://localhost:9001 -rate 50 -d 60s
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// リクエスト種別ごとの点数と失敗時の減点
+var points = map[string]int{"list": 1, "search": 3, "detail": 1}
+
+const errorPenalty = 10
+
+type options struct {
+	baseURL      string
+	rate         float64
+	duration     time.Duration
+	warmup       time.Duration
+	burstEvery   time.Duration
+	burstLength  time.Duration
+	burstFactor  float64
+	workers      int
+	timeout      time.Duration
+	searchRatio  float64
+	detailRatio  float64
+	maxPage      int
+	zipfS        float64
+	maxID        int
+	limit        int
+	keywords     []weighted
+	columns      []string
+	maxErrorRate float64
+	seed         int64
+}
+
+// weighted は重み付きで選ぶ値
+type weighted struct {
+	value  string
+	weight int
+}
+
+type job struct {
+	kind   string
+	req    *http.Request
+	warmup bool
+}
+
+type result struct {
+	kind    string
+	latency time.Duration
+	ok      bool
+	warmup  bool
+	// 計測終了で打ち切られた
+	canceled bool
+}
+
+func main() {
+	opts, err := parseFlags()
+	if err != nil {
+		log.Fatal("[CONTEST FATAL] ", err)
+	}
+
+	log.Printf("[CONTEST] Target: %s, rate: %.1f/s, duration: %v (warmup %v), burst: x%.1f for %v every %v",
+		opts.baseURL, opts.rate, opts.duration, opts.warmup, opts.burstFactor, opts.burstLength, opts.burstEvery)
+
+	client := &http.Client{
+		Timeout: opts.timeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        opts.workers,
+			MaxIdleConnsPerHost: opts.workers,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.warmup+opts.duration)
+	defer cancel()
+
+	jobs := make(chan job)
+	results := make(chan result, opts.workers*4)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results <- do(client, j)
+			}
+		}()
+	}
+
+	// 送信側が詰まった分 (同時実行数の上限に達した分) は失敗として数える
+	skipped := map[string]int{}
+	go func() {
+		skipped = dispatch(ctx, opts, jobs)
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	byKind := map[string][]result{}
+	for res := range results {
+		// ウォームアップ中と終了時に打ち切られたリクエストは集計しない
+		if res.warmup || res.canceled {
+			continue
+		}
+		byKind[res.kind] = append(byKind[res.kind], res)
+	}
+
+	if !report(os.Stdout, byKind, skipped, opts) {
+		os.Exit(1)
+	}
+}
+
+func parseFlags() (*options, error) {
+	opts := &options{}
+	var keywords, columns string
+	flag.StringVar(&opts.baseURL, "url", "http://localhost:9001", "バックエンドのベース URL")
+	flag.Float64Var(&opts.rate, "rate", 50, "平常時の 1 秒あたりのリクエスト数")
+	flag.DurationVar(&opts.duration, "d", 60*time.Second, "計測時間 (ウォームアップを除く)")
+	flag.DurationVar(&opts.warmup, "warmup", 5*time.Second, "集計しないウォームアップ時間")
+	flag.DurationVar(&opts.burstEvery, "burst-every", 20*time.Second, "バーストの間隔 (0 で無効)")
+	flag.DurationVar(&opts.burstLength, "burst-length", 3*time.Second, "バーストの長さ")
+	flag.Float64Var(&opts.burstFactor, "burst-factor", 4, "バースト中のレートの倍率")
+	flag.IntVar(&opts.workers, "workers", 64, "同時に送信中にできるリクエストの上限")
+	flag.DurationVar(&opts.timeout, "timeout", 5*time.Second, "リクエストごとのタイムアウト (超えたら失敗)")
+	flag.Float64Var(&opts.searchRatio, "search-ratio", 0.3, "検索リクエストの割合")
+	flag.Float64Var(&opts.detailRatio, "detail-ratio", 0, "詳細リクエストの割合")
+	flag.IntVar(&opts.maxPage, "max-page", 50, "一覧・検索で要求する最大ページ")
+	flag.Float64Var(&opts.zipfS, "zipf-s", 1.2, "ページ分布の Zipf パラメータ (大きいほど先頭ページに偏る)")
+	flag.IntVar(&opts.maxID, "max-id", 25, "詳細で要求する最大の製品 ID")
+	flag.IntVar(&opts.limit, "limit", 10, "1 ページあたりの件数")
+	flag.StringVar(&keywords, "keywords", "Pro:30,Apple:25,ノート:15,ワイヤレス:15,Sony:10,Galaxy:5", "検索キーワードと重み (カンマ区切りの keyword:weight)")
+	flag.StringVar(&columns, "columns", "name,brand,category,description", "検索対象の列 (カンマ区切り)")
+	flag.Float64Var(&opts.maxErrorRate, "max-error-rate", 0.01, "これを超えるエラー率で失格とする")
+	flag.Int64Var(&opts.seed, "seed", 0, "乱数のシード (0 なら現在時刻)")
+	flag.Parse()
+
+	opts.baseURL = strings.TrimRight(opts.baseURL, "/")
+	if opts.rate <= 0 || opts.workers < 1 || opts.maxPage < 1 || opts.zipfS <= 1 {
+		return nil, fmt.Errorf("rate, workers and max-page must be positive and zipf-s must be greater than 1")
+	}
+	if opts.searchRatio < 0 || opts.detailRatio < 0 || opts.searchRatio+opts.detailRatio > 1 {
+		return nil, fmt.Errorf("search-ratio and detail-ratio must sum to at most 1")
+	}
+	if opts.seed == 0 {
+		opts.seed = time.Now().UnixNano()
+	}
+
+	var err error
+	if opts.keywords, err = parseWeighted(keywords); err != nil {
+		return nil, err
+	}
+	opts.columns = splitList(columns)
+	if opts.searchRatio > 0 && (len(opts.keywords) == 0 || len(opts.columns) == 0) {
+		return nil, fmt.Errorf("search requires keywords and columns")
+	}
+	return opts, nil
+}
+
+// parseWeighted は "Pro:30,Apple:25" 形式の重み付きリストを読み取る。重みを省略すると 1
+func parseWeighted(spec string) ([]weighted, error) {
+	var out []weighted
+	for _, entry := range splitList(spec) {
+		value, weight, ok := strings.Cut(entry, ":")
+		n := 1
+		if ok {
+			var err error
+			if n, err = strconv.Atoi(weight); err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid weighted entry: %q", entry)
+			}
+		}
+		out = append(out, weighted{value: value, weight: n})
+	}
+	return out, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func pickWeighted(rnd *rand.Rand, items []weighted) string {
+	total := 0
+	for _, it := range items {
+		total += it.weight
+	}
+	n := rnd.Intn(total)
+	for _, it := range items {
+		if n < it.weight {
+			return it.value
+		}
+		n -= it.weight
+	}
+	return items[len(items)-1].value
+}
+
+// currentRate は開始からの経過時間に応じた送信レートを返す
+func currentRate(opts *options, elapsed time.Duration) float64 {
+	if opts.burstEvery > 0 && elapsed%opts.burstEvery >= opts.burstEvery-opts.burstLength {
+		return opts.rate * opts.burstFactor
+	}
+	return opts.rate
+}
+
+// dispatch は指定レートでリクエストを組み立ててワーカーに渡す。
+// ワーカーが空いていなければ待たずに諦め、種別ごとの件数を返す
+func dispatch(ctx context.Context, opts *options, jobs chan<- job) map[string]int {
+	rnd := rand.New(rand.NewSource(opts.seed))
+	pages := rand.NewZipf(rnd, opts.zipfS, 1, uint64(opts.maxPage-1))
+	skipped := map[string]int{}
+
+	start := time.Now()
+	next := start
+	for {
+		elapsed := time.Since(start)
+		next = next.Add(time.Duration(float64(time.Second) / currentRate(opts, elapsed)))
+		select {
+		case <-ctx.Done():
+			return skipped
+		case <-time.After(time.Until(next)):
+		}
+
+		j, err := newJob(ctx, opts, rnd, int(pages.Uint64())+1)
+		if err != nil {
+			log.Printf("[CONTEST ERROR] Failed to build request: %v", err)
+			continue
+		}
+		j.warmup = time.Since(start) < opts.warmup
+
+		select {
+		case jobs <- j:
+		default:
+			if !j.warmup {
+				skipped[j.kind]++
+			}
+		}
+	}
+}
+
+func newJob(ctx context.Context, opts *options, rnd *rand.Rand, page int) (job, error) {
+	var req *http.Request
+	var err error
+	kind := "list"
+
+	switch p := rnd.Float64(); {
+	case p < opts.searchRatio:
+		kind = "search"
+		body, _ := json.Marshal(map[string]interface{}{
+			"column":  opts.columns[rnd.Intn(len(opts.columns))],
+			"keyword": pickWeighted(rnd, opts.keywords),
+			"page":    page,
+			"limit":   opts.limit,
+		})
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, opts.baseURL+"/api/search", bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	case p < opts.searchRatio+opts.detailRatio:
+		kind = "detail"
+		url := fmt.Sprintf("%s/api/products/%d", opts.baseURL, 1+rnd.Intn(opts.maxID))
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	default:
+		url := fmt.Sprintf("%s/api/products?page=%d&limit=%d", opts.baseURL, page, opts.limit)
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}
+	return job{kind: kind, req: req}, err
+}
+
+func do(client *http.Client, j job) result {
+	start := time.Now()
+	resp, err := client.Do(j.req)
+	if err != nil {
+		canceled := j.req.Context().Err() != nil
+		return result{kind: j.kind, latency: time.Since(start), warmup: j.warmup, canceled: canceled}
+	}
+	// 本文まで読み切った時間をレイテンシとする
+	_, err = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	ok := err == nil && resp.StatusCode < 400
+	return result{kind: j.kind, latency: time.Since(start), ok: ok, warmup: j.warmup}
+}
+
+// report は種別ごとの集計とスコアを出力し、失格でなければ true を返す
+func report(w io.Writer, byKind map[string][]result, skipped map[string]int, opts *options) bool {
+	kinds := make([]string, 0, len(byKind))
+	for kind := range byKind {
+		kinds = append(kinds, kind)
+	}
+	for kind := range skipped {
+		if _, ok := byKind[kind]; !ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+
+	score, total, failed := 0, 0, 0
+	fmt.Fprintf(w, "\n%-8s %8s %7s %7s %10s %10s %10s %7s\n", "kind", "requests", "errors", "skipped", "p50", "p90", "p99", "score")
+	for _, kind := range kinds {
+		results := byKind[kind]
+		errors := skipped[kind]
+		latencies := make([]time.Duration, 0, len(results))
+		for _, res := range results {
+			if !res.ok {
+				errors++
+			}
+			latencies = append(latencies, res.latency)
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+		n := len(results) + skipped[kind]
+		kindScore := (n-errors)*points[kind] - errors*errorPenalty
+		score += kindScore
+		total += n
+		failed += errors
+
+		fmt.Fprintf(w, "%-8s %8d %7d %7d %10v %10v %10v %7d\n", kind, n, errors-skipped[kind], skipped[kind],
+			percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99), kindScore)
+	}
+
+	errorRate := 0.0
+	if total > 0 {
+		errorRate = float64(failed) / float64(total)
+	}
+	passed := errorRate <= opts.maxErrorRate
+	if score < 0 {
+		score = 0
+	}
+
+	fmt.Fprintf(w, "\nrequests: %d, error rate: %.2f%%, seed: %d\n", total, errorRate*100, opts.seed)
+	if !passed {
+		fmt.Fprintf(w, "score: 0 (FAIL: error rate exceeds %.2f%%)\n", opts.maxErrorRate*100)
+		return false
+	}
+	fmt.Fprintf(w, "score: %d\n", score)
+	return true
+}
+
+// percentile はソート済みのレイテンシから p パーセンタイル値を返す
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := (len(sorted)*p+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx].Round(time.Microsecond)
+}
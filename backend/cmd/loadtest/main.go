@@ -0,0 +1,260 @@
+// loadtest は起動中のバックエンドに一覧・検索・詳細のリクエストを混ぜて送り、
+// レイテンシのパーセンタイルとスループットを表示する負荷試験ツール。
+//
+//	go run ./cmd/loadtest -url http://localhost:9001 -c 20 -d 30s -mix list=70,search=30
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type options struct {
+	baseURL     string
+	concurrency int
+	duration    time.Duration
+	mix         map[string]int
+	maxPage     int
+	maxID       int
+	limit       int
+	keywords    []string
+	columns     []string
+	timeout     time.Duration
+}
+
+// result は 1 リクエストの計測結果
+type result struct {
+	kind    string
+	latency time.Duration
+	status  int
+	err     error
+}
+
+func main() {
+	opts, err := parseFlags()
+	if err != nil {
+		log.Fatal("[LOADTEST FATAL] ", err)
+	}
+
+	log.Printf("[LOADTEST] Target: %s, concurrency: %d, duration: %v, mix: %v", opts.baseURL, opts.concurrency, opts.duration, opts.mix)
+
+	client := &http.Client{
+		Timeout: opts.timeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        opts.concurrency,
+			MaxIdleConnsPerHost: opts.concurrency,
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.duration)
+	defer cancel()
+
+	results := make(chan result, opts.concurrency*4)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.concurrency; i++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for ctx.Err() == nil {
+				results <- send(ctx, client, opts, pickKind(rnd, opts.mix), rnd)
+			}
+		}(time.Now().UnixNano() + int64(i))
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	start := time.Now()
+	byKind := map[string][]result{}
+	for res := range results {
+		// 終了時に打ち切られたリクエストは集計しない
+		if res.err != nil && ctx.Err() != nil {
+			continue
+		}
+		byKind[res.kind] = append(byKind[res.kind], res)
+	}
+
+	report(os.Stdout, byKind, time.Since(start))
+}
+
+func parseFlags() (*options, error) {
+	opts := &options{}
+	var mix, keywords, columns string
+	flag.StringVar(&opts.baseURL, "url", "http://localhost:9001", "バックエンドのベース URL")
+	flag.IntVar(&opts.concurrency, "c", 10, "同時接続数")
+	flag.DurationVar(&opts.duration, "d", 30*time.Second, "実行時間")
+	flag.StringVar(&mix, "mix", "list=70,search=30,detail=0", "リクエスト種別ごとの比率")
+	flag.IntVar(&opts.maxPage, "max-page", 5, "一覧で要求する最大ページ")
+	flag.IntVar(&opts.maxID, "max-id", 25, "詳細で要求する最大の製品 ID")
+	flag.IntVar(&opts.limit, "limit", 10, "1 ページあたりの件数")
+	flag.StringVar(&keywords, "keywords", "Pro,Apple,ノート,ワイヤレス,Sony", "検索キーワード (カンマ区切り)")
+	flag.StringVar(&columns, "columns", "name,brand,category,description", "検索対象の列 (カンマ区切り)")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "リクエストごとのタイムアウト")
+	flag.Parse()
+
+	opts.baseURL = strings.TrimRight(opts.baseURL, "/")
+	if opts.concurrency < 1 {
+		return nil, fmt.Errorf("concurrency must be positive")
+	}
+
+	var err error
+	if opts.mix, err = parseMix(mix); err != nil {
+		return nil, err
+	}
+	opts.keywords = splitList(keywords)
+	opts.columns = splitList(columns)
+	if opts.mix["search"] > 0 && (len(opts.keywords) == 0 || len(opts.columns) == 0) {
+		return nil, fmt.Errorf("search requires keywords and columns")
+	}
+	return opts, nil
+}
+
+// parseMix は "list=70,search=30" 形式の比率を読み取る
+func parseMix(spec string) (map[string]int, error) {
+	mix := map[string]int{}
+	total := 0
+	for _, entry := range splitList(spec) {
+		kind, weight, ok := strings.Cut(entry, "=")
+		n, err := strconv.Atoi(weight)
+		if !ok || err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid mix entry: %q", entry)
+		}
+		switch kind {
+		case "list", "search", "detail":
+		default:
+			return nil, fmt.Errorf("unknown request kind: %q", kind)
+		}
+		mix[kind] = n
+		total += n
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("mix must contain a positive weight")
+	}
+	return mix, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func pickKind(rnd *rand.Rand, mix map[string]int) string {
+	total := 0
+	for _, w := range mix {
+		total += w
+	}
+	n := rnd.Intn(total)
+	// map の順序に依存しないよう固定順で選ぶ
+	for _, kind := range []string{"list", "search", "detail"} {
+		if n < mix[kind] {
+			return kind
+		}
+		n -= mix[kind]
+	}
+	return "list"
+}
+
+func send(ctx context.Context, client *http.Client, opts *options, kind string, rnd *rand.Rand) result {
+	var req *http.Request
+	var err error
+
+	switch kind {
+	case "search":
+		body, _ := json.Marshal(map[string]interface{}{
+			"column":  opts.columns[rnd.Intn(len(opts.columns))],
+			"keyword": opts.keywords[rnd.Intn(len(opts.keywords))],
+			"page":    1 + rnd.Intn(opts.maxPage),
+			"limit":   opts.limit,
+		})
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, opts.baseURL+"/api/search", bytes.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	case "detail":
+		url := fmt.Sprintf("%s/api/products/%d", opts.baseURL, 1+rnd.Intn(opts.maxID))
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	default:
+		url := fmt.Sprintf("%s/api/products?page=%d&limit=%d", opts.baseURL, 1+rnd.Intn(opts.maxPage), opts.limit)
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}
+	if err != nil {
+		return result{kind: kind, err: err}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return result{kind: kind, latency: time.Since(start), err: err}
+	}
+	// 本文まで読み切った時間をレイテンシとする
+	_, err = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return result{kind: kind, latency: time.Since(start), status: resp.StatusCode, err: err}
+}
+
+func report(w io.Writer, byKind map[string][]result, elapsed time.Duration) {
+	kinds := make([]string, 0, len(byKind))
+	for kind := range byKind {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	var all []result
+	fmt.Fprintf(w, "\n%-8s %8s %7s %9s %10s %10s %10s %10s\n", "kind", "requests", "errors", "rps", "p50", "p90", "p99", "max")
+	for _, kind := range kinds {
+		all = append(all, byKind[kind]...)
+		printRow(w, kind, byKind[kind], elapsed)
+	}
+	printRow(w, "total", all, elapsed)
+	fmt.Fprintf(w, "\nelapsed: %v\n", elapsed.Round(time.Millisecond))
+}
+
+func printRow(w io.Writer, kind string, results []result, elapsed time.Duration) {
+	if len(results) == 0 {
+		return
+	}
+
+	errors := 0
+	latencies := make([]time.Duration, 0, len(results))
+	for _, res := range results {
+		if res.err != nil || res.status >= 400 {
+			errors++
+		}
+		latencies = append(latencies, res.latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	rps := float64(len(results)) / elapsed.Seconds()
+	fmt.Fprintf(w, "%-8s %8d %7d %9.1f %10v %10v %10v %10v\n", kind, len(results), errors, rps,
+		percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99), latencies[len(latencies)-1].Round(time.Microsecond))
+}
+
+// percentile はソート済みのレイテンシから p パーセンタイル値を返す
+func percentile(sorted []time.Duration, p int) time.Duration {
+	idx := (len(sorted)*p+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx].Round(time.Microsecond)
+}
@@ -1,33 +1,97 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/app"
+	"sample-backend/internal/auth"
 	"sample-backend/internal/config"
 	"sample-backend/internal/database"
-	"sample-backend/internal/server"
-	"sample-backend/internal/tracing"
+	"sample-backend/internal/fieldcrypt"
+	"sample-backend/internal/logging"
 )
 
 func main() {
+	// ログの形式は最初に決める (設定の読み込みのログも同じ形式にするため)
+	if err := logging.Setup(config.LogSettings()); err != nil {
+		log.Fatal("[MAIN FATAL] Invalid log settings: ", err)
+	}
 	log.Println("[MAIN] Starting product-search-backend server...")
 
 	// 設定読み込み
 	cfg := config.Load()
 
-	// トレーシング初期化
-	tracing.Init(cfg)
-
-	// データベース接続
-	db, err := database.Connect(cfg)
+	// DB・リポジトリ・サービス・ハンドラーの組み立て
+	a, err := app.New(cfg)
 	if err != nil {
-		log.Fatal("[MAIN FATAL] Failed to connect to database:", err)
+		log.Fatal("[MAIN FATAL] Failed to initialize application:", err)
 	}
-	defer db.Close()
 
-	// サーバー起動
-	srv := server.New(cfg, db)
-	if err := srv.Start(); err != nil {
+	// SIGHUP で資格情報を読み直す
+	go watchReload(cfg, a.DB, a.Keys)
+
+	// サーバー起動 (SIGTERM / SIGINT を受けたら処理中のリクエストを待ってから終了する)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	served := make(chan error, 1)
+	go func() { served <- a.Server.Start() }()
+
+	select {
+	case err := <-served:
+		a.Close()
 		log.Fatal("[MAIN FATAL] Server failed:", err)
+	case <-ctx.Done():
+		// 2 回目のシグナルではすぐに終了する
+		stop()
+		log.Println("[MAIN] Shutdown signal received")
+	}
+	if err := a.Shutdown(context.Background()); err != nil {
+		os.Exit(1)
+	}
+	log.Println("[MAIN] Server stopped")
+}
+
+// watchReload は SIGHUP を受けると設定を読み直し、変わった資格情報だけを差し替える。
+// 失敗した場合は古い資格情報のまま動き続ける
+func watchReload(cfg *config.Config, db *sqlx.DB, keys *auth.KeySet) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	current := cfg
+	for range hup {
+		log.Println("[MAIN] SIGHUP received, reloading credentials...")
+		next := config.Load()
+
+		if next.DatabaseURL != current.DatabaseURL {
+			if err := database.RotateCredentials(db, next.DatabaseURL); err != nil {
+				log.Printf("[MAIN ERROR] Failed to rotate database credentials: %v", err)
+				next.DatabaseURL = current.DatabaseURL
+			}
+		}
+
+		if keys != nil {
+			// 外した鍵で署名済みのトークンが失効するまでは検証できるようにする
+			if err := keys.Rotate(next.JWTKeys, next.JWTActiveKey, next.JWTTokenTTL); err != nil {
+				log.Printf("[MAIN ERROR] Failed to rotate JWT keys: %v", err)
+			}
+		}
+
+		if len(next.FieldEncryptionKeys) > 0 {
+			keyring, err := fieldcrypt.NewKeyring(next.FieldEncryptionKeys, next.FieldEncryptionActiveKey)
+			if err != nil {
+				log.Printf("[MAIN ERROR] Failed to reload field encryption keys: %v", err)
+			} else {
+				fieldcrypt.SetKeyring(keyring)
+			}
+		}
+
+		current = next
+		log.Println("[MAIN] Credentials reloaded")
 	}
 }
@@ -0,0 +1,352 @@
+// pagecheck は製品一覧をオフセット方式とカーソル方式のそれぞれで最後まで辿り、
+// 行の取りこぼしや重複がないかを検証するツール。
+//
+// -dsn を指定すると開始前の products の ID を基準として読み込み、辿っている間に
+// 別のゴルーチンから行の追加・削除を行う。基準に含まれ、途中で削除されなかった行は
+// ちょうど 1 回ずつ返らなければならない。追加した行は 0 回か 1 回であればよい。
+// 追加した行は終了時に削除する。-delete-existing を付けると既存の行も削除するので、
+// 検証用の DB でだけ使うこと。
+//
+// カーソル方式はレスポンスの next_cursor を cursor パラメータで渡して辿る。
+// サーバーが next_cursor を返さない場合はスキップする。
+//
+//	go run ./cmd/pagecheck -url http://localhost:9001 -dsn 'root:mysql@tcp(localhost:3306)/sample_db' -writers 2
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+type options struct {
+	baseURL        string
+	dsn            string
+	limit          int
+	writers        int
+	writeInterval  time.Duration
+	deleteExisting bool
+	pageDelay      time.Duration
+	timeout        time.Duration
+}
+
+// pageResponse は検証に必要なレスポンスの項目
+type pageResponse struct {
+	Products []struct {
+		ID int `json:"id"`
+	} `json:"products"`
+	TotalPages int    `json:"totalPages"`
+	Count      int    `json:"count"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// walkResult は 1 回分の走査結果
+type walkResult struct {
+	mode    string
+	pages   int
+	seen    map[int]int
+	elapsed time.Duration
+	skipped string
+	err     error
+}
+
+// writeLog は走査中に行った書き込み
+type writeLog struct {
+	mu       sync.Mutex
+	inserted map[int]bool
+	deleted  map[int]bool
+}
+
+func main() {
+	opts := &options{}
+	flag.StringVar(&opts.baseURL, "url", "http://localhost:9001", "バックエンドのベース URL")
+	flag.StringVar(&opts.dsn, "dsn", "", "基準の取得と同時書き込みに使う DSN (空なら書き込みなしで比較のみ)")
+	flag.IntVar(&opts.limit, "limit", 10, "1 ページあたりの件数")
+	flag.IntVar(&opts.writers, "writers", 1, "同時に書き込むゴルーチン数 (-dsn 指定時)")
+	flag.DurationVar(&opts.writeInterval, "write-interval", 20*time.Millisecond, "書き込みの間隔")
+	flag.BoolVar(&opts.deleteExisting, "delete-existing", false, "既存の行も削除する (検証用 DB 専用)")
+	flag.DurationVar(&opts.pageDelay, "page-delay", 0, "ページ取得の間隔 (書き込みと重なりやすくする)")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "リクエストごとのタイムアウト")
+	flag.Parse()
+	opts.baseURL = strings.TrimRight(opts.baseURL, "/")
+
+	client := &http.Client{Timeout: opts.timeout}
+
+	var db *sql.DB
+	var baseline map[int]bool
+	if opts.dsn != "" {
+		var err error
+		db, err = sql.Open("mysql", opts.dsn+"?charset=utf8mb4&parseTime=True&loc=Asia%2FTokyo")
+		if err != nil {
+			log.Fatal("[PAGECHECK FATAL] ", err)
+		}
+		defer db.Close()
+		if baseline, err = loadIDs(db); err != nil {
+			log.Fatal("[PAGECHECK FATAL] Failed to load baseline: ", err)
+		}
+		log.Printf("[PAGECHECK] Baseline: %d products", len(baseline))
+	}
+
+	writes := &writeLog{inserted: map[int]bool{}, deleted: map[int]bool{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	if db != nil {
+		for i := 0; i < opts.writers; i++ {
+			wg.Add(1)
+			go func(seed int64) {
+				defer wg.Done()
+				write(ctx, db, opts, writes, baseline, rand.New(rand.NewSource(seed)))
+			}(time.Now().UnixNano() + int64(i))
+		}
+	}
+
+	var results []walkResult
+	var rwg sync.WaitGroup
+	var mu sync.Mutex
+	for _, mode := range []string{"offset", "cursor"} {
+		rwg.Add(1)
+		go func(mode string) {
+			defer rwg.Done()
+			res := walk(client, opts, mode)
+			mu.Lock()
+			results = append(results, res)
+			mu.Unlock()
+		}(mode)
+	}
+	rwg.Wait()
+	cancel()
+	wg.Wait()
+
+	if db != nil {
+		cleanup(db, writes)
+	}
+
+	// 書き込みなしの場合は両方の走査で返った行の和集合を基準とする
+	if baseline == nil {
+		baseline = map[int]bool{}
+		for _, res := range results {
+			for id := range res.seen {
+				baseline[id] = true
+			}
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].mode > results[j].mode })
+	if !report(os.Stdout, results, baseline, writes) {
+		os.Exit(1)
+	}
+}
+
+func loadIDs(db *sql.DB) (map[int]bool, error) {
+	rows, err := db.Query("SELECT id FROM products")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// write は走査が終わるまで行の追加と削除を繰り返す
+func write(ctx context.Context, db *sql.DB, opts *options, writes *writeLog, baseline map[int]bool, rnd *rand.Rand) {
+	existing := make([]int, 0, len(baseline))
+	for id := range baseline {
+		existing = append(existing, id)
+	}
+
+	ticker := time.NewTicker(opts.writeInterval)
+	defer ticker.Stop()
+	for n := 0; ; n++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		switch {
+		case opts.deleteExisting && len(existing) > 0 && rnd.Intn(3) == 0:
+			id := existing[rnd.Intn(len(existing))]
+			if _, err := db.ExecContext(ctx, "DELETE FROM products WHERE id = ?", id); err != nil {
+				log.Printf("[PAGECHECK ERROR] Failed to delete product %d: %v", id, err)
+				continue
+			}
+			writes.mu.Lock()
+			writes.deleted[id] = true
+			writes.mu.Unlock()
+		default:
+			res, err := db.ExecContext(ctx,
+				"INSERT INTO products (name, category, brand, model, description, price) VALUES (?, 'pagecheck', 'pagecheck', 'pagecheck', 'pagecheck', 0)",
+				fmt.Sprintf("pagecheck-%d-%d", time.Now().UnixNano(), n))
+			if err != nil {
+				log.Printf("[PAGECHECK ERROR] Failed to insert product: %v", err)
+				continue
+			}
+			id, _ := res.LastInsertId()
+			writes.mu.Lock()
+			writes.inserted[int(id)] = true
+			writes.mu.Unlock()
+		}
+	}
+}
+
+// cleanup は走査中に追加した行を削除する
+func cleanup(db *sql.DB, writes *writeLog) {
+	n := 0
+	for id := range writes.inserted {
+		if _, err := db.Exec("DELETE FROM products WHERE id = ?", id); err != nil {
+			log.Printf("[PAGECHECK ERROR] Failed to clean up product %d: %v", id, err)
+			continue
+		}
+		n++
+	}
+	log.Printf("[PAGECHECK] Cleaned up %d inserted products", n)
+}
+
+func walk(client *http.Client, opts *options, mode string) walkResult {
+	res := walkResult{mode: mode, seen: map[int]int{}}
+	start := time.Now()
+	defer func() { res.elapsed = time.Since(start) }()
+
+	cursor := ""
+	for page := 1; ; page++ {
+		q := url.Values{}
+		q.Set("limit", fmt.Sprint(opts.limit))
+		if mode == "offset" {
+			q.Set("page", fmt.Sprint(page))
+		} else if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+
+		body, err := fetch(client, opts.baseURL+"/api/products?"+q.Encode())
+		if err != nil {
+			res.err = err
+			return res
+		}
+		res.pages++
+		for _, p := range body.Products {
+			res.seen[p.ID]++
+		}
+
+		if mode == "offset" {
+			if len(body.Products) == 0 || page >= body.TotalPages {
+				return res
+			}
+		} else {
+			if body.NextCursor == "" {
+				if page == 1 && body.Count > len(body.Products) {
+					res.skipped = "server did not return next_cursor"
+				}
+				return res
+			}
+			cursor = body.NextCursor
+		}
+
+		if opts.pageDelay > 0 {
+			time.Sleep(opts.pageDelay)
+		}
+	}
+}
+
+func fetch(client *http.Client, url string) (*pageResponse, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("GET %s: %d %s", url, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	var body pageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return &body, nil
+}
+
+// report は走査ごとの検証結果を出力し、すべて問題なければ true を返す
+func report(w io.Writer, results []walkResult, baseline map[int]bool, writes *writeLog) bool {
+	passed := true
+	fmt.Fprintf(w, "\ninserted: %d, deleted: %d\n", len(writes.inserted), len(writes.deleted))
+	fmt.Fprintf(w, "\n%-8s %6s %7s %10s %8s %10s %10s  %s\n", "mode", "pages", "rows", "duplicates", "missing", "unexpected", "elapsed", "result")
+
+	for _, res := range results {
+		if res.skipped != "" || res.err != nil {
+			reason := res.skipped
+			if res.err != nil {
+				reason = "ERROR: " + res.err.Error()
+				passed = false
+			}
+			fmt.Fprintf(w, "%-8s %6d %7s %10s %8s %10s %10v  %s\n", res.mode, res.pages, "-", "-", "-", "-", res.elapsed.Round(time.Millisecond), reason)
+			continue
+		}
+
+		var duplicates, missing, unexpected []int
+		rows := 0
+		for id, n := range res.seen {
+			rows += n
+			if n > 1 {
+				duplicates = append(duplicates, id)
+			}
+			if !baseline[id] && !writes.inserted[id] {
+				unexpected = append(unexpected, id)
+			}
+		}
+		for id := range baseline {
+			if res.seen[id] == 0 && !writes.deleted[id] {
+				missing = append(missing, id)
+			}
+		}
+
+		result := "OK"
+		if len(duplicates) > 0 || len(missing) > 0 || len(unexpected) > 0 {
+			result = "FAIL"
+			passed = false
+		}
+		fmt.Fprintf(w, "%-8s %6d %7d %10d %8d %10d %10v  %s\n", res.mode, res.pages, rows,
+			len(duplicates), len(missing), len(unexpected), res.elapsed.Round(time.Millisecond), result)
+		printIDs(w, res.mode, "duplicate", duplicates)
+		printIDs(w, res.mode, "missing", missing)
+		printIDs(w, res.mode, "unexpected", unexpected)
+	}
+	return passed
+}
+
+// printIDs は問題のあった ID を先頭 20 件まで出力する
+func printIDs(w io.Writer, mode, label string, ids []int) {
+	if len(ids) == 0 {
+		return
+	}
+	sort.Ints(ids)
+	shown := ids
+	if len(shown) > 20 {
+		shown = shown[:20]
+	}
+	fmt.Fprintf(w, "  %s %s ids: %v", mode, label, shown)
+	if len(ids) > len(shown) {
+		fmt.Fprintf(w, " ... (%d more)", len(ids)-len(shown))
+	}
+	fmt.Fprintln(w)
+}
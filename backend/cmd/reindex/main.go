@@ -0,0 +1,52 @@
+// reindex は検索用の非正規化テーブル (product_search) を products から作り直す。
+// サーバーの管理用 API (POST /admin/reindex) と同じ処理を、サーバーを経由せずに実行する。
+// 実行中もサーバーは古いテーブルを読み続け、最後に入れ替える。
+//
+//	go run ./cmd/reindex -dsn 'root:mysql@tcp(localhost:3306)/sample_db' -chunk 10000 -c 4
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/reindex"
+)
+
+func main() {
+	dsn := flag.String("dsn", "root:mysql@tcp(localhost:3306)/sample_db", "対象の DSN")
+	chunk := flag.Int("chunk", 10000, "1 回のコピーで扱う ID の幅")
+	concurrency := flag.Int("c", 4, "同時に実行するコピーの数")
+	timeout := flag.Duration("timeout", time.Hour, "全体のタイムアウト")
+	flag.Parse()
+
+	db, err := sqlx.Open("mysql", *dsn+"?charset=utf8mb4&parseTime=True&loc=Asia%2FTokyo")
+	if err != nil {
+		log.Fatal("[REINDEX FATAL] ", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(*concurrency + 2)
+
+	// Ctrl-C で打ち切ったら一時的なテーブルとトリガーを片付けて終了する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	start := time.Now()
+	err = reindex.Rebuild(ctx, db, reindex.Options{ChunkSize: *chunk, Concurrency: *concurrency}, func(p reindex.Progress) {
+		if p.TotalChunks > 0 {
+			log.Printf("[REINDEX] %d/%d chunks (%d rows) - %v", p.DoneChunks, p.TotalChunks, p.Rows, time.Since(start).Round(time.Second))
+		}
+	})
+	if err != nil {
+		log.Fatal("[REINDEX FATAL] ", err)
+	}
+}
@@ -0,0 +1,261 @@
+// sqlreplay は SQL_RECORD_FILE で記録した SQL を別の DB (インデックスを変えたスキーマなど) に
+// 流し直し、クエリごとに記録時と再生時の実行時間を比べる。
+//
+// 既定では参照系 (kind=query) だけを再生し、更新系は -writes を付けたときだけ実行する。
+// 記録時にエラーになったクエリは再生しない。
+//
+//	go run ./cmd/sqlreplay -file /tmp/queries.jsonl -dsn 'root:mysql@tcp(localhost:3306)/sample_db' -c 4
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+
+	"sample-backend/internal/database"
+)
+
+type options struct {
+	file        string
+	dsn         string
+	concurrency int
+	writes      bool
+	limit       int
+	timeout     time.Duration
+}
+
+// stats は同じ SQL 文をまとめた集計
+type stats struct {
+	query    string
+	recorded []time.Duration
+	replayed []time.Duration
+	errors   int
+}
+
+type outcome struct {
+	query    string
+	recorded time.Duration
+	replayed time.Duration
+	err      error
+}
+
+func main() {
+	opts := &options{}
+	flag.StringVar(&opts.file, "file", "", "記録ファイル (JSON Lines)")
+	flag.StringVar(&opts.dsn, "dsn", "root:mysql@tcp(localhost:3306)/sample_db", "再生先の DSN")
+	flag.IntVar(&opts.concurrency, "c", 4, "同時に実行するクエリ数")
+	flag.BoolVar(&opts.writes, "writes", false, "更新系 (kind=exec) も再生する")
+	flag.IntVar(&opts.limit, "limit", 0, "再生する最大件数 (0 ですべて)")
+	flag.DurationVar(&opts.timeout, "timeout", 30*time.Second, "クエリごとのタイムアウト")
+	flag.Parse()
+
+	if opts.file == "" || opts.concurrency < 1 {
+		log.Fatal("[REPLAY FATAL] -file is required and -c must be positive")
+	}
+
+	f, err := os.Open(opts.file)
+	if err != nil {
+		log.Fatal("[REPLAY FATAL] ", err)
+	}
+	defer f.Close()
+
+	db, err := sql.Open("mysql", opts.dsn+"?charset=utf8mb4&parseTime=True&loc=Asia%2FTokyo")
+	if err != nil {
+		log.Fatal("[REPLAY FATAL] ", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(opts.concurrency)
+	if err := db.Ping(); err != nil {
+		log.Fatal("[REPLAY FATAL] Failed to connect: ", err)
+	}
+
+	entries := make(chan database.RecordedQuery)
+	outcomes := make(chan outcome, opts.concurrency*4)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range entries {
+				outcomes <- replay(db, e, opts.timeout)
+			}
+		}()
+	}
+
+	go func() {
+		n, skipped, err := feed(f, entries, opts)
+		if err != nil {
+			log.Printf("[REPLAY ERROR] Stopped reading %s: %v", opts.file, err)
+		}
+		log.Printf("[REPLAY] Replaying %d queries (skipped %d)", n, skipped)
+		close(entries)
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	start := time.Now()
+	byQuery := map[string]*stats{}
+	for o := range outcomes {
+		st, ok := byQuery[o.query]
+		if !ok {
+			st = &stats{query: o.query}
+			byQuery[o.query] = st
+		}
+		if o.err != nil {
+			st.errors++
+			log.Printf("[REPLAY ERROR] %v: %s", o.err, o.query)
+			continue
+		}
+		st.recorded = append(st.recorded, o.recorded)
+		st.replayed = append(st.replayed, o.replayed)
+	}
+
+	report(os.Stdout, byQuery, time.Since(start))
+}
+
+// feed は記録ファイルを読んで再生対象のエントリを渡す
+func feed(r io.Reader, entries chan<- database.RecordedQuery, opts *options) (n, skipped int, err error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var e database.RecordedQuery
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return n, skipped, err
+		}
+		if e.Error != "" || (e.Kind != "query" && !opts.writes) {
+			skipped++
+			continue
+		}
+		entries <- e
+		n++
+		if opts.limit > 0 && n >= opts.limit {
+			break
+		}
+	}
+	return n, skipped, sc.Err()
+}
+
+func replay(db *sql.DB, e database.RecordedQuery, timeout time.Duration) outcome {
+	o := outcome{
+		query:    normalize(e.Query),
+		recorded: time.Duration(e.DurationUS) * time.Microsecond,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	args := make([]interface{}, len(e.Args))
+	for i, a := range e.Args {
+		args[i] = decodeArg(a)
+	}
+
+	start := time.Now()
+	if e.Kind == "exec" {
+		_, o.err = db.ExecContext(ctx, e.Query, args...)
+	} else {
+		o.err = drain(db.QueryContext(ctx, e.Query, args...))
+	}
+	o.replayed = time.Since(start)
+	return o
+}
+
+// drain は記録時と同じく結果を読み切るまでを計測に含める
+func drain(rows *sql.Rows, err error) error {
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+// decodeArg は JSON から読んだ引数をドライバーに渡せる値に戻す。
+// 数値は整数ならそのまま整数、RFC3339 の文字列は時刻として扱う
+func decodeArg(v interface{}) interface{} {
+	switch a := v.(type) {
+	case float64:
+		if a == float64(int64(a)) {
+			return int64(a)
+		}
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, a); err == nil {
+			return t
+		}
+	}
+	return v
+}
+
+// normalize は空白の違いを無視して同じ SQL 文をまとめる
+func normalize(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+func report(w io.Writer, byQuery map[string]*stats, elapsed time.Duration) {
+	list := make([]*stats, 0, len(byQuery))
+	for _, st := range byQuery {
+		list = append(list, st)
+	}
+	// 再生時の合計時間が長いものから並べる
+	sort.Slice(list, func(i, j int) bool { return sum(list[i].replayed) > sum(list[j].replayed) })
+
+	fmt.Fprintf(w, "\n%7s %6s %12s %12s %12s %8s  %s\n", "count", "errors", "rec avg", "replay avg", "replay p95", "change", "query")
+	for _, st := range list {
+		if len(st.replayed) == 0 {
+			fmt.Fprintf(w, "%7d %6d %12s %12s %12s %8s  %s\n", 0, st.errors, "-", "-", "-", "-", truncate(st.query, 100))
+			continue
+		}
+		rec, rep := avg(st.recorded), avg(st.replayed)
+		change := "-"
+		if rec > 0 {
+			change = fmt.Sprintf("%+.0f%%", (float64(rep)/float64(rec)-1)*100)
+		}
+		fmt.Fprintf(w, "%7d %6d %12v %12v %12v %8s  %s\n", len(st.replayed), st.errors,
+			rec.Round(time.Microsecond), rep.Round(time.Microsecond), p95(st.replayed), change, truncate(st.query, 100))
+	}
+	fmt.Fprintf(w, "\nelapsed: %v\n", elapsed.Round(time.Millisecond))
+}
+
+func sum(ds []time.Duration) time.Duration {
+	var total time.Duration
+	for _, d := range ds {
+		total += d
+	}
+	return total
+}
+
+func avg(ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	return sum(ds) / time.Duration(len(ds))
+}
+
+func p95(ds []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := (len(sorted)*95+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx].Round(time.Microsecond)
+}
+
+func truncate(s string, n int) string {
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "..."
+}
@@ -0,0 +1,292 @@
+// Package app はアプリケーションの構成ルート。設定から DB・リポジトリ・サービス・ハンドラー・
+// サーバーを順に組み立てる。ツールや検証用に別の組み合わせが必要な場合も、ここの部品を使って組み立てる
+package app
+
+import (
+	"context"
+	"database/sql/driver"
+	"expvar"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/auth"
+	"sample-backend/internal/chaos"
+	"sample-backend/internal/config"
+	"sample-backend/internal/database"
+	"sample-backend/internal/events"
+	"sample-backend/internal/fieldcrypt"
+	"sample-backend/internal/handlers"
+	"sample-backend/internal/health"
+	"sample-backend/internal/hooks"
+	"sample-backend/internal/metrics"
+	"sample-backend/internal/middleware"
+	"sample-backend/internal/models"
+	"sample-backend/internal/notify"
+	"sample-backend/internal/recommend"
+	"sample-backend/internal/reindex"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/rerank"
+	"sample-backend/internal/sales"
+	"sample-backend/internal/server"
+	"sample-backend/internal/service"
+	"sample-backend/internal/tracing"
+)
+
+// App は組み立て済みのアプリケーション
+type App struct {
+	Config *config.Config
+	DB     *sqlx.DB
+	// JWT の署名鍵 (未設定なら nil)
+	Keys *auth.KeySet
+	// 依存先の状態 (DB の死活監視が更新する)
+	Readiness *health.Readiness
+	// リクエストの各段階の通知先 (横断的な機能はここに登録する)
+	Hooks *hooks.Registry
+
+	Products       repository.ProductRepository
+	ProductService *service.ProductService
+	// 再入荷・値下がりの通知。製品の価格・在庫を変えたら Publish する
+	Notifier *notify.Worker
+	// 製品の登録・更新・削除の購読 (SSE)。終了時に閉じて接続を切る
+	Events *events.Hub
+	Server *server.Server
+}
+
+// New は設定からアプリケーションを組み立てる。DB には接続するが、サーバーはまだ起動しない
+func New(cfg *config.Config) (*App, error) {
+	a := &App{Config: cfg, Readiness: health.NewReadiness(), Hooks: hooks.NewRegistry()}
+	expvar.Publish("readiness", expvar.Func(func() any { return a.Readiness.Failing() }))
+
+	// 機密カラムの暗号化鍵
+	if len(cfg.FieldEncryptionKeys) > 0 {
+		keyring, err := fieldcrypt.NewKeyring(cfg.FieldEncryptionKeys, cfg.FieldEncryptionActiveKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field encryption keys: %w", err)
+		}
+		fieldcrypt.SetKeyring(keyring)
+	}
+
+	// JWT の署名鍵。設定の誤りで失敗する処理は DB への接続やバックグラウンドのジョブの起動より前に済ませ、
+	// エラーで戻るときに止めるものが残らないようにする
+	if len(cfg.JWTKeys) > 0 {
+		var err error
+		if a.Keys, err = auth.NewKeySet(cfg.JWTKeys, cfg.JWTActiveKey); err != nil {
+			return nil, fmt.Errorf("invalid JWT keys: %w", err)
+		}
+	}
+
+	// トレーシング初期化
+	tracing.Init(cfg)
+
+	// フックの登録 (SQL の通知は接続前に登録したものだけが有効になる)
+	registerHooks(cfg, a.Hooks)
+	if a.Hooks.HasDBQuery() {
+		database.ObserveQueries(func(ctx context.Context, kind, query string, _ []driver.NamedValue, start time.Time, rows int64, err error) {
+			a.Hooks.DBQuery(ctx, hooks.Query{Kind: kind, Query: query, Duration: time.Since(start), Rows: rows, Err: err})
+		})
+	}
+
+	// データベース接続
+	db, err := database.Connect(cfg)
+	if err != nil {
+		// 起動済みのトレースのエクスポーターを止めてから戻る
+		if terr := tracing.Shutdown(context.Background()); terr != nil {
+			log.Printf("[MAIN ERROR] Failed to flush traces: %v", terr)
+		}
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	a.DB = db
+	metrics.RegisterDBStats(db.DB)
+
+	// インデックスヒントの読み込み
+	database.LoadIndexHints(cfg.IndexHints)
+
+	// 死活監視 (接続断やプールの枯渇で readiness を落とす)
+	if cfg.DBHealthInterval > 0 {
+		monitor := database.NewMonitor(db, cfg.DBHealthInterval)
+		monitor.OnChange(func(healthy bool, reason string) {
+			a.Readiness.Set("database", reason)
+		})
+		monitor.Start()
+	}
+
+	// パーティションのメンテナンス
+	database.StartPartitionMaintenance(db, cfg.PartitionMonthsAhead)
+
+	// 閲覧履歴の記録とおすすめの集計
+	if cfg.RecordProductViews {
+		a.Hooks.OnResponse(recommend.NewViewRecorder(db, 0).OnResponse)
+	}
+	recommend.StartJob(db, recommend.JobConfig{
+		Interval: cfg.RecommendInterval,
+		Window:   cfg.RecommendWindow,
+		TopN:     cfg.RecommendTopN,
+	})
+
+	// 再入荷・値下がりの通知
+	a.Notifier = notify.NewWorker(db, notify.Config{
+		Interval:       cfg.NotifyInterval,
+		UnsubscribeURL: cfg.NotifyUnsubscribeURL,
+		Senders: map[string]notify.Sender{
+			models.AlertEmail:   notify.EmailSender(cfg.SMTPAddr, cfg.SMTPFrom, cfg.SMTPUsername, cfg.SMTPPassword),
+			models.AlertWebhook: notify.NewWebhookSender(cfg.NotifyWebhookSecret, 5*time.Second),
+		},
+	})
+	a.Notifier.Start()
+
+	// リポジトリ (障害注入は本番以外で明示的に有効にした場合のみ)
+	// クエリの所要時間は DB に問い合わせた分だけを計測する (タイムアウトで打ち切った分も含める)
+	a.Products = metrics.WrapRepository(repository.WithTimeouts(repository.NewProductRepository(db), cfg.DBReadTimeout, cfg.DBWriteTimeout))
+	if cfg.ChaosActive() {
+		a.Products = chaos.WrapRepository(a.Products)
+	} else if cfg.ChaosEnabled {
+		log.Printf("[CHAOS] Ignoring CHAOS_ENABLED in %s environment", cfg.AppEnv)
+	}
+
+	// 読み取りキャッシュ (キャッシュに当たった分は障害注入の対象外になるよう外側に重ねる)
+	var cached repository.CachedRepository
+	if cfg.RepoCacheTTL > 0 {
+		cached = repository.NewCachedRepository(a.Products, cfg.RepoCacheTTL, cfg.RepoCacheSize)
+		a.Products = cached
+	}
+
+	// サービスとハンドラー
+	a.ProductService = service.NewProductService(a.Products, cfg)
+	if cfg.RerankURL != "" {
+		a.ProductService.SetRanker(rerank.NewHTTPRanker(cfg.RerankURL, cfg.RerankTimeout))
+	}
+	// タイムセール (一覧・検索・詳細の製品にセール価格を付ける)
+	saleRepo := repository.NewSaleRepository(db)
+	saleCatalog := sales.NewCatalog(saleRepo, cfg.SalesRefreshInterval, cfg.SalesHorizon)
+	saleCatalog.Start()
+	a.ProductService.SetSales(saleCatalog)
+	// 価格を変えたら値下がりの通知に知らせる
+	a.ProductService.SetNotifier(a.Notifier)
+	// 製品の変化を SSE の購読者に配る
+	a.Events = events.NewHub()
+	a.ProductService.SetEvents(a.Events)
+	// 店舗での受け取りによる絞り込み
+	a.ProductService.SetStores(repository.NewStoreRepository(db))
+	if cached != nil {
+		// 書き込みでリポジトリのキャッシュを捨てたら、事前生成したページも作り直す
+		cached.OnInvalidate(a.ProductService.InvalidateCache)
+	}
+	// 検索用テーブルの作り直し (読み取りのキャッシュと事前生成したページも捨てる)
+	reindexer := reindex.NewRunner(db, reindex.Options{
+		ChunkSize:   cfg.ReindexChunkSize,
+		Concurrency: cfg.ReindexConcurrency,
+	}, cfg.ReindexTimeout)
+	if cached != nil {
+		reindexer.OnDone(cached.Invalidate)
+	} else {
+		reindexer.OnDone(a.ProductService.InvalidateCache)
+	}
+
+	// JWT のログイン (署名鍵が設定されている場合のみ)
+	var authHandler *handlers.AuthHandler
+	if a.Keys != nil {
+		lockout := auth.NewLockout(auth.LockoutConfig{
+			Threshold:    cfg.AuthLockoutThreshold,
+			BaseDuration: cfg.AuthLockoutBase,
+			MaxDuration:  cfg.AuthLockoutMax,
+		})
+		authHandler = handlers.NewAuthHandler(
+			service.NewAuthService(repository.NewUserRepository(db), a.Keys, cfg.JWTTokenTTL, lockout),
+			middleware.NewProxyTrust(cfg.TrustProxyHeaders, cfg.TrustedProxyCIDRs))
+	}
+
+	// 在庫 (再入荷の通知と SSE に知らせ、製品詳細・一覧・事前生成したページのキャッシュを捨てる)
+	stock := service.NewStockService(repository.NewStockRepository(db), a.Notifier, a.Events)
+	// セール中の製品の引き当てはセールの在庫 (stock_cap) も確保する
+	stock.SetSales(saleCatalog)
+	stock.OnChange(a.forgetProduct(cached))
+
+	// レビュー (投稿で製品の件数と平均が変わるので、在庫と同じくキャッシュを捨てる)
+	reviews := service.NewReviewService(repository.NewReviewRepository(db))
+	reviews.OnChange(a.forgetProduct(cached))
+
+	questions := service.NewQuestionService(a.Products, repository.NewQuestionRepository(db))
+	a.Server = server.New(cfg, server.Handlers{
+		Product: handlers.NewProductHandler(a.ProductService, questions,
+			service.NewHistoryService(repository.NewProductHistoryRepository(db))),
+		Search:   handlers.NewSearchHandler(a.ProductService),
+		Supplier: handlers.NewSupplierHandler(repository.NewSupplierRepository(db)),
+		QR:       handlers.NewQRHandler(a.ProductService, cfg),
+		Recommendation: handlers.NewRecommendationHandler(
+			service.NewRecommendationService(a.Products, repository.NewRecommendationRepository(db))),
+		Question: handlers.NewQuestionHandler(questions),
+		Stock:    handlers.NewStockHandler(stock),
+		Export:   handlers.NewExportHandler(service.NewExportService(repository.NewExportRepository(db))),
+		Events:   handlers.NewEventsHandler(a.Events, cfg.EventsHeartbeat),
+		Review:   handlers.NewReviewHandler(reviews),
+		Alert: handlers.NewAlertHandler(
+			service.NewAlertService(a.Products, repository.NewAlertRepository(db), cfg.NotifyWebhookHTTP)),
+		Reindex: handlers.NewReindexHandler(reindexer),
+		Sale:    handlers.NewSaleHandler(service.NewSaleService(a.Products, saleRepo, saleCatalog)),
+		Auth:    authHandler,
+		Probe:   handlers.NewProbeHandler(db, a.Readiness, cfg.ReadyTimeout),
+	}, a.Keys, a.Hooks)
+
+	return a, nil
+}
+
+// registerHooks は設定で有効にした横断的な機能をフックに登録する
+func registerHooks(cfg *config.Config, h *hooks.Registry) {
+	// 遅い SQL のログ (リクエストのログと突き合わせられるよう reqlog で出す)
+	if threshold := cfg.SlowQueryThreshold; threshold > 0 {
+		h.OnDBQuery(func(ctx context.Context, q hooks.Query) {
+			if q.Duration >= threshold {
+				reqlog.From(ctx).Printf("[DB SLOW] %s took %v (%d rows): %s", q.Kind, q.Duration.Round(time.Microsecond), q.Rows, strings.Join(strings.Fields(q.Query), " "))
+			}
+		})
+	}
+}
+
+// Shutdown はサーバーを止めてから DB 接続を閉じる。先に readyz を 503 にして
+// ShutdownDelay だけ待ち、ロードバランサーが振り分けを止めてから処理中のリクエストを待つ
+func (a *App) Shutdown(ctx context.Context) error {
+	a.Readiness.Set("server", "shutting down")
+	if d := a.Config.ShutdownDelay; d > 0 {
+		log.Printf("[MAIN] Waiting %v for the load balancer to stop routing requests...", d)
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+		}
+	}
+
+	// SSE の接続は終わらないので、先に購読を閉じて切断させる
+	a.Events.Close()
+	ctx, cancel := context.WithTimeout(ctx, a.Config.ShutdownTimeout)
+	defer cancel()
+	log.Printf("[MAIN] Draining in-flight requests (timeout %v)...", a.Config.ShutdownTimeout)
+	err := a.Server.Shutdown(ctx)
+	if err != nil {
+		log.Printf("[MAIN ERROR] Server did not shut down cleanly: %v", err)
+	}
+	if terr := tracing.Shutdown(ctx); terr != nil {
+		log.Printf("[MAIN ERROR] Failed to flush traces: %v", terr)
+	}
+	if cerr := a.Close(); cerr != nil {
+		log.Printf("[MAIN ERROR] Failed to close database: %v", cerr)
+	}
+	return err
+}
+
+// Close は DB 接続を閉じる
+func (a *App) Close() error {
+	return a.DB.Close()
+}
+
+// forgetProduct は製品 1 件の列をリポジトリを経由せずに変えたあとに、読み取りのキャッシュと事前生成したページを捨てる関数を返す
+// (リポジトリのキャッシュを捨てると OnInvalidate で事前生成したページも作り直す)
+func (a *App) forgetProduct(cached repository.CachedRepository) func(productID int) {
+	if cached != nil {
+		return cached.Forget
+	}
+	return func(int) { a.ProductService.InvalidateCache() }
+}
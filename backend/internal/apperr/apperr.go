@@ -0,0 +1,120 @@
+// Package apperr はリポジトリ・サービスが返すエラーの分類をまとめる。
+// 呼び出し側は errors.Is で分類を判定し、HTTP ステータスへの対応付けはハンドラーの 1 か所だけで行う
+package apperr
+
+import (
+	"errors"
+	"time"
+)
+
+// エラーの分類。*Error は Kind に指定したいずれかとして errors.Is に一致する
+var (
+	// ErrNotFound は対象が存在しない
+	ErrNotFound = errors.New("not found")
+	// ErrConflict は既存のデータと衝突した (一意制約違反など)
+	ErrConflict = errors.New("conflict")
+	// ErrValidation はリクエストの内容が不正
+	ErrValidation = errors.New("validation failed")
+	// ErrUnauthorized は操作に必要な認証がない
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden は認証済みだが操作の権限がない
+	ErrForbidden = errors.New("forbidden")
+	// ErrTooManyRequests は試行が多すぎる。RetryAfter の後に再試行すればよい
+	ErrTooManyRequests = errors.New("too many requests")
+	// ErrUnavailable は DB などの依存先が一時的に使えない。時間をおいて再試行すればよい
+	ErrUnavailable = errors.New("unavailable")
+)
+
+// Error は分類とクライアントに返してよいメッセージを持つエラー。
+// Err は原因となったエラーでログにだけ出し、クライアントには返さない。
+// Details はクライアントに返してよい補足 (衝突した既存のデータなど)
+type Error struct {
+	Kind    error
+	Msg     string
+	Err     error
+	Details interface{}
+	// ErrTooManyRequests のとき、再試行までに待つ時間
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Msg + ": " + e.Err.Error()
+	}
+	return e.Msg
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NotFound は対象が存在しないことを表すエラーを返す
+func NotFound(msg string) error {
+	return &Error{Kind: ErrNotFound, Msg: msg}
+}
+
+// Conflict は既存のデータとの衝突を表すエラーを返す
+func Conflict(msg string, err error) error {
+	return &Error{Kind: ErrConflict, Msg: msg, Err: err}
+}
+
+// ConflictWith は衝突した既存のデータ (details) をクライアントに返す Conflict を返す
+func ConflictWith(msg string, details interface{}, err error) error {
+	return &Error{Kind: ErrConflict, Msg: msg, Err: err, Details: details}
+}
+
+// Validation はリクエストの内容が不正なことを表すエラーを返す
+func Validation(msg string) error {
+	return &Error{Kind: ErrValidation, Msg: msg}
+}
+
+// Unauthorized は操作に必要な認証がないことを表すエラーを返す
+func Unauthorized(msg string) error {
+	return &Error{Kind: ErrUnauthorized, Msg: msg}
+}
+
+// Forbidden は認証済みだが操作の権限がないことを表すエラーを返す
+func Forbidden(msg string) error {
+	return &Error{Kind: ErrForbidden, Msg: msg}
+}
+
+// TooManyRequests は試行が多すぎるため retryAfter の間は受け付けないことを表すエラーを返す
+func TooManyRequests(msg string, retryAfter time.Duration) error {
+	return &Error{Kind: ErrTooManyRequests, Msg: msg, RetryAfter: retryAfter}
+}
+
+// Unavailable は依存先が一時的に使えないことを表すエラーを返す
+func Unavailable(msg string, err error) error {
+	return &Error{Kind: ErrUnavailable, Msg: msg, Err: err}
+}
+
+// Message はクライアントに返してよいメッセージを返す。分類されていないエラーなら false
+func Message(err error) (string, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Msg, true
+	}
+	return "", false
+}
+
+// Details はクライアントに返してよい補足を返す。無ければ nil
+func Details(err error) interface{} {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Details
+	}
+	return nil
+}
+
+// RetryAfter は再試行までに待つ時間を返す。指定が無ければ 0
+func RetryAfter(err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.RetryAfter
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package auth
+
+import "context"
+
+type contextKey int
+
+const (
+	clientKey contextKey = iota
+	userKey
+)
+
+// ユーザーの役割
+const (
+	// RoleAdmin は製品の登録・更新・削除ができる
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// User は JWT で認証したユーザー
+type User struct {
+	ID       int
+	Username string
+	Role     string
+}
+
+// WithClient は認証済みのクライアント名をコンテキストに設定する
+func WithClient(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, clientKey, name)
+}
+
+// ClientFrom は認証済みのクライアント名を返す。匿名のリクエストでは false
+func ClientFrom(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(clientKey).(string)
+	return name, ok
+}
+
+// WithUser は JWT で認証したユーザーをコンテキストに設定する
+func WithUser(ctx context.Context, u User) context.Context {
+	return context.WithValue(ctx, userKey, u)
+}
+
+// UserFrom は JWT で認証したユーザーを返す。トークンの無いリクエストでは false
+func UserFrom(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value(userKey).(User)
+	return u, ok
+}
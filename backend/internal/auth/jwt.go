@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken はトークンの形式・署名・有効期限のいずれかが正しくない
+var ErrInvalidToken = errors.New("invalid token")
+
+type tokenHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+type tokenClaims struct {
+	Subject  string `json:"sub"`
+	Username string `json:"name"`
+	Role     string `json:"role"`
+	IssuedAt int64  `json:"iat"`
+	Expires  int64  `json:"exp"`
+}
+
+// Issue は u のトークンを有効な鍵で署名して発行する (HS256)。トークンと有効期限を返す
+func (k *KeySet) Issue(u User, ttl time.Duration) (string, time.Time, error) {
+	kid, secret := k.SigningKey()
+	now := k.clock.Now()
+	expires := now.Add(ttl)
+
+	header, err := json.Marshal(tokenHeader{Alg: "HS256", Typ: "JWT", Kid: kid})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	claims, err := json.Marshal(tokenClaims{
+		Subject:  strconv.Itoa(u.ID),
+		Username: u.Username,
+		Role:     u.Role,
+		IssuedAt: now.Unix(),
+		Expires:  expires.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign(secret, signed)), expires, nil
+}
+
+// Verify はトークンの署名と有効期限を確かめ、トークンのユーザーを返す。
+// 署名の検証にはヘッダーの kid の鍵を使う (ローテーションで外された鍵も猶予期間の間は受け付ける)
+func (k *KeySet) Verify(token string) (User, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return User{}, ErrInvalidToken
+	}
+	var header tokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return User{}, ErrInvalidToken
+	}
+	secret, ok := k.VerificationKey(header.Kid)
+	if !ok {
+		return User{}, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, sign(secret, parts[0]+"."+parts[1])) {
+		return User{}, ErrInvalidToken
+	}
+
+	var claims tokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return User{}, ErrInvalidToken
+	}
+	if !k.clock.Now().Before(time.Unix(claims.Expires, 0)) {
+		return User{}, ErrInvalidToken
+	}
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return User{}, ErrInvalidToken
+	}
+	return User{ID: id, Username: claims.Username, Role: claims.Role}, nil
+}
+
+func sign(secret []byte, signed string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return mac.Sum(nil)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
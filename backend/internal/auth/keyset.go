@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"sample-backend/internal/clock"
+)
+
+type signingKey struct {
+	secret []byte
+	// ゼロ値なら現役の鍵。ローテーションで外された鍵はこの時刻まで検証にだけ使う
+	retireAt time.Time
+}
+
+// KeySet は JWT の署名鍵の集合。署名は有効な鍵で行い、検証はトークンの kid で鍵を選ぶ。
+// Rotate で外された鍵は、それで署名したトークンが失効するまで検証に使い続ける
+type KeySet struct {
+	clock clock.Clock
+
+	mu     sync.RWMutex
+	active string
+	keys   map[string]*signingKey
+}
+
+func NewKeySet(keys map[string]string, active string) (*KeySet, error) {
+	k := &KeySet{clock: clock.Real}
+	if err := k.Rotate(keys, active, 0); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Rotate は鍵の集合を差し替える。新しい集合に含まれない鍵は grace の間だけ検証用に残す
+func (k *KeySet) Rotate(keys map[string]string, active string, grace time.Duration) error {
+	if _, ok := keys[active]; !ok {
+		return fmt.Errorf("active key %q is not in the key set", active)
+	}
+	for kid, secret := range keys {
+		if len(secret) < 32 {
+			return fmt.Errorf("key %q must be at least 32 bytes", kid)
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.clock.Now()
+	next := make(map[string]*signingKey, len(keys)+len(k.keys))
+	for kid, old := range k.keys {
+		if _, kept := keys[kid]; kept {
+			continue
+		}
+		if old.retireAt.IsZero() {
+			old.retireAt = now.Add(grace)
+		}
+		if now.Before(old.retireAt) {
+			next[kid] = old
+			log.Printf("[AUTH] Signing key %q retired, still accepted until %s", kid, old.retireAt.Format(time.RFC3339))
+		}
+	}
+	for kid, secret := range keys {
+		next[kid] = &signingKey{secret: []byte(secret)}
+	}
+
+	if k.active != "" && k.active != active {
+		log.Printf("[AUTH] Active signing key changed: %q -> %q", k.active, active)
+	}
+	k.active = active
+	k.keys = next
+	return nil
+}
+
+// SetClock は鍵の猶予期間の判定に使う Clock を差し替える
+func (k *KeySet) SetClock(c clock.Clock) {
+	k.clock = clock.OrReal(c)
+}
+
+// SigningKey は新しいトークンの署名に使う鍵を返す
+func (k *KeySet) SigningKey() (string, []byte) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active, k.keys[k.active].secret
+}
+
+// VerificationKey は kid の鍵を返す。猶予期間を過ぎた鍵は返さない
+func (k *KeySet) VerificationKey(kid string) ([]byte, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[kid]
+	if !ok || (!key.retireAt.IsZero() && k.clock.Now().After(key.retireAt)) {
+		return nil, false
+	}
+	return key.secret, true
+}
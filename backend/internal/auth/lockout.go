@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	"sample-backend/internal/clock"
+)
+
+// 認証失敗の集計値 (管理用リスナーの /debug/vars で参照できる)
+var (
+	failedAttemptsTotal = expvar.NewInt("auth_failed_attempts_total")
+	lockoutsTotal       = expvar.NewInt("auth_lockouts_total")
+	blockedTotal        = expvar.NewInt("auth_blocked_requests_total")
+)
+
+// LockoutConfig は認証失敗によるロックアウトの設定
+type LockoutConfig struct {
+	// この回数連続で失敗するとロックする
+	Threshold int
+	// 最初のロック時間。ロックされるたびに倍になる
+	BaseDuration time.Duration
+	// ロック時間の上限
+	MaxDuration time.Duration
+	// 最後の失敗からこの時間が経過したら記録を忘れる
+	Window time.Duration
+	// ロック期限の判定に使う Clock (nil ならシステム時刻)
+	Clock clock.Clock
+}
+
+type failureState struct {
+	failures    int
+	lockouts    int
+	lockedUntil time.Time
+	lastFailure time.Time
+}
+
+// Lockout は識別子 (API キーや IP アドレス) ごとの認証失敗を記録し、
+// 失敗が続いたものを指数的に伸びる時間だけ締め出す
+type Lockout struct {
+	cfg LockoutConfig
+
+	mu      sync.Mutex
+	entries map[string]*failureState
+}
+
+func NewLockout(cfg LockoutConfig) *Lockout {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 5
+	}
+	if cfg.BaseDuration <= 0 {
+		cfg.BaseDuration = 30 * time.Second
+	}
+	if cfg.MaxDuration < cfg.BaseDuration {
+		cfg.MaxDuration = cfg.BaseDuration
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 15 * time.Minute
+	}
+
+	cfg.Clock = clock.OrReal(cfg.Clock)
+
+	l := &Lockout{cfg: cfg, entries: make(map[string]*failureState)}
+	go l.sweep()
+	return l
+}
+
+// Locked は key がロック中であれば残り時間を返す
+func (l *Lockout) Locked(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	st, ok := l.entries[key]
+	if !ok {
+		return 0, false
+	}
+	remaining := st.lockedUntil.Sub(l.cfg.Clock.Now())
+	if remaining <= 0 {
+		return 0, false
+	}
+	blockedTotal.Add(1)
+	return remaining, true
+}
+
+// Fail は認証失敗を記録する。これによってロックされた場合はロック時間を返す
+func (l *Lockout) Fail(key string) time.Duration {
+	failedAttemptsTotal.Add(1)
+	now := l.cfg.Clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	st, ok := l.entries[key]
+	if !ok || now.Sub(st.lastFailure) > l.cfg.Window {
+		st = &failureState{}
+		l.entries[key] = st
+	}
+	st.failures++
+	st.lastFailure = now
+	if st.failures < l.cfg.Threshold {
+		return 0
+	}
+
+	d := l.lockDuration(st.lockouts)
+	if st.lockouts < maxLockoutDoublings {
+		st.lockouts++
+	}
+	st.failures = 0
+	st.lockedUntil = now.Add(d)
+	lockoutsTotal.Add(1)
+	return d
+}
+
+// maxLockoutDoublings は倍にする回数の上限 (time.Duration は 63 ビットなのでこれ以上はあふれる)
+const maxLockoutDoublings = 62
+
+// lockDuration はロックのたびに時間を倍にする (上限あり)。
+// シフトする前に MaxDuration と比べて、あふれて負やゼロにならないようにする
+func (l *Lockout) lockDuration(lockouts int) time.Duration {
+	d := l.cfg.BaseDuration
+	for i := 0; i < lockouts && i < maxLockoutDoublings; i++ {
+		if d > l.cfg.MaxDuration>>1 {
+			return l.cfg.MaxDuration
+		}
+		d <<= 1
+	}
+	if d <= 0 || d > l.cfg.MaxDuration {
+		return l.cfg.MaxDuration
+	}
+	return d
+}
+
+// Succeed は認証に成功した key の記録を消す
+func (l *Lockout) Succeed(key string) {
+	l.mu.Lock()
+	delete(l.entries, key)
+	l.mu.Unlock()
+}
+
+// sweep は期限の切れた記録を定期的に捨てる
+func (l *Lockout) sweep() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		now := l.cfg.Clock.Now()
+		l.mu.Lock()
+		for key, st := range l.entries {
+			if now.After(st.lockedUntil) && now.Sub(st.lastFailure) > l.cfg.Window {
+				delete(l.entries, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockDurationClamp(t *testing.T) {
+	l := &Lockout{cfg: LockoutConfig{BaseDuration: time.Minute, MaxDuration: time.Hour}}
+
+	tests := []struct {
+		lockouts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, 2 * time.Minute},
+		{5, 32 * time.Minute},
+		{6, time.Hour},
+		{40, time.Hour},
+		{64, time.Hour},
+		{1 << 20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := l.lockDuration(tt.lockouts); got != tt.want {
+			t.Errorf("lockDuration(%d) = %v, want %v", tt.lockouts, got, tt.want)
+		}
+	}
+}
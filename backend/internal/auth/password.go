@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// passwordIterations は PBKDF2 の反復回数 (OWASP の PBKDF2-HMAC-SHA256 の推奨値)。
+	// 回数はハッシュに含めるので、変えても既存のハッシュはそのまま検証できる
+	passwordIterations = 600000
+	passwordSaltSize   = 16
+	passwordKeySize    = 32
+	passwordScheme     = "pbkdf2-sha256"
+)
+
+// HashPassword はパスワードを PBKDF2-HMAC-SHA256 でハッシュにする。
+// 形式は "pbkdf2-sha256$<反復回数>$<salt>$<hash>" (salt と hash は base64)
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := pbkdf2SHA256([]byte(password), salt, passwordIterations, passwordKeySize)
+	return fmt.Sprintf("%s$%d$%s$%s", passwordScheme, passwordIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// CheckPassword は password が HashPassword で作ったハッシュと一致するかを返す
+func CheckPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != passwordScheme {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(want) == 0 {
+		return false
+	}
+	got := pbkdf2SHA256([]byte(password), salt, iterations, len(want))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+var dummyHash = sync.OnceValue(func() string {
+	h, _ := HashPassword("dummy password")
+	return h
+})
+
+// CheckDummyPassword は存在しないユーザーのログインでも CheckPassword と同じだけ時間をかける
+// (応答時間の差からユーザー名の有無を推測されないようにする)
+func CheckDummyPassword(password string) {
+	CheckPassword(dummyHash(), password)
+}
+
+// pbkdf2SHA256 は RFC 8018 の PBKDF2 (PRF は HMAC-SHA256)
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	size := prf.Size()
+	key := make([]byte, 0, (keyLen+size-1)/size*size)
+	var block [4]byte
+	u := make([]byte, size)
+	for i := 1; len(key) < keyLen; i++ {
+		binary.BigEndian.PutUint32(block[:], uint32(i))
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(block[:])
+		u = prf.Sum(u[:0])
+		t := append([]byte(nil), u...)
+		for n := 1; n < iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			subtle.XORBytes(t, t, u)
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
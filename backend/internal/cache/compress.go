@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"github.com/klauspost/compress/zstd"
+)
+
+// EncodeAll / DecodeAll は並行に呼び出せるため、エンコーダーとデコーダーは共有する
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// blob はキャッシュに保持するペイロード。threshold 以上のものは zstd で圧縮して持つ
+type blob struct {
+	data       []byte
+	compressed bool
+	rawSize    int
+}
+
+func newBlob(raw []byte, threshold int) blob {
+	if threshold <= 0 || len(raw) < threshold {
+		return blob{data: raw, rawSize: len(raw)}
+	}
+
+	compressed := zstdEncoder.EncodeAll(raw, make([]byte, 0, len(raw)/4))
+	// 圧縮しても小さくならないものはそのまま持つ
+	if len(compressed) >= len(raw) {
+		return blob{data: raw, rawSize: len(raw)}
+	}
+	return blob{data: compressed, compressed: true, rawSize: len(raw)}
+}
+
+// bytes は元のペイロードを返す。圧縮されていれば展開する
+func (b blob) bytes() ([]byte, error) {
+	if !b.compressed {
+		return b.data, nil
+	}
+	return zstdDecoder.DecodeAll(b.data, make([]byte, 0, b.rawSize))
+}
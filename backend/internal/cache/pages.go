@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"sample-backend/internal/clock"
+)
+
+// Page は事前に生成しておいた一覧ページのレスポンス
+type Page struct {
+	Body       []byte
+	TotalCount int
+	TotalPages int
+	Returned   int
+	// 生成を始めた時刻 (PageCache が設定する)
+	BuiltAt time.Time
+}
+
+// pageEntry は保持中のページ。Body は blob として (必要なら圧縮して) 持つ
+type pageEntry struct {
+	meta Page
+	body blob
+}
+
+// PageLoader は指定ページのレスポンスを DB から組み立てる
+type PageLoader func(ctx context.Context, page, limit int) (*Page, error)
+
+// PageCache は既定の一覧 (limit 固定) の先頭 N ページ分の JSON を保持し、
+// TTL ごとにバックグラウンドで作り直す
+type PageCache struct {
+	pages  int
+	limit  int
+	ttl    time.Duration
+	loader PageLoader
+	clock  clock.Clock
+
+	// この大きさ (バイト) 以上のレスポンスは圧縮して保持する (0 で無効)
+	compressThreshold int
+
+	mu      sync.RWMutex
+	entries map[int]*pageEntry
+	// generation は Invalidate のたびに増やす。生成を始めたときと世代が違えば、生成中に書き込みがあったので結果を捨てる
+	generation uint64
+
+	refresh chan struct{}
+	stop    chan struct{}
+	once    sync.Once
+}
+
+func NewPageCache(pages, limit int, ttl time.Duration, loader PageLoader) *PageCache {
+	return &PageCache{
+		pages:   pages,
+		limit:   limit,
+		ttl:     ttl,
+		loader:  loader,
+		clock:   clock.Real,
+		entries: make(map[int]*pageEntry, pages),
+		refresh: make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+}
+
+// SetCompression は threshold バイト以上のページを zstd で圧縮して保持するようにする。
+// Start より前に呼び出す
+func (c *PageCache) SetCompression(threshold int) {
+	c.compressThreshold = threshold
+}
+
+// SetClock は TTL の判定に使う Clock を差し替える
+func (c *PageCache) SetClock(clk clock.Clock) {
+	c.clock = clock.OrReal(clk)
+}
+
+// Start は再生成するゴルーチンを起動する。初回の生成もゴルーチンの中で行うので DB を待たずに戻り、
+// 生成が終わるまでの Get は false を返す (呼び出し側は DB から読む)。以降は TTL ごとに作り直す
+func (c *PageCache) Start() {
+	log.Printf("[CACHE] Page cache enabled - pages: %d, limit: %d, ttl: %v", c.pages, c.limit, c.ttl)
+
+	go func() {
+		c.rebuild()
+
+		ticker := time.NewTicker(c.ttl)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.rebuild()
+			case <-c.refresh:
+				c.rebuild()
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop は再生成ゴルーチンを停止する
+func (c *PageCache) Stop() {
+	c.once.Do(func() { close(c.stop) })
+}
+
+// Get はキャッシュ対象のページであれば生成済みのレスポンスを返す。
+// 再生成に失敗し続けて TTL の 2 倍より古くなったものは返さない
+func (c *PageCache) Get(page, limit int) (*Page, bool) {
+	if limit != c.limit || page < 1 || page > c.pages {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	e, ok := c.entries[page]
+	c.mu.RUnlock()
+	if !ok || c.clock.Now().Sub(e.meta.BuiltAt) > 2*c.ttl {
+		return nil, false
+	}
+
+	body, err := e.body.bytes()
+	if err != nil {
+		log.Printf("[CACHE ERROR] Failed to decompress page %d: %v", page, err)
+		return nil, false
+	}
+
+	p := e.meta
+	p.Body = body
+	return &p, true
+}
+
+// Invalidate は保持しているページを破棄し、非同期で再生成を要求する。
+// 書き込み処理の後に呼び出す
+func (c *PageCache) Invalidate() {
+	c.mu.Lock()
+	c.entries = make(map[int]*pageEntry, c.pages)
+	c.generation++
+	c.mu.Unlock()
+
+	select {
+	case c.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func (c *PageCache) rebuild() {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), c.ttl)
+	defer cancel()
+
+	c.mu.RLock()
+	generation := c.generation
+	c.mu.RUnlock()
+
+	entries := make(map[int]*pageEntry, c.pages)
+	rawBytes, storedBytes := 0, 0
+	for page := 1; page <= c.pages; page++ {
+		// 読み始める前の時刻を生成時刻とする (読んでいる間の変更を含んでいない可能性があるため)
+		builtAt := c.clock.Now()
+		p, err := c.loader(ctx, page, c.limit)
+		if err != nil {
+			log.Printf("[CACHE ERROR] Failed to build page %d: %v", page, err)
+			return
+		}
+		e := &pageEntry{meta: *p, body: newBlob(p.Body, c.compressThreshold)}
+		e.meta.Body = nil
+		e.meta.BuiltAt = builtAt
+		entries[page] = e
+		rawBytes += e.body.rawSize
+		storedBytes += len(e.body.data)
+		// 総ページ数を超えた分は作らない
+		if page >= p.TotalPages {
+			break
+		}
+	}
+
+	c.mu.Lock()
+	if c.generation != generation {
+		// 生成中に Invalidate された (書き込みの前のデータで作ったかもしれない)。
+		// Invalidate が再生成を要求しているので、ここでは保存しない
+		c.mu.Unlock()
+		log.Printf("[CACHE] Discarded %d pages built before an invalidation", len(entries))
+		return
+	}
+	c.entries = entries
+	c.mu.Unlock()
+
+	log.Printf("[CACHE] Rebuilt %d pages in %v (%d bytes, stored %d bytes)", len(entries), time.Since(start), rawBytes, storedBytes)
+}
@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"context"
+	"expvar"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// キャッシュの種類ごとの当たり・外れの件数 (管理用リスナーの /debug/vars で参照できる)
+var outcomes = expvar.NewMap("cache_total")
+
+// Record はキャッシュ name の当たり・外れを数え、ctx のスパンに cache.<name>.hit として残す
+func Record(ctx context.Context, name string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	outcomes.Add(name+"_"+result, 1)
+
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		span.SetAttributes(attribute.Bool("cache."+name+".hit", hit))
+	}
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"sample-backend/internal/clock"
+)
+
+type ttlEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// TTL は有効期限付きのキャッシュ。上限に達したら期限切れを掃除し、それでも空かなければ任意の 1 件を捨てる
+type TTL[K comparable, V any] struct {
+	ttl   time.Duration
+	max   int
+	clock clock.Clock
+
+	mu      sync.Mutex
+	entries map[K]ttlEntry[V]
+}
+
+func NewTTL[K comparable, V any](ttl time.Duration, max int) *TTL[K, V] {
+	return &TTL[K, V]{
+		ttl:     ttl,
+		max:     max,
+		clock:   clock.Real,
+		entries: make(map[K]ttlEntry[V]),
+	}
+}
+
+// SetClock は有効期限の判定に使う Clock を差し替える
+func (c *TTL[K, V]) SetClock(clk clock.Clock) {
+	c.clock = clock.OrReal(clk)
+}
+
+// Get は期限内の値を返す
+func (c *TTL[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !c.clock.Now().Before(e.expires) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set は値を保持する
+func (c *TTL[K, V]) Set(key K, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock.Now()
+	if _, ok := c.entries[key]; !ok && c.max > 0 && len(c.entries) >= c.max {
+		c.evict(now)
+	}
+	c.entries[key] = ttlEntry[V]{value: value, expires: now.Add(c.ttl)}
+}
+
+// Delete は key の値を捨てる
+func (c *TTL[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// Clear はすべての値を捨てる
+func (c *TTL[K, V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[K]ttlEntry[V])
+}
+
+func (c *TTL[K, V]) evict(now time.Time) {
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	if len(c.entries) < c.max {
+		return
+	}
+	for k := range c.entries {
+		delete(c.entries, k)
+		return
+	}
+}
@@ -0,0 +1,154 @@
+// Package chaos は検証用の障害注入を扱う。リクエスト単位の注入指示はコンテキストで受け渡す
+package chaos
+
+import (
+	"context"
+	"expvar"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+)
+
+// ErrInjected は障害注入で発生させた DB エラー。実際の DB 障害と同じく apperr.ErrUnavailable に分類する
+var ErrInjected error = &apperr.Error{Kind: apperr.ErrUnavailable, Msg: "chaos: injected database error"}
+
+// 注入した障害の種類ごとの件数 (管理用リスナーの /debug/vars で参照できる)
+var Injected = expvar.NewMap("chaos_injected_total")
+
+type dbFaultKey struct{}
+
+// WithDBFault はこのリクエストの DB アクセスを失敗させるよう指示したコンテキストを返す
+func WithDBFault(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dbFaultKey{}, true)
+}
+
+// DBFault はコンテキストに DB エラーの注入が指示されていれば ErrInjected を返す
+func DBFault(ctx context.Context) error {
+	if v, _ := ctx.Value(dbFaultKey{}).(bool); v {
+		return ErrInjected
+	}
+	return nil
+}
+
+// faultyRepository は注入が指示されたリクエストで DB に問い合わせずに失敗する
+type faultyRepository struct {
+	next repository.ProductRepository
+}
+
+// WrapRepository は障害注入に対応した ProductRepository を返す。障害注入が有効なときだけ使う
+func WrapRepository(next repository.ProductRepository) repository.ProductRepository {
+	return &faultyRepository{next: next}
+}
+
+func (r *faultyRepository) Count(ctx context.Context, filter repository.ListFilter) (int, error) {
+	if err := DBFault(ctx); err != nil {
+		return 0, err
+	}
+	return r.next.Count(ctx, filter)
+}
+
+func (r *faultyRepository) List(ctx context.Context, filter repository.ListFilter, limit, offset int) ([]models.Product, error) {
+	if err := DBFault(ctx); err != nil {
+		return nil, err
+	}
+	return r.next.List(ctx, filter, limit, offset)
+}
+
+func (r *faultyRepository) ListAfter(ctx context.Context, filter repository.ListFilter, afterID, limit int) ([]models.Product, error) {
+	if err := DBFault(ctx); err != nil {
+		return nil, err
+	}
+	return r.next.ListAfter(ctx, filter, afterID, limit)
+}
+
+func (r *faultyRepository) CountBy(ctx context.Context, filter repository.ListFilter, column string, limit int) ([]models.FacetCount, error) {
+	if err := DBFault(ctx); err != nil {
+		return nil, err
+	}
+	return r.next.CountBy(ctx, filter, column, limit)
+}
+
+func (r *faultyRepository) CountByPrice(ctx context.Context, filter repository.ListFilter, bounds []float64) ([]models.PriceBucket, error) {
+	if err := DBFault(ctx); err != nil {
+		return nil, err
+	}
+	return r.next.CountByPrice(ctx, filter, bounds)
+}
+
+func (r *faultyRepository) Get(ctx context.Context, id int) (*models.Product, error) {
+	if err := DBFault(ctx); err != nil {
+		return nil, err
+	}
+	return r.next.Get(ctx, id)
+}
+
+func (r *faultyRepository) GetMany(ctx context.Context, ids []int) ([]models.Product, error) {
+	if err := DBFault(ctx); err != nil {
+		return nil, err
+	}
+	return r.next.GetMany(ctx, ids)
+}
+
+func (r *faultyRepository) GetBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	if err := DBFault(ctx); err != nil {
+		return nil, err
+	}
+	return r.next.GetBySKU(ctx, sku)
+}
+
+func (r *faultyRepository) SearchCount(ctx context.Context, q repository.SearchQuery) (int, error) {
+	if err := DBFault(ctx); err != nil {
+		return 0, err
+	}
+	return r.next.SearchCount(ctx, q)
+}
+
+func (r *faultyRepository) Search(ctx context.Context, q repository.SearchQuery, limit, offset int) ([]models.Product, error) {
+	if err := DBFault(ctx); err != nil {
+		return nil, err
+	}
+	return r.next.Search(ctx, q, limit, offset)
+}
+
+func (r *faultyRepository) FullTextCount(ctx context.Context, keyword string) (int, error) {
+	if err := DBFault(ctx); err != nil {
+		return 0, err
+	}
+	return r.next.FullTextCount(ctx, keyword)
+}
+
+func (r *faultyRepository) FullText(ctx context.Context, keyword string, limit, offset int) ([]models.Product, error) {
+	if err := DBFault(ctx); err != nil {
+		return nil, err
+	}
+	return r.next.FullText(ctx, keyword, limit, offset)
+}
+
+func (r *faultyRepository) Create(ctx context.Context, p *models.Product) error {
+	if err := DBFault(ctx); err != nil {
+		return err
+	}
+	return r.next.Create(ctx, p)
+}
+
+func (r *faultyRepository) CreateBatch(ctx context.Context, products []models.Product) error {
+	if err := DBFault(ctx); err != nil {
+		return err
+	}
+	return r.next.CreateBatch(ctx, products)
+}
+
+func (r *faultyRepository) Update(ctx context.Context, p *models.Product) (*models.Product, error) {
+	if err := DBFault(ctx); err != nil {
+		return nil, err
+	}
+	return r.next.Update(ctx, p)
+}
+
+func (r *faultyRepository) Delete(ctx context.Context, id int) error {
+	if err := DBFault(ctx); err != nil {
+		return err
+	}
+	return r.next.Delete(ctx, id)
+}
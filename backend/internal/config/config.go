@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -10,8 +13,162 @@ type Config struct {
 	Port           string
 	TraceEnabled   bool
 	JaegerEndpoint string
+
+	// 終了時 (SIGTERM) の動作。readyz を 503 にしてから ShutdownDelay だけ待ち (ロードバランサーが
+	// 振り分けを止めるまで)、処理中のリクエストを最大 ShutdownTimeout 待ってから終了する
+	ShutdownDelay   time.Duration
+	ShutdownTimeout time.Duration
+	// readyz で DB に ping するときのタイムアウト
+	ReadyTimeout time.Duration
+
+	// 管理用リスナー (pprof など)。クライアント証明書による認証を必須とする
+	AdminPort     string
+	AdminTLSCert  string
+	AdminTLSKey   string
+	AdminClientCA string
+
+	// 管理用リスナーのルートグループ (admin / metrics / pprof) ごとの接続元 CIDR。空なら制限しない
+	AdminAllowlists map[string][]string
+
+	// 既定の一覧の先頭ページキャッシュ (0 で無効)
+	PageCachePages int
+	PageCacheTTL   time.Duration
+	// リポジトリの読み取りキャッシュ (TTL が 0 で無効)。件数は種類ごとの上限
+	RepoCacheTTL  time.Duration
+	RepoCacheSize int
+	// この大きさ (バイト) 以上のキャッシュエントリは圧縮して保持する (0 で無効)
+	CacheCompressThreshold int
+
+	// products の月次パーティションを何か月先まで作っておくか (0 で無効)
+	PartitionMonthsAhead int
+
+	// アクセスログ (正常なリクエストはサンプリングし、エラーと遅いリクエストは常に記録する)
+	AccessLogSampleRate    float64
+	AccessLogSlowThreshold time.Duration
+	AccessLogBufferSize    int
+
+	// CORS (フロントエンドを別オリジンから配信する場合に設定する)
+	CORSAllowedOrigins   []string
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSMaxAge           time.Duration
+	CORSAllowCredentials bool
+
+	// API キー (クライアント名 → キー)。未設定なら API キー認証は行わない
+	APIKeys map[string]string
+	// nginx が付与する X-Real-IP / X-Forwarded-For をクライアント IP として信頼するか。
+	// 信頼するのは接続元が TrustedProxyCIDRs に含まれるときだけ (空ならヘッダーは読まない)
+	TrustProxyHeaders bool
+	TrustedProxyCIDRs []string
+	// 認証失敗によるロックアウト
+	AuthLockoutThreshold int
+	AuthLockoutBase      time.Duration
+	AuthLockoutMax       time.Duration
+
+	// JWT の署名鍵 (鍵 ID → 秘密鍵) と署名に使う鍵 ID、トークンの有効期間
+	JWTKeys      map[string]string
+	JWTActiveKey string
+	JWTTokenTTL  time.Duration
+
+	// 機密カラムの暗号化鍵 (鍵 ID → base64 の 32 バイト鍵) と暗号化に使う鍵 ID
+	FieldEncryptionKeys      map[string]string
+	FieldEncryptionActiveKey string
+
+	// 異常リクエスト検知 ("off" / "flag" / "throttle")
+	AnomalyMode          string
+	AnomalyThrottleLimit int
+
+	// クライアントごとのレート制限 ("10/s" や "600/m,50" の形式。空なら制限しない)。
+	// ルートごとの設定は "|" 区切りで "GET /api/products/search=5/s,10" の形式 ("off" で除外)
+	RateLimit       string
+	RateLimitRoutes map[string]string
+
+	// セキュリティヘッダー
+	ContentSecurityPolicy string
+	FrameOptions          string
+	ReferrerPolicy        string
+	// パスのプレフィックスごとの CSP ("|" 区切りで "/swagger/=default-src 'self'" の形式)
+	CSPOverrides map[string]string
+	// ETag を付けるルートごとの Cache-Control ("|" 区切りで "GET /api/products=public, max-age=5" の形式)。
+	// 指定しないルートは既定の no-cache (毎回 If-None-Match で再検証させる)
+	CacheControl map[string]string
+
+	// クエリ名ごとのインデックスヒント (例: "products_list=FORCE INDEX (PRIMARY)")
+	IndexHints string
+	// DB の死活確認の間隔 (0 で無効)
+	DBHealthInterval time.Duration
+	// 実行した SQL を JSON Lines で追記するファイル (空なら記録しない)
+	SQLRecordFile string
+	// これ以上かかった SQL をログに出す (0 で無効)
+	SlowQueryThreshold time.Duration
+	// 製品リポジトリの 1 回の操作のタイムアウト。書き込みは一括登録のバッチを含むので長めにする
+	DBReadTimeout  time.Duration
+	DBWriteTimeout time.Duration
+
+	// 製品ページの QR コード。URL の {id} を製品 ID に置き換える。
+	// 誤り訂正レベルは L / M / Q / H、キャッシュは生成済みの画像の件数
+	QRProductURL      string
+	QRDefaultSize     int
+	QRErrorCorrection string
+	QRCacheSize       int
+
+	// 閲覧履歴からのおすすめ。閲覧の記録は X-Visitor-ID を送ったリクエストだけが対象。
+	// 集計ジョブは間隔が 0 で無効、Window より古い閲覧履歴は削除する
+	RecordProductViews bool
+	RecommendInterval  time.Duration
+	RecommendWindow    time.Duration
+	RecommendTopN      int
+
+	// 検索結果を並べ替える外部のスコアリングサービス。URL が空なら並べ替えない。
+	// タイムアウトを過ぎたら元の並び順で返す
+	RerankURL     string
+	RerankTimeout time.Duration
+
+	// 再入荷・値下がりの通知。NotifyInterval が 0 なら配信ワーカーを起動しない。
+	// SMTPAddr が空ならメールは送らずログに出す (開発用)
+	NotifyInterval       time.Duration
+	NotifyUnsubscribeURL string
+	NotifyWebhookSecret  string
+	NotifyWebhookHTTP    bool
+	SMTPAddr             string
+	SMTPFrom             string
+	SMTPUsername         string
+	SMTPPassword         string
+
+	// 検索用テーブルの作り直し (管理用 API)。ID の幅ごとに Concurrency 本並列でコピーする
+	ReindexChunkSize   int
+	ReindexConcurrency int
+	ReindexTimeout     time.Duration
+
+	// タイムセール。RefreshInterval ごとに、開催中と Horizon 以内に始まるセールを読み込む
+	SalesRefreshInterval time.Duration
+	SalesHorizon         time.Duration
+
+	// 製品の変化の SSE (/api/products/events)。何も送らない時間がこれを超えたら接続維持のコメントを送る
+	EventsHeartbeat time.Duration
+
+	// ログのレベル (debug / info / warn / error) と形式 (plain / text / json)。logging.Setup に渡す
+	LogLevel  string
+	LogFormat string
+
+	// 実行環境 ("production" / "staging" / "development")
+	AppEnv string
+	// 障害注入 (検証用。AppEnv が production のときは有効にしても無視する)
+	ChaosEnabled     bool
+	ChaosLatency     time.Duration
+	ChaosLatencyRate float64
+	ChaosDropRate    float64
+	ChaosDBErrorRate float64
+}
+
+// LogSettings はログのレベル (LOG_LEVEL) と形式 (LOG_FORMAT) を返す。
+// 設定の読み込みのログも同じ形式で出せるよう、Load より前に読んで logging.Setup に渡す
+func LogSettings() (level, format string) {
+	return getEnv("LOG_LEVEL", "info"), getEnv("LOG_FORMAT", "plain")
 }
 
+// Load は環境変数から設定を読み込む。SIGHUP による再読み込みでも呼び出される
 func Load() *Config {
 	log.Println("[CONFIG] Loading configuration...")
 
@@ -20,18 +177,267 @@ func Load() *Config {
 		Port:           getEnv("PORT", "8080"),
 		TraceEnabled:   getEnv("TRACE_ENABLED", "false") == "true",
 		JaegerEndpoint: getEnv("JAEGER_ENDPOINT", "http://jaeger:14268/api/traces"),
+		AdminPort:      getEnv("ADMIN_PORT", "8443"),
+		AdminTLSCert:   getEnv("ADMIN_TLS_CERT", ""),
+		AdminTLSKey:    getEnv("ADMIN_TLS_KEY", ""),
+		AdminClientCA:  getEnv("ADMIN_CLIENT_CA", ""),
+		AdminAllowlists: map[string][]string{
+			"admin":   getEnvList("IP_ALLOWLIST_ADMIN", nil),
+			"metrics": getEnvList("IP_ALLOWLIST_METRICS", nil),
+			"pprof":   getEnvList("IP_ALLOWLIST_PPROF", nil),
+		},
+		PageCachePages: getEnvInt("PAGE_CACHE_PAGES", 5),
+		PageCacheTTL:   getEnvDuration("PAGE_CACHE_TTL", 5*time.Second),
+		RepoCacheTTL:   getEnvDurationAllowZero("REPO_CACHE_TTL", 2*time.Second),
+		RepoCacheSize:  getEnvInt("REPO_CACHE_SIZE", 10000),
+
+		CacheCompressThreshold: getEnvInt("CACHE_COMPRESS_THRESHOLD", 4096),
+
+		PartitionMonthsAhead: getEnvInt("PARTITION_MONTHS_AHEAD", 3),
+		IndexHints:           getEnv("INDEX_HINTS", ""),
+		SQLRecordFile:        getEnv("SQL_RECORD_FILE", ""),
+		DBHealthInterval:     getEnvDurationAllowZero("DB_HEALTH_INTERVAL", 5*time.Second),
+		SlowQueryThreshold:   getEnvDuration("SLOW_QUERY_THRESHOLD", 0),
+		DBReadTimeout:        getEnvDuration("DB_READ_TIMEOUT", 5*time.Second),
+		DBWriteTimeout:       getEnvDuration("DB_WRITE_TIMEOUT", 30*time.Second),
+
+		ShutdownDelay:   getEnvDuration("SHUTDOWN_DELAY", 0),
+		ShutdownTimeout: getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+		ReadyTimeout:    getEnvDuration("READY_TIMEOUT", 2*time.Second),
+
+		QRProductURL:      getEnv("QR_PRODUCT_URL", "http://localhost/products/{id}"),
+		QRDefaultSize:     getEnvInt("QR_DEFAULT_SIZE", 256),
+		QRErrorCorrection: getEnv("QR_ERROR_CORRECTION", "M"),
+		QRCacheSize:       getEnvInt("QR_CACHE_SIZE", 1000),
+
+		RecordProductViews: getEnv("RECORD_PRODUCT_VIEWS", "true") == "true",
+		RecommendInterval:  getEnvDurationAllowZero("RECOMMEND_INTERVAL", 10*time.Minute),
+		RecommendWindow:    getEnvDuration("RECOMMEND_WINDOW", 30*24*time.Hour),
+		RecommendTopN:      getEnvInt("RECOMMEND_TOP_N", 20),
+
+		RerankURL:     getEnv("RERANK_URL", ""),
+		RerankTimeout: getEnvDuration("RERANK_TIMEOUT", 100*time.Millisecond),
+
+		NotifyInterval:       getEnvDurationAllowZero("NOTIFY_INTERVAL", 30*time.Second),
+		NotifyUnsubscribeURL: getEnv("NOTIFY_UNSUBSCRIBE_URL", "http://localhost/alerts/unsubscribe?token={token}"),
+		NotifyWebhookSecret:  getEnv("NOTIFY_WEBHOOK_SECRET", ""),
+		NotifyWebhookHTTP:    getEnv("NOTIFY_WEBHOOK_ALLOW_HTTP", "false") == "true",
+		SMTPAddr:             getEnv("SMTP_ADDR", ""),
+		SMTPFrom:             getEnv("SMTP_FROM", "noreply@example.com"),
+		SMTPUsername:         getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:         getEnv("SMTP_PASSWORD", ""),
+
+		ReindexChunkSize:   getEnvInt("REINDEX_CHUNK_SIZE", 10000),
+		ReindexConcurrency: getEnvInt("REINDEX_CONCURRENCY", 4),
+		ReindexTimeout:     getEnvDuration("REINDEX_TIMEOUT", time.Hour),
+
+		SalesRefreshInterval: getEnvDuration("SALES_REFRESH_INTERVAL", 5*time.Second),
+		SalesHorizon:         getEnvDuration("SALES_HORIZON", 10*time.Minute),
+		EventsHeartbeat:      getEnvDuration("EVENTS_HEARTBEAT", 15*time.Second),
+
+		AccessLogSampleRate:    getEnvFloat("ACCESS_LOG_SAMPLE_RATE", 1.0),
+		AccessLogSlowThreshold: getEnvDuration("ACCESS_LOG_SLOW_THRESHOLD", 500*time.Millisecond),
+		AccessLogBufferSize:    getEnvInt("ACCESS_LOG_BUFFER_SIZE", 4096),
+
+		CORSAllowedOrigins:   getEnvList("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		CORSAllowedMethods:   getEnvList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+		CORSAllowedHeaders:   getEnvList("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", "X-API-Key", "X-Request-ID", "X-Visitor-ID", "If-None-Match"}),
+		CORSExposedHeaders:   getEnvList("CORS_EXPOSED_HEADERS", []string{"X-Request-ID", "ETag"}),
+		CORSMaxAge:           getEnvDuration("CORS_MAX_AGE", 10*time.Minute),
+		CORSAllowCredentials: getEnv("CORS_ALLOW_CREDENTIALS", "false") == "true",
+
+		APIKeys:              getEnvKeyValues("API_KEYS"),
+		TrustProxyHeaders:    getEnv("TRUST_PROXY_HEADERS", "false") == "true",
+		TrustedProxyCIDRs:    getEnvList("TRUSTED_PROXY_CIDRS", nil),
+		AuthLockoutThreshold: getEnvInt("AUTH_LOCKOUT_THRESHOLD", 5),
+		AuthLockoutBase:      getEnvDuration("AUTH_LOCKOUT_BASE", 30*time.Second),
+		AuthLockoutMax:       getEnvDuration("AUTH_LOCKOUT_MAX", 15*time.Minute),
+
+		JWTKeys:      getEnvKeyValues("JWT_KEYS"),
+		JWTActiveKey: getEnv("JWT_ACTIVE_KEY", ""),
+		JWTTokenTTL:  getEnvDuration("JWT_TOKEN_TTL", time.Hour),
+
+		FieldEncryptionKeys:      getEnvKeyValues("FIELD_ENCRYPTION_KEYS"),
+		FieldEncryptionActiveKey: getEnv("FIELD_ENCRYPTION_ACTIVE_KEY", "1"),
+
+		AnomalyMode:          getEnv("ANOMALY_MODE", "flag"),
+		AnomalyThrottleLimit: getEnvInt("ANOMALY_THROTTLE_LIMIT", 20),
+		RateLimit:            getEnv("RATE_LIMIT", ""),
+		RateLimitRoutes:      getEnvPrefixMap("RATE_LIMIT_ROUTES", map[string]string{}),
+
+		ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", "default-src 'none'; frame-ancestors 'none'"),
+		FrameOptions:          getEnv("FRAME_OPTIONS", "DENY"),
+		ReferrerPolicy:        getEnv("REFERRER_POLICY", "no-referrer"),
+		CSPOverrides: getEnvPrefixMap("CSP_OVERRIDES", map[string]string{
+			"/swagger/": "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:",
+			"/admin/":   "default-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' data:; frame-ancestors 'none'",
+		}),
+		CacheControl: getEnvPrefixMap("CACHE_CONTROL", map[string]string{}),
+
+		AppEnv:           getEnv("APP_ENV", "production"),
+		ChaosEnabled:     getEnv("CHAOS_ENABLED", "false") == "true",
+		ChaosLatency:     getEnvDuration("CHAOS_LATENCY", 500*time.Millisecond),
+		ChaosLatencyRate: getEnvFloat("CHAOS_LATENCY_RATE", 0),
+		ChaosDropRate:    getEnvFloat("CHAOS_DROP_RATE", 0),
+		ChaosDBErrorRate: getEnvFloat("CHAOS_DB_ERROR_RATE", 0),
 	}
+	cfg.LogLevel, cfg.LogFormat = LogSettings()
 
 	log.Printf("[CONFIG] Port: %s", cfg.Port)
 	log.Printf("[CONFIG] TraceEnabled: %t", cfg.TraceEnabled)
 	log.Printf("[CONFIG] JaegerEndpoint: %s", cfg.JaegerEndpoint)
+	log.Printf("[CONFIG] Shutdown: delay=%v, timeout=%v, ready_timeout=%v", cfg.ShutdownDelay, cfg.ShutdownTimeout, cfg.ReadyTimeout)
+	log.Printf("[CONFIG] AdminPort: %s (mTLS: %t)", cfg.AdminPort, cfg.AdminClientCA != "")
+	log.Printf("[CONFIG] PageCache: pages=%d, ttl=%v, compress_threshold=%d", cfg.PageCachePages, cfg.PageCacheTTL, cfg.CacheCompressThreshold)
+	log.Printf("[CONFIG] RepoCache: ttl=%v, size=%d", cfg.RepoCacheTTL, cfg.RepoCacheSize)
+	log.Printf("[CONFIG] PartitionMonthsAhead: %d", cfg.PartitionMonthsAhead)
+	log.Printf("[CONFIG] SlowQueryThreshold: %v", cfg.SlowQueryThreshold)
+	log.Printf("[CONFIG] DBTimeouts: read=%v, write=%v", cfg.DBReadTimeout, cfg.DBWriteTimeout)
+	log.Printf("[CONFIG] Recommend: record_views=%t, interval=%v, window=%v, top=%d", cfg.RecordProductViews, cfg.RecommendInterval, cfg.RecommendWindow, cfg.RecommendTopN)
+	log.Printf("[CONFIG] Rerank: url=%q, timeout=%v", cfg.RerankURL, cfg.RerankTimeout)
+	log.Printf("[CONFIG] Reindex: chunk=%d, concurrency=%d, timeout=%v", cfg.ReindexChunkSize, cfg.ReindexConcurrency, cfg.ReindexTimeout)
+	log.Printf("[CONFIG] Sales: refresh=%v, horizon=%v", cfg.SalesRefreshInterval, cfg.SalesHorizon)
+	log.Printf("[CONFIG] Events: heartbeat=%v", cfg.EventsHeartbeat)
+	log.Printf("[CONFIG] Notify: interval=%v, smtp=%q, webhook_signed=%t", cfg.NotifyInterval, cfg.SMTPAddr, cfg.NotifyWebhookSecret != "")
+	log.Printf("[CONFIG] APIKeys: %d clients", len(cfg.APIKeys))
+	log.Printf("[CONFIG] ProxyHeaders: trusted=%t, proxies=%v", cfg.TrustProxyHeaders, cfg.TrustedProxyCIDRs)
+	if cfg.TrustProxyHeaders && len(cfg.TrustedProxyCIDRs) == 0 {
+		log.Printf("[CONFIG] TRUST_PROXY_HEADERS is set without TRUSTED_PROXY_CIDRS; forwarded headers are ignored")
+	}
+	log.Printf("[CONFIG] JWT: keys=%d, active=%q, ttl=%v", len(cfg.JWTKeys), cfg.JWTActiveKey, cfg.JWTTokenTTL)
+	log.Printf("[CONFIG] CacheControl: %d routes", len(cfg.CacheControl))
+	log.Printf("[CONFIG] RateLimit: default=%q, routes=%d", cfg.RateLimit, len(cfg.RateLimitRoutes))
+	log.Printf("[CONFIG] AccessLog: sample_rate=%.2f, slow=%v", cfg.AccessLogSampleRate, cfg.AccessLogSlowThreshold)
+	log.Printf("[CONFIG] Log: level=%s, format=%s", cfg.LogLevel, cfg.LogFormat)
+	log.Printf("[CONFIG] AppEnv: %s (chaos: %t)", cfg.AppEnv, cfg.ChaosEnabled)
 
 	return cfg
 }
 
+// ChaosActive は障害注入を有効にするかを返す。本番環境では設定にかかわらず無効
+func (c *Config) ChaosActive() bool {
+	return c.ChaosEnabled && c.AppEnv != "production"
+}
+
+// lookupEnv は環境変数を読む。KEY_FILE が設定されていればそのファイルの内容を使う
+// (Docker secrets などで渡した資格情報を、再起動せずに Load し直せるようにするため)
+func lookupEnv(key string) string {
+	if path := os.Getenv(key + "_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err == nil {
+			return strings.TrimSpace(string(b))
+		}
+		log.Printf("[CONFIG] Failed to read %s_FILE: %v", key, err)
+	}
+	return os.Getenv(key)
+}
+
 func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+	if value := lookupEnv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	value := lookupEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("[CONFIG] Invalid %s=%q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := lookupEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("[CONFIG] Invalid %s=%q, using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvDurationAllowZero は 0 を「無効」として受け付ける getEnvDuration。負の値だけを既定値に戻す
+func getEnvDurationAllowZero(key string, defaultValue time.Duration) time.Duration {
+	value := lookupEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Printf("[CONFIG] Invalid %s=%q, using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return d
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := lookupEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("[CONFIG] Invalid %s=%q, using default %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return f
+}
+
+// getEnvList はカンマ区切りの値を読み取る
+func getEnvList(key string, defaultValue []string) []string {
+	value := lookupEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var list []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	if len(list) == 0 {
+		return defaultValue
+	}
+	return list
+}
+
+// getEnvPrefixMap は "|" 区切りの "prefix=value" を読み取る。値には ";" や空白を含められる
+func getEnvPrefixMap(key string, defaultValue map[string]string) map[string]string {
+	value := lookupEnv(key)
+	if value == "" {
+		return defaultValue
+	}
+	m := map[string]string{}
+	for _, entry := range strings.Split(value, "|") {
+		prefix, v, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || prefix == "" {
+			log.Printf("[CONFIG] Ignoring invalid %s entry: %q", key, entry)
+			continue
+		}
+		m[prefix] = strings.TrimSpace(v)
+	}
+	return m
+}
+
+// getEnvKeyValues はカンマ区切りの "name:value" を読み取る
+func getEnvKeyValues(key string) map[string]string {
+	m := map[string]string{}
+	for _, entry := range getEnvList(key, nil) {
+		name, value, ok := strings.Cut(entry, ":")
+		if !ok || name == "" || value == "" {
+			log.Printf("[CONFIG] Ignoring invalid %s entry", key)
+			continue
+		}
+		m[name] = value
+	}
+	return m
+}
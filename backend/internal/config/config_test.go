@@ -0,0 +1,57 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetEnvDurationAllowZero(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 2 * time.Second},
+		{"0", 0},
+		{"0s", 0},
+		{"500ms", 500 * time.Millisecond},
+		{"-1s", 2 * time.Second},
+		{"soon", 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Setenv("TEST_DURATION", tt.value)
+		if got := getEnvDurationAllowZero("TEST_DURATION", 2*time.Second); got != tt.want {
+			t.Errorf("getEnvDurationAllowZero(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+// 「0 で無効」と書いた設定は 0 を既定値に戻さない
+func TestLoadZeroDisables(t *testing.T) {
+	tests := []struct {
+		key string
+		get func(*Config) time.Duration
+	}{
+		{"REPO_CACHE_TTL", func(c *Config) time.Duration { return c.RepoCacheTTL }},
+		{"NOTIFY_INTERVAL", func(c *Config) time.Duration { return c.NotifyInterval }},
+		{"RECOMMEND_INTERVAL", func(c *Config) time.Duration { return c.RecommendInterval }},
+		{"DB_HEALTH_INTERVAL", func(c *Config) time.Duration { return c.DBHealthInterval }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			t.Setenv(tt.key, "0")
+			if got := tt.get(Load()); got != 0 {
+				t.Errorf("%s=0 loaded as %v, want 0", tt.key, got)
+			}
+		})
+	}
+}
+
+// API キーで認証するクライアントもブラウザから呼べるよう、既定で X-API-Key のプリフライトを許可する
+func TestCORSAllowedHeadersDefault(t *testing.T) {
+	for _, h := range Load().CORSAllowedHeaders {
+		if h == "X-API-Key" {
+			return
+		}
+	}
+	t.Error("default CORS_ALLOWED_HEADERS does not include X-API-Key")
+}
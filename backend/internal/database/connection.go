@@ -2,29 +2,55 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 
 	"sample-backend/internal/config"
 )
 
+// コネクションプールの設定
+const (
+	maxOpenConns    = 25
+	maxIdleConns    = 10
+	connMaxLifetime = 5 * time.Minute
+	// 使われていない接続は早めに閉じ、MySQL 側で切られた接続を掴みにくくする
+	connMaxIdleTime = time.Minute
+)
+
+func buildDSN(databaseURL string) string {
+	return fmt.Sprintf("%s?charset=utf8mb4&parseTime=True&loc=Asia%%2FTokyo", databaseURL)
+}
+
 func Connect(cfg *config.Config) (*sqlx.DB, error) {
 	log.Println("[DB] Initializing database connection...")
 
-	dsn := fmt.Sprintf("%s?charset=utf8mb4&parseTime=True&loc=Asia%%2FTokyo", cfg.DatabaseURL)
+	dsn := buildDSN(cfg.DatabaseURL)
 	log.Printf("[DB] Using DSN: %s", strings.ReplaceAll(dsn, "mysql", "mysql://***:***"))
 
-	dbConn, err := sqlx.Open("mysql", dsn)
+	// 資格情報をローテーションできるよう、接続ごとに現在の DSN を使う connector で開く
+	rc, err := newRotatingConnector(dsn)
 	if err != nil {
 		log.Printf("[DB ERROR] Failed to open database connection: %v", err)
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
 
+	// 実行した SQL の記録 (インデックス変更のオフライン評価用に cmd/sqlreplay で再生できる)
+	observers := append([]QueryObserver(nil), queryObservers...)
+	if cfg.SQLRecordFile != "" {
+		rec, err := newQueryRecorder(cfg.SQLRecordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open SQL record file: %w", err)
+		}
+		observers = append(observers, rec.record)
+	}
+	rc.observe = combineObservers(observers)
+	dbConn := sqlx.NewDb(sql.OpenDB(rc), "mysql")
+
 	// 接続テスト（タイムアウト付き）
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -46,9 +72,11 @@ func Connect(cfg *config.Config) (*sqlx.DB, error) {
 	}
 
 	// コネクションプールの設定
-	dbConn.SetMaxOpenConns(25)
-	dbConn.SetMaxIdleConns(10)
-	dbConn.SetConnMaxLifetime(5 * time.Minute)
+	dbConn.SetMaxOpenConns(maxOpenConns)
+	dbConn.SetMaxIdleConns(maxIdleConns)
+	dbConn.SetConnMaxLifetime(connMaxLifetime)
+	dbConn.SetConnMaxIdleTime(connMaxIdleTime)
+	connector = rc
 
 	log.Println("[DB] Database connection established successfully")
 	return dbConn, nil
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+
+	"sample-backend/internal/apperr"
+)
+
+// MySQL のエラー番号
+const (
+	erDupEntry        = 1062
+	erConCount        = 1040 // Too many connections
+	erLockWaitTimeout = 1205
+)
+
+// Classify は DB から返ったエラーを apperr の分類に対応付ける。
+// 接続できない・タイムアウトは ErrUnavailable、一意制約違反は ErrConflict とし、それ以外はそのまま返す
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case erDupEntry:
+			return apperr.Conflict("Already exists", err)
+		case erConCount, erLockWaitTimeout:
+			return apperr.Unavailable("Database unavailable", err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, sql.ErrConnDone) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &netErr) {
+		return apperr.Unavailable("Database unavailable", err)
+	}
+	return err
+}
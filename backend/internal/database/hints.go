@@ -0,0 +1,47 @@
+package database
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// インデックスヒントとして受け付ける形式 (SQL インジェクション防止のため厳密に制限する)
+var indexHintPattern = regexp.MustCompile(`(?i)^(FORCE|USE|IGNORE) INDEX( FOR (JOIN|ORDER BY|GROUP BY))? \(\s*[A-Za-z0-9_]+(\s*,\s*[A-Za-z0-9_]+)*\s*\)$`)
+
+var (
+	hintsMu    sync.RWMutex
+	indexHints = map[string]string{}
+)
+
+// LoadIndexHints はクエリ名ごとのインデックスヒントを読み込む。
+// 形式は "products_list=FORCE INDEX (PRIMARY);search_count=USE INDEX (idx_name)"
+func LoadIndexHints(spec string) {
+	hints := map[string]string{}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, hint, ok := strings.Cut(entry, "=")
+		name, hint = strings.TrimSpace(name), strings.TrimSpace(hint)
+		if !ok || name == "" || !indexHintPattern.MatchString(hint) {
+			log.Printf("[DB] Ignoring invalid index hint: %q", entry)
+			continue
+		}
+		hints[name] = hint
+		log.Printf("[DB] Index hint for %s: %s", name, hint)
+	}
+
+	hintsMu.Lock()
+	indexHints = hints
+	hintsMu.Unlock()
+}
+
+// IndexHint はクエリ名に設定されたヒントを返す。未設定なら空文字
+func IndexHint(name string) string {
+	hintsMu.RLock()
+	defer hintsMu.RUnlock()
+	return indexHints[name]
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// 異常時に確認する間隔 (正常時は Monitor の interval)
+	monitorRetryInterval = time.Second
+	monitorPingTimeout   = 2 * time.Second
+)
+
+// Monitor は定期的に DB へ ping し、接続断やプールの枯渇を検知して登録したフックに通知する。
+// 接続断を検知したらアイドル接続を捨て、次の問い合わせで新しい接続を張り直させる。
+// database/sql の Stmt は接続ごとに準備し直されるため、プリペアドステートメントの作り直しは不要
+type Monitor struct {
+	db       *sqlx.DB
+	interval time.Duration
+
+	mu       sync.Mutex
+	healthy  bool
+	reason   string
+	hooks    []func(healthy bool, reason string)
+	lastWait int64
+}
+
+func NewMonitor(db *sqlx.DB, interval time.Duration) *Monitor {
+	return &Monitor{db: db, interval: interval, healthy: true}
+}
+
+// OnChange は状態が変わったときに呼ぶ関数を登録する。Start より前に呼び出す
+func (m *Monitor) OnChange(fn func(healthy bool, reason string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, fn)
+}
+
+// Healthy は直近の確認で DB が使える状態だったかを返す
+func (m *Monitor) Healthy() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.healthy
+}
+
+// Start は確認のゴルーチンを起動する。異常の間は短い間隔で確認し続ける
+func (m *Monitor) Start() {
+	log.Printf("[DB] Health monitor enabled - interval: %v", m.interval)
+	go func() {
+		for {
+			delay := m.interval
+			if !m.check() {
+				delay = monitorRetryInterval
+			}
+			time.Sleep(delay)
+		}
+	}()
+}
+
+func (m *Monitor) check() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), monitorPingTimeout)
+	defer cancel()
+
+	if err := m.db.PingContext(ctx); err != nil {
+		m.set(false, "ping failed: "+err.Error())
+		// MySQL の再起動などで切れたアイドル接続を捨てて張り直させる
+		m.db.SetMaxIdleConns(0)
+		m.db.SetMaxIdleConns(maxIdleConns)
+		return false
+	}
+
+	// 前回から待ちが発生していて全接続が使用中なら、プールが枯渇している
+	stats := m.db.Stats()
+	waited := stats.WaitCount > m.lastWait
+	m.lastWait = stats.WaitCount
+	if waited && stats.InUse >= stats.MaxOpenConnections {
+		m.set(false, "connection pool exhausted")
+		return false
+	}
+
+	m.set(true, "")
+	return true
+}
+
+func (m *Monitor) set(healthy bool, reason string) {
+	m.mu.Lock()
+	changed := m.healthy != healthy || m.reason != reason
+	m.healthy, m.reason = healthy, reason
+	hooks := m.hooks
+	m.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if healthy {
+		log.Println("[DB] Database connection recovered")
+	} else {
+		log.Printf("[DB ERROR] Database unhealthy: %s", reason)
+	}
+	for _, fn := range hooks {
+		fn(healthy, reason)
+	}
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// partitionCheckInterval はパーティションの過不足を確認する間隔
+const partitionCheckInterval = 24 * time.Hour
+
+// StartPartitionMaintenance は products の月次パーティションを monthsAhead か月先まで
+// 用意しておくジョブを起動する。テーブルがパーティション化されていなければ何もしない
+func StartPartitionMaintenance(db *sqlx.DB, monthsAhead int) {
+	if monthsAhead <= 0 {
+		log.Println("[DB] Partition maintenance disabled")
+		return
+	}
+
+	run := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if err := ensurePartitions(ctx, db, monthsAhead); err != nil {
+			log.Printf("[DB ERROR] Partition maintenance failed: %v", err)
+		}
+	}
+
+	run()
+	go func() {
+		ticker := time.NewTicker(partitionCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
+}
+
+func ensurePartitions(ctx context.Context, db *sqlx.DB, monthsAhead int) error {
+	var hasMax int
+	err := db.GetContext(ctx, &hasMax, `SELECT COUNT(*) FROM information_schema.PARTITIONS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'products' AND PARTITION_NAME = 'pmax'`)
+	if err != nil {
+		return fmt.Errorf("failed to read partitions: %w", err)
+	}
+	if hasMax == 0 {
+		log.Println("[DB] products is not partitioned, skipping partition maintenance")
+		return nil
+	}
+
+	// 境界値は UNIX 時刻なので、DB のセッションタイムゾーンで日時に戻して扱う
+	var state struct {
+		LastBound sql.NullTime `db:"last_bound"`
+		Now       time.Time    `db:"now"`
+	}
+	err = db.GetContext(ctx, &state, `SELECT MAX(FROM_UNIXTIME(PARTITION_DESCRIPTION)) AS last_bound, NOW() AS now
+		FROM information_schema.PARTITIONS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'products' AND PARTITION_DESCRIPTION <> 'MAXVALUE'`)
+	if err != nil {
+		return fmt.Errorf("failed to read partition bounds: %w", err)
+	}
+
+	lastBound := firstOfMonth(state.Now)
+	if state.LastBound.Valid {
+		lastBound = state.LastBound.Time
+	}
+
+	target := firstOfMonth(state.Now).AddDate(0, monthsAhead+1, 0)
+	var defs []string
+	for bound := lastBound; bound.Before(target); {
+		next := firstOfMonth(bound).AddDate(0, 1, 0)
+		defs = append(defs, fmt.Sprintf("PARTITION p%s VALUES LESS THAN (UNIX_TIMESTAMP('%s'))",
+			bound.Format("200601"), next.Format("2006-01-02 15:04:05")))
+		bound = next
+	}
+	if len(defs) == 0 {
+		log.Printf("[DB] Partitions are up to date (until %s)", lastBound.Format("2006-01-02"))
+		return nil
+	}
+
+	defs = append(defs, "PARTITION pmax VALUES LESS THAN MAXVALUE")
+	ddl := fmt.Sprintf("ALTER TABLE products REORGANIZE PARTITION pmax INTO (%s)", strings.Join(defs, ", "))
+	log.Printf("[DB] Creating %d partitions up to %s", len(defs)-1, target.Format("2006-01-02"))
+	if _, err := db.ExecContext(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to reorganize partitions: %w", err)
+	}
+	return nil
+}
+
+func firstOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
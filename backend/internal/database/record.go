@@ -0,0 +1,277 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// RecordedQuery は記録した 1 回分の SQL 実行 (JSON Lines の 1 行)。
+// 引数の []byte は文字列、時刻は RFC3339 の文字列として保存する
+type RecordedQuery struct {
+	Time       time.Time     `json:"time"`
+	Kind       string        `json:"kind"` // "query" または "exec"
+	Query      string        `json:"query"`
+	Args       []interface{} `json:"args,omitempty"`
+	DurationUS int64         `json:"duration_us"`
+	Rows       int64         `json:"rows"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// QueryObserver は SQL を実行するたびに呼ばれる。rows は更新系なら影響行数、参照系なら読んだ行数
+type QueryObserver func(ctx context.Context, kind, query string, args []driver.NamedValue, start time.Time, rows int64, err error)
+
+// queryObservers は ObserveQueries で登録された通知先
+var queryObservers []QueryObserver
+
+// ObserveQueries は実行した SQL の通知先を登録する。Connect より前に呼び出すこと
+func ObserveQueries(fn QueryObserver) {
+	queryObservers = append(queryObservers, fn)
+}
+
+// combineObservers は複数の通知先を 1 つにまとめる。通知先がなければ nil
+func combineObservers(observers []QueryObserver) QueryObserver {
+	switch len(observers) {
+	case 0:
+		return nil
+	case 1:
+		return observers[0]
+	}
+	return func(ctx context.Context, kind, query string, args []driver.NamedValue, start time.Time, rows int64, err error) {
+		for _, o := range observers {
+			o(ctx, kind, query, args, start, rows, err)
+		}
+	}
+}
+
+// queryRecorder は実行した SQL をリクエストのゴルーチンから切り離してファイルに書き出す
+type queryRecorder struct {
+	entries chan RecordedQuery
+	dropped atomic.Int64
+}
+
+func newQueryRecorder(path string) (*queryRecorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &queryRecorder{entries: make(chan RecordedQuery, 4096)}
+	go r.run(f)
+
+	log.Printf("[DB] Recording executed SQL to %s", path)
+	return r, nil
+}
+
+func (r *queryRecorder) run(w io.Writer) {
+	enc := json.NewEncoder(w)
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e := <-r.entries:
+			if err := enc.Encode(e); err != nil {
+				log.Printf("[DB ERROR] Failed to write recorded query: %v", err)
+			}
+		case <-ticker.C:
+			if n := r.dropped.Swap(0); n > 0 {
+				log.Printf("[DB] Dropped %d recorded queries (buffer full)", n)
+			}
+		}
+	}
+}
+
+func (r *queryRecorder) record(_ context.Context, kind, query string, args []driver.NamedValue, start time.Time, rows int64, err error) {
+	e := RecordedQuery{
+		Time:       start,
+		Kind:       kind,
+		Query:      query,
+		DurationUS: time.Since(start).Microseconds(),
+		Rows:       rows,
+	}
+	for _, a := range args {
+		switch v := a.Value.(type) {
+		case []byte:
+			e.Args = append(e.Args, string(v))
+		case time.Time:
+			e.Args = append(e.Args, v.Format(time.RFC3339Nano))
+		default:
+			e.Args = append(e.Args, v)
+		}
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	select {
+	case r.entries <- e:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// recordingConn は driver.Conn をラップして実行した SQL を通知する。
+// 元の接続が実装しているオプションのインターフェースはそのまま委譲する
+type recordingConn struct {
+	driver.Conn
+	observe QueryObserver
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *recordingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = pc.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &recordingStmt{Stmt: stmt, query: query, observe: c.observe}, nil
+}
+
+func (c *recordingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := ec.ExecContext(ctx, query, args)
+	// ErrSkip のときは database/sql がプリペアドステートメントで実行し直すので、そちらで記録する
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+	var affected int64
+	if err == nil {
+		affected, _ = res.RowsAffected()
+	}
+	c.observe(ctx, "exec", query, args, start, affected, err)
+	return res, err
+}
+
+func (c *recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := qc.QueryContext(ctx, query, args)
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+	if err != nil {
+		c.observe(ctx, "query", query, args, start, 0, err)
+		return nil, err
+	}
+	return &recordingRows{Rows: rows, ctx: ctx, query: query, args: args, start: start, observe: c.observe}, nil
+}
+
+func (c *recordingConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *recordingConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *recordingConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *recordingConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// recordingStmt はプリペアドステートメントの実行を通知する
+type recordingStmt struct {
+	driver.Stmt
+	query   string
+	observe QueryObserver
+}
+
+func (s *recordingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	res, err := s.Stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+	var affected int64
+	if err == nil {
+		affected, _ = res.RowsAffected()
+	}
+	s.observe(ctx, "exec", s.query, args, start, affected, err)
+	return res, err
+}
+
+func (s *recordingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	rows, err := s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+	if err != nil {
+		s.observe(ctx, "query", s.query, args, start, 0, err)
+		return nil, err
+	}
+	return &recordingRows{Rows: rows, ctx: ctx, query: s.query, args: args, start: start, observe: s.observe}, nil
+}
+
+func (s *recordingStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// recordingRows は結果を読み終えて閉じるまでを実行時間として通知する
+type recordingRows struct {
+	driver.Rows
+	ctx     context.Context
+	query   string
+	args    []driver.NamedValue
+	start   time.Time
+	observe QueryObserver
+	rows    int64
+	err     error
+}
+
+func (r *recordingRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err == nil {
+		r.rows++
+	} else if err != io.EOF {
+		r.err = err
+	}
+	return err
+}
+
+func (r *recordingRows) Close() error {
+	err := r.Rows.Close()
+	r.observe(r.ctx, "query", r.query, r.args, r.start, r.rows, r.err)
+	return err
+}
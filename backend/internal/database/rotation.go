@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// rotatingConnector は新しい接続を張るたびに現在の DSN の connector を使う。
+// 資格情報を差し替えても *sqlx.DB はそのまま使え、既存の接続は返却後に順次張り直される
+type rotatingConnector struct {
+	current atomic.Pointer[driver.Connector]
+	// 設定されていれば実行した SQL を通知する (記録ファイルや ObserveQueries の通知先)
+	observe QueryObserver
+}
+
+func newRotatingConnector(dsn string) (*rotatingConnector, error) {
+	c := &rotatingConnector{}
+	connector, err := newMySQLConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	c.current.Store(&connector)
+	return c, nil
+}
+
+func (c *rotatingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := (*c.current.Load()).Connect(ctx)
+	if err != nil || c.observe == nil {
+		return conn, err
+	}
+	return &recordingConn{Conn: conn, observe: c.observe}, nil
+}
+
+func (c *rotatingConnector) Driver() driver.Driver {
+	return mysql.MySQLDriver{}
+}
+
+func newMySQLConnector(dsn string) (driver.Connector, error) {
+	mysqlCfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN: %w", err)
+	}
+	return mysql.NewConnector(mysqlCfg)
+}
+
+// connector は Connect で作った DB の connector (プロセスで 1 つ)
+var connector *rotatingConnector
+
+// RotateCredentials は新しい DATABASE_URL で接続できることを確かめてから切り替え、
+// アイドル中の古い接続を閉じる。処理中の接続は返却され次第、新しい資格情報で張り直される
+func RotateCredentials(db *sqlx.DB, databaseURL string) error {
+	if connector == nil {
+		return fmt.Errorf("database is not connected")
+	}
+
+	next, err := newMySQLConnector(buildDSN(databaseURL))
+	if err != nil {
+		return err
+	}
+
+	// 切り替える前に新しい資格情報で 1 本接続してみる
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := next.Connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect with new credentials: %w", err)
+	}
+	conn.Close()
+
+	connector.current.Store(&next)
+
+	// アイドル接続を捨てて新しい資格情報の接続に入れ替えさせる
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(maxIdleConns)
+
+	log.Println("[DB] Database credentials rotated")
+	return nil
+}
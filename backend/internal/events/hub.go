@@ -0,0 +1,153 @@
+// Package events は製品の変化 (登録・更新・削除・在庫) をプロセス内で配る購読の仕組み。
+// 製品を書き換えるサービスが Hub.Publish で知らせ、SSE のハンドラーなどが Subscribe で受け取る。
+// 配るのは同じプロセスで起きた変化だけなので、複数台で動かす場合は台ごとに別の流れになる
+package events
+
+import (
+	"sync"
+	"time"
+
+	"sample-backend/internal/models"
+)
+
+// イベントの種類
+const (
+	ProductCreated = "product.created"
+	ProductUpdated = "product.updated"
+	ProductDeleted = "product.deleted"
+	// 入荷・引き当てによる在庫数の変化
+	ProductStockChanged = "product.stock_changed"
+	// 一括登録。製品ごとには配らず件数だけを知らせる
+	ProductsImported = "products.imported"
+)
+
+// Event は製品の 1 回の変化。ID は Hub が振る通し番号 (SSE の id に使う)
+type Event struct {
+	ID        uint64          `json:"id"`
+	Type      string          `json:"type"`
+	ProductID int             `json:"product_id,omitempty"`
+	Product   *models.Product `json:"product,omitempty"`
+	// 更新で価格が変わった場合の変更前の価格
+	PreviousPrice *float64 `json:"previous_price,omitempty"`
+	// 在庫の変化の後の在庫数
+	Stock *int `json:"stock,omitempty"`
+	// 一括登録で登録した件数
+	Count int       `json:"count,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+const (
+	// replaySize は再接続した購読者に送り直すために覚えておく直近のイベント数
+	replaySize = 256
+	// subscriberBuffer は購読者ごとに溜められるイベント数。溢れた購読者は切断し、再接続で送り直させる
+	subscriberBuffer = 64
+)
+
+// Subscription は 1 つの購読。C が閉じたら購読は終わり (Hub を閉じたか、受け取りが追いつかなかった)
+type Subscription struct {
+	C   <-chan Event
+	c   chan Event
+	hub *Hub
+}
+
+// Close は購読をやめる。何度呼んでもよい
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// Hub は購読者の一覧と直近のイベント。nil の Hub では Publish は何もしない
+type Hub struct {
+	mu     sync.Mutex
+	nextID uint64
+	recent []Event
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: map[*Subscription]struct{}{}}
+}
+
+// Publish は ID と時刻を付けてイベントを購読者に配る。書き込みのリクエストを待たせないよう、
+// 受け取りが追いつかない購読者は待たずに切断する
+func (h *Hub) Publish(e Event) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.nextID++
+	e.ID = h.nextID
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+	if len(h.recent) >= replaySize {
+		h.recent = append(h.recent[:0], h.recent[1:]...)
+	}
+	h.recent = append(h.recent, e)
+
+	for s := range h.subs {
+		select {
+		case s.c <- e:
+		default:
+			delete(h.subs, s)
+			close(s.c)
+		}
+	}
+}
+
+// Subscribe は購読を始める。lastID が 0 でなければ、覚えている範囲でそれより後のイベントを先に返す
+// (SSE の Last-Event-ID。覚えている範囲より古ければ送り直せた分だけになる)。Hub を閉じた後は nil
+func (h *Hub) Subscribe(lastID uint64) (*Subscription, []Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, nil
+	}
+	var missed []Event
+	if lastID > 0 {
+		for _, e := range h.recent {
+			if e.ID > lastID {
+				missed = append(missed, e)
+			}
+		}
+	}
+	c := make(chan Event, subscriberBuffer)
+	s := &Subscription{C: c, c: c, hub: h}
+	h.subs[s] = struct{}{}
+	return s, missed
+}
+
+// Subscribers は購読者の数を返す
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Close はすべての購読を終わらせ、以降の Publish と Subscribe を無視する。
+// 終了時に呼び、開いたままの SSE の接続がサーバーの停止を妨げないようにする
+func (h *Hub) Close() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for s := range h.subs {
+		delete(h.subs, s)
+		close(s.c)
+	}
+}
+
+func (h *Hub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.c)
+	}
+}
@@ -0,0 +1,119 @@
+// Package fieldcrypt は機密性の高いカラム (仕入れ原価や取引先の連絡先など) を
+// AES-GCM で暗号化して保存するための型を提供する。
+// 暗号文は保存先のテーブル・列・行 (AAD) に結び付けるため、String / Float64 は Valuer / Scanner ではなく、
+// リポジトリが行を特定したうえで Seal / Open を呼ぶ。ハンドラーやサービスは平文の型として扱えばよい
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+var (
+	ErrNoKeyring     = errors.New("field encryption key is not configured")
+	ErrUnknownKey    = errors.New("ciphertext was encrypted with an unknown key")
+	ErrMalformedData = errors.New("malformed ciphertext")
+)
+
+// Keyring は鍵 ID ごとの AES-GCM を持つ。暗号化は有効な鍵で行い、
+// 復号は暗号文の先頭に記録された鍵 ID で行うため、古い鍵で暗号化された値も読める
+type Keyring struct {
+	active byte
+	aeads  map[byte]cipher.AEAD
+}
+
+// NewKeyring は 鍵 ID (0〜255) → base64 の 32 バイト鍵 から Keyring を作る
+func NewKeyring(keys map[string]string, active string) (*Keyring, error) {
+	k := &Keyring{aeads: make(map[byte]cipher.AEAD, len(keys))}
+	for id, encoded := range keys {
+		n, err := strconv.ParseUint(id, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) != 32 {
+			return nil, fmt.Errorf("key %s must be 32 bytes encoded in base64", id)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[byte(n)] = aead
+	}
+
+	n, err := strconv.ParseUint(active, 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid active key id %q", active)
+	}
+	if _, ok := k.aeads[byte(n)]; !ok {
+		return nil, fmt.Errorf("active key %s is not in the key set", active)
+	}
+	k.active = byte(n)
+	return k, nil
+}
+
+// AAD は暗号文を保存先に結び付ける追加認証データ。DB に書き込める人が暗号文を別の行や列に
+// 移し替えても (例: 他の製品の原価をコピーする)、復号が失敗して気付けるようにする
+func AAD(table, column string, rowID int) []byte {
+	return []byte(table + "." + column + "#" + strconv.Itoa(rowID))
+}
+
+// Encrypt は [鍵 ID 1 バイト][nonce][暗号文] の形式で暗号化する。aad は暗号文に含めず、復号の際に同じ値が要る
+func (k *Keyring) Encrypt(plain, aad []byte) ([]byte, error) {
+	aead := k.aeads[k.active]
+	out := make([]byte, 1+aead.NonceSize(), 1+aead.NonceSize()+len(plain)+aead.Overhead())
+	out[0] = k.active
+	if _, err := rand.Read(out[1:]); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, out[1:], plain, aad), nil
+}
+
+// Decrypt は Encrypt の暗号文を復号する。aad が暗号化のときと違えばエラーを返す
+func (k *Keyring) Decrypt(data, aad []byte) ([]byte, error) {
+	if len(data) < 1 {
+		return nil, ErrMalformedData
+	}
+	aead, ok := k.aeads[data[0]]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if len(data) < 1+aead.NonceSize()+aead.Overhead() {
+		return nil, ErrMalformedData
+	}
+	nonce := data[1 : 1+aead.NonceSize()]
+	return aead.Open(nil, nonce, data[1+aead.NonceSize():], aad)
+}
+
+var current atomic.Pointer[Keyring]
+
+// SetKeyring は String / Float64 が使う鍵を設定する。設定の再読み込みで差し替えてよい
+func SetKeyring(k *Keyring) {
+	current.Store(k)
+}
+
+func encrypt(plain, aad []byte) ([]byte, error) {
+	k := current.Load()
+	if k == nil {
+		return nil, ErrNoKeyring
+	}
+	return k.Encrypt(plain, aad)
+}
+
+func decrypt(data, aad []byte) ([]byte, error) {
+	k := current.Load()
+	if k == nil {
+		return nil, ErrNoKeyring
+	}
+	return k.Decrypt(data, aad)
+}
@@ -0,0 +1,68 @@
+package fieldcrypt
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func testKeyring(t *testing.T, active string, ids ...string) *Keyring {
+	t.Helper()
+	keys := map[string]string{}
+	for _, id := range ids {
+		keys[id] = base64.StdEncoding.EncodeToString([]byte(strings.Repeat(id, 32)[:32]))
+	}
+	k, err := NewKeyring(keys, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestSealBindsRow(t *testing.T) {
+	SetKeyring(testKeyring(t, "1", "1"))
+	t.Cleanup(func() { SetKeyring(nil) })
+
+	aad := AAD("product_supplier_info", "supplier_cost", 1)
+	data, err := Float64(1234.5).Seal(aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Float64
+	if err := got.Open(data, aad); err != nil || got != 1234.5 {
+		t.Fatalf("Open = %v, %v; want 1234.5", got, err)
+	}
+
+	// 別の行や列に移した暗号文は復号できない
+	for _, other := range [][]byte{
+		AAD("product_supplier_info", "supplier_cost", 2),
+		AAD("product_supplier_info", "partner_contact", 1),
+		nil,
+	} {
+		if err := got.Open(data, other); err == nil {
+			t.Errorf("Open with AAD %q succeeded", other)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	aad := AAD("product_supplier_info", "partner_contact", 7)
+	old := testKeyring(t, "1", "1")
+	data, err := old.Encrypt([]byte("sales@example.com"), aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 鍵 2 に切り替えても、鍵 1 の暗号文は読める
+	rotated := testKeyring(t, "2", "1", "2")
+	plain, err := rotated.Decrypt(data, aad)
+	if err != nil || string(plain) != "sales@example.com" {
+		t.Fatalf("Decrypt = %q, %v", plain, err)
+	}
+	if _, err := testKeyring(t, "2", "2").Decrypt(data, aad); err != ErrUnknownKey {
+		t.Errorf("Decrypt without key 1: err = %v, want ErrUnknownKey", err)
+	}
+	if _, err := rotated.Decrypt(data[:5], aad); err != ErrMalformedData {
+		t.Errorf("Decrypt of truncated data: err = %v, want ErrMalformedData", err)
+	}
+}
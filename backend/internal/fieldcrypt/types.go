@@ -0,0 +1,47 @@
+package fieldcrypt
+
+import (
+	"strconv"
+)
+
+// String は暗号化して保存される文字列
+type String string
+
+// Seal は aad (AAD で作る保存先) に結び付けて暗号化する
+func (s String) Seal(aad []byte) ([]byte, error) {
+	return encrypt([]byte(s), aad)
+}
+
+// Open は Seal の暗号文を復号する
+func (s *String) Open(data, aad []byte) error {
+	plain, err := decrypt(data, aad)
+	if err != nil {
+		*s = ""
+		return err
+	}
+	*s = String(plain)
+	return nil
+}
+
+// Float64 は暗号化して保存される数値 (金額など)
+type Float64 float64
+
+// Seal は aad (AAD で作る保存先) に結び付けて暗号化する
+func (f Float64) Seal(aad []byte) ([]byte, error) {
+	return encrypt([]byte(strconv.FormatFloat(float64(f), 'f', -1, 64)), aad)
+}
+
+// Open は Seal の暗号文を復号する
+func (f *Float64) Open(data, aad []byte) error {
+	plain, err := decrypt(data, aad)
+	if err != nil {
+		*f = 0
+		return err
+	}
+	v, err := strconv.ParseFloat(string(plain), 64)
+	if err != nil {
+		return ErrMalformedData
+	}
+	*f = Float64(v)
+	return nil
+}
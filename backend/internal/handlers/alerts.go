@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/auth"
+	"sample-backend/internal/models"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
+)
+
+// AlertHandler は製品の再入荷・値下がりの通知の購読を扱う。
+// 購読は申し込み時に返すトークンで確認・解除する (通知のメールにも解除用の URL を載せる)
+type AlertHandler struct {
+	svc *service.AlertService
+}
+
+func NewAlertHandler(svc *service.AlertService) *AlertHandler {
+	return &AlertHandler{svc: svc}
+}
+
+func (h *AlertHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "subscribe_alert")
+	defer span.End()
+
+	id, err := pathID(r, "product")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var req models.AlertRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPostBodySize)).Decode(&req); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to decode request body: %v", err)
+		writeError(w, r, apperr.Validation("Invalid request body"))
+		return
+	}
+	span.SetAttributes(
+		attribute.Int("product.id", id),
+		attribute.String("alert.kind", req.Kind),
+		attribute.String("alert.channel", req.Channel),
+	)
+
+	_, authenticated := auth.ClientFrom(ctx)
+	sub, err := h.svc.Subscribe(ctx, id, req, authenticated)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeCreated(w, r, sub)
+}
+
+// GetAlert は購読の状態と配信記録を返す
+func (h *AlertHandler) GetAlert(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "get_alert")
+	defer span.End()
+
+	sub, err := h.svc.Get(ctx, r.PathValue("token"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(sub); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode alert response: %v", err)
+	}
+}
+
+func (h *AlertHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "unsubscribe_alert")
+	defer span.End()
+
+	if err := h.svc.Unsubscribe(ctx, r.PathValue("token")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
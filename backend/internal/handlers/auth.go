@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/auth"
+	"sample-backend/internal/middleware"
+	"sample-backend/internal/models"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
+)
+
+// AuthHandler はログイン (JWT の発行) を扱う。ユーザーの登録は管理用リスナーのみで公開する
+type AuthHandler struct {
+	svc        *service.AuthService
+	trustProxy *middleware.ProxyTrust
+}
+
+func NewAuthHandler(svc *service.AuthService, trustProxy *middleware.ProxyTrust) *AuthHandler {
+	return &AuthHandler{svc: svc, trustProxy: trustProxy}
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "login")
+	defer span.End()
+
+	var req models.LoginRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPostBodySize)).Decode(&req); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to decode request body: %v", err)
+		writeError(w, r, apperr.Validation("Invalid request body"))
+		return
+	}
+	resp, err := h.svc.Login(ctx, req, middleware.ClientIP(r, h.trustProxy))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// トークンを中間のキャッシュに残さない
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode login response: %v", err)
+	}
+}
+
+// CreateUser はユーザーを登録する (管理用)
+func (h *AuthHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "create_user")
+	defer span.End()
+
+	var req models.UserRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPostBodySize)).Decode(&req); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to decode request body: %v", err)
+		writeError(w, r, apperr.Validation("Invalid request body"))
+		return
+	}
+	u, err := h.svc.CreateUser(ctx, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeCreated(w, r, u)
+}
+
+// authorizeProductWrite は製品の書き込みを許可するかを判定する。
+// API キーで認証したクライアントと、JWT で認証した admin のユーザーに許可する
+func authorizeProductWrite(ctx context.Context) error {
+	if _, ok := auth.ClientFrom(ctx); ok {
+		return nil
+	}
+	u, ok := auth.UserFrom(ctx)
+	if !ok {
+		return apperr.Unauthorized("An API key or an admin token is required to modify products")
+	}
+	if u.Role != auth.RoleAdmin {
+		return apperr.Forbidden("Only admin users can modify products")
+	}
+	return nil
+}
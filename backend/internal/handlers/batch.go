@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
+)
+
+// maxBatchBodySize は POST /api/products/batch で読むリクエストボディの上限 (200 件の ID に十分な大きさ)
+const maxBatchBodySize = 16 << 10
+
+// GetProductsBatch は ID を指定して製品をまとめて返す。
+// GET では ?ids=1,2,3、POST では JSON の配列 [1, 2, 3] で指定する (最大 service.MaxBatchIDs 件)
+func (h *ProductHandler) GetProductsBatch(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "get_products_batch")
+	defer span.End()
+
+	var ids []int
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize)).Decode(&ids); err != nil {
+			reqlog.From(ctx).Printf("[ERROR] Failed to decode request body: %v", err)
+			writeError(w, r, apperr.Validation("The request body must be a JSON array of product ids"))
+			return
+		}
+	} else {
+		var err error
+		if ids, err = parseIDList(r.URL.Query().Get("ids")); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	response, err := h.svc.GetProducts(ctx, ids)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode products response: %v", err)
+	}
+}
+
+// parseIDList はカンマ区切りの ID を読む。巨大なクエリ文字列を全部読む前に件数の上限で打ち切る
+func parseIDList(v string) ([]int, error) {
+	if strings.TrimSpace(v) == "" {
+		return nil, apperr.Validation("ids is required")
+	}
+	parts := strings.Split(v, ",")
+	if len(parts) > service.MaxBatchIDs {
+		return nil, service.ErrTooManyBatchIDs
+	}
+	ids := make([]int, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id < 1 {
+			return nil, apperr.Validation("ids must be a comma-separated list of product ids")
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/hooks"
+)
+
+// errorBody はエラー時のレスポンス ({"error": {"code": ..., "message": ..., "details": ...}})
+type errorBody struct {
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// errorStatus は apperr の分類と HTTP ステータス・エラーコードの対応
+var errorStatus = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{apperr.ErrValidation, http.StatusBadRequest, "invalid_request"},
+	{apperr.ErrUnauthorized, http.StatusUnauthorized, "unauthorized"},
+	{apperr.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{apperr.ErrTooManyRequests, http.StatusTooManyRequests, "too_many_requests"},
+	{apperr.ErrNotFound, http.StatusNotFound, "not_found"},
+	{apperr.ErrConflict, http.StatusConflict, "conflict"},
+	{apperr.ErrUnavailable, http.StatusServiceUnavailable, "unavailable"},
+}
+
+// writeError はエラーを分類に応じた HTTP ステータスと JSON のエラーレスポンスに変換して書き込む。
+// 分類されていないエラーは内容を返さず 500 とする。エラーは hooks の OnError にも通知する
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code, message := http.StatusInternalServerError, "internal", "Internal server error"
+	var details interface{}
+	for _, e := range errorStatus {
+		if errors.Is(err, e.kind) {
+			status, code = e.status, e.code
+			if msg, ok := apperr.Message(err); ok {
+				message = msg
+			}
+			details = apperr.Details(err)
+			break
+		}
+	}
+
+	hooks.ReportError(r, status, err)
+
+	switch status {
+	case http.StatusServiceUnavailable:
+		w.Header().Set("Retry-After", "1")
+	case http.StatusTooManyRequests:
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(apperr.RetryAfter(err).Seconds()))))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(errorBody{Error: errorDetail{Code: code, Message: message, Details: details}}); err != nil {
+		log.Printf("[ERROR] Failed to encode error response: %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/events"
+	"sample-backend/internal/reqlog"
+)
+
+// sseRetry はクライアントが切断後に再接続するまでの待ち時間 (ミリ秒)
+const sseRetry = 3000
+
+// EventsHandler は製品の変化を Server-Sent Events で送る。一覧を定期的に取り直す代わりに使う
+type EventsHandler struct {
+	hub *events.Hub
+	// 何も送らない時間がこれを超えたらコメント行を送る (プロキシに接続を切られないようにする)
+	heartbeat time.Duration
+}
+
+func NewEventsHandler(hub *events.Hub, heartbeat time.Duration) *EventsHandler {
+	return &EventsHandler{hub: hub, heartbeat: heartbeat}
+}
+
+// StreamProductEvents は製品の登録・更新・削除を event: product.updated のように種類ごとに送る。
+// 再接続時の Last-Event-ID (または ?last_event_id=) より後のイベントは、直近の分だけ送り直す
+func (h *EventsHandler) StreamProductEvents(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "stream_product_events")
+	defer span.End()
+
+	lastID, err := lastEventID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	sub, missed := h.hub.Subscribe(lastID)
+	if sub == nil {
+		writeError(w, r, apperr.Unavailable("The server is shutting down", nil))
+		return
+	}
+	defer sub.Close()
+	span.SetAttributes(attribute.Int("events.replayed", len(missed)))
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// nginx などのリバースプロキシにバッファさせない
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry)
+	for _, e := range missed {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Event stream cannot be flushed: %v", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				// 受け取りが追いつかなかったか、サーバーを止める。クライアントは Last-Event-ID を付けて再接続する
+				return
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
+
+// lastEventID は再接続したクライアントが最後に受け取ったイベントの ID を返す。
+// EventSource はヘッダーで、ヘッダーを付けられないクライアントはクエリで渡す
+func lastEventID(r *http.Request) (uint64, error) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, apperr.Validation("Invalid Last-Event-ID")
+	}
+	return id, nil
+}
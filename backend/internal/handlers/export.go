@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/auth"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
+)
+
+// ExportHandler は製品のカタログのエクスポートを扱う
+type ExportHandler struct {
+	svc *service.ExportService
+}
+
+func NewExportHandler(svc *service.ExportService) *ExportHandler {
+	return &ExportHandler{svc: svc}
+}
+
+// exportContentTypes は形式ごとの Content-Type
+var exportContentTypes = map[string]string{
+	service.ExportCSV:    "text/csv; charset=utf-8",
+	service.ExportNDJSON: "application/x-ndjson",
+}
+
+// ExportProducts は ?format=csv|ndjson (既定は csv) で製品を添付ファイルとして返す。
+// 一覧と同じ category / brand / min_price / max_price / created_from / created_to / sort で絞り込める。
+// 件数が決まらないうちから送り始めるので Content-Length は付けず、チャンク形式で返す。
+// 全件を読むため API キーか admin のユーザーに限る
+func (h *ExportHandler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "export_products_request")
+	defer span.End()
+
+	if err := authorizeExport(ctx); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	q := r.URL.Query()
+	format := strings.ToLower(q.Get("format"))
+	if format == "" {
+		format = service.ExportCSV
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		writeError(w, r, apperr.Validation("format must be csv or ndjson"))
+		return
+	}
+	filter, err := parseListFilter(q)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	span.SetAttributes(attribute.String("export.format", format))
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="products-%s.%s"`, time.Now().UTC().Format("20060102-150405"), format))
+	w.Header().Set("Cache-Control", "no-store")
+
+	ew := &exportWriter{w: w}
+	if _, err := h.svc.ExportProducts(ctx, format, filter, ew); err != nil {
+		if !ew.wrote {
+			w.Header().Del("Content-Disposition")
+			w.Header().Del("Cache-Control")
+			writeError(w, r, err)
+			return
+		}
+		// 送り始めた後はステータスを変えられない。最後のチャンクを送らずに接続を切り、
+		// クライアントに途中までのファイルを完全なものと取り違えさせない
+		reqlog.From(ctx).Printf("[ERROR] Export aborted mid-stream: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// exportWriter はレスポンスに書き始めたかを記録する (書き始める前のエラーは JSON のエラーで返せる)
+type exportWriter struct {
+	w     http.ResponseWriter
+	wrote bool
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) {
+	e.wrote = true
+	return e.w.Write(p)
+}
+
+// authorizeExport はエクスポートを API キーで認証したクライアントか admin のユーザーに限る
+func authorizeExport(ctx context.Context) error {
+	if _, ok := auth.ClientFrom(ctx); ok {
+		return nil
+	}
+	u, ok := auth.UserFrom(ctx)
+	if !ok {
+		return apperr.Unauthorized("An API key or an admin token is required to export products")
+	}
+	if u.Role != auth.RoleAdmin {
+		return apperr.Forbidden("Only admin users can export products")
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
+)
+
+// GetFacets はカテゴリ・ブランド・価格帯ごとの製品数を返す。一覧と同じ絞り込みのパラメータを指定できる
+func (h *ProductHandler) GetFacets(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "get_product_facets")
+	defer span.End()
+
+	query := r.URL.Query()
+	filter, err := parseListFilter(query)
+	if err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Invalid list filter: %v", err)
+		writeError(w, r, err)
+		return
+	}
+	pickup, err := parsePickupQuery(query)
+	if err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Invalid pickup filter: %v", err)
+		writeError(w, r, err)
+		return
+	}
+	if pickup != nil {
+		span.SetAttributes(attribute.Bool("pickup_filter", true))
+	}
+
+	facets, err := h.svc.Facets(ctx, service.FacetsRequest{Filter: filter, Pickup: pickup})
+	if err != nil {
+		span.SetAttributes(attribute.String("error", err.Error()))
+		writeError(w, r, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(facets); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode facets response: %v", err)
+	}
+}
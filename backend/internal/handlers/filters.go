@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/service"
+)
+
+// parseListFilter は created_from / created_to / category / brand / min_price / max_price / sort パラメータを読み取る。
+// 日付は 2006-01-02 または RFC3339 を受け付け、created_to の日付指定はその日の終わりまでを含む。
+// 値の範囲の検証はサービスが行う
+func parseListFilter(q url.Values) (repository.ListFilter, error) {
+	var f repository.ListFilter
+
+	if v := q.Get("created_from"); v != "" {
+		t, _, err := parseTimeParam(v)
+		if err != nil {
+			return f, apperr.Validation(fmt.Sprintf("invalid created_from: %s", v))
+		}
+		f.CreatedFrom = t
+	}
+
+	if v := q.Get("created_to"); v != "" {
+		t, dateOnly, err := parseTimeParam(v)
+		if err != nil {
+			return f, apperr.Validation(fmt.Sprintf("invalid created_to: %s", v))
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		f.CreatedTo = t
+	}
+
+	f.Category = q.Get("category")
+	f.Brand = q.Get("brand")
+	for _, p := range []struct {
+		name string
+		dst  **float64
+	}{{"min_price", &f.MinPrice}, {"max_price", &f.MaxPrice}} {
+		if v := q.Get(p.name); v != "" {
+			price, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return f, apperr.Validation(fmt.Sprintf("invalid %s: %s", p.name, v))
+			}
+			*p.dst = &price
+		}
+	}
+	f.Sort = q.Get("sort")
+
+	return f, nil
+}
+
+// parsePickupQuery は store_id または near=緯度,経度 と radius (km) パラメータを読み取る。
+// どちらも無ければ nil を返す
+func parsePickupQuery(q url.Values) (*service.PickupQuery, error) {
+	storeID, near := q.Get("store_id"), q.Get("near")
+	switch {
+	case storeID != "" && near != "":
+		return nil, apperr.Validation("store_id and near cannot be used together")
+	case storeID != "":
+		id, err := strconv.Atoi(storeID)
+		if err != nil {
+			return nil, apperr.Validation(fmt.Sprintf("invalid store_id: %s", storeID))
+		}
+		return &service.PickupQuery{StoreID: id}, nil
+	case near != "":
+		latStr, lngStr, ok := strings.Cut(near, ",")
+		lat, latErr := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+		lng, lngErr := strconv.ParseFloat(strings.TrimSpace(lngStr), 64)
+		if !ok || latErr != nil || lngErr != nil {
+			return nil, apperr.Validation(fmt.Sprintf("invalid near (expected lat,lng): %s", near))
+		}
+		pq := &service.PickupQuery{Near: true, Latitude: lat, Longitude: lng, RadiusKM: service.DefaultPickupRadiusKM}
+		if v := q.Get("radius"); v != "" {
+			radius, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, apperr.Validation(fmt.Sprintf("invalid radius: %s", v))
+			}
+			pq.RadiusKM = radius
+		}
+		return pq, nil
+	case q.Get("radius") != "":
+		return nil, apperr.Validation("radius requires near")
+	}
+	return nil, nil
+}
+
+func parseTimeParam(v string) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse(time.RFC3339, v); err == nil {
+		return t, false, nil
+	}
+	if t, err = time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, err
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"sample-backend/internal/apperr"
+)
+
+var filterSeeds = []string{
+	"",
+	"created_from=2025-01-01&created_to=2025-01-31",
+	"created_from=2025-01-01T00:00:00Z&created_to=2025-01-31T23:59:59%2B09:00",
+	"created_to=9999-12-31",
+	"category=pc&brand=acme&sort=price_asc",
+	"min_price=100&max_price=1e309",
+	"min_price=NaN&max_price=-Inf",
+	"min_price=abc",
+	"created_from=2025-13-40",
+	"store_id=1",
+	"near=35.68,139.76&radius=5",
+	"near=35.68&radius=x",
+	"store_id=1&near=1,2",
+	"radius=3",
+}
+
+func FuzzParseListFilter(f *testing.F) {
+	for _, seed := range filterSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		q, err := url.ParseQuery(raw)
+		if err != nil {
+			return
+		}
+		filter, err := parseListFilter(q)
+		if err != nil {
+			if !errors.Is(err, apperr.ErrValidation) {
+				t.Fatalf("parseListFilter(%q) error = %v, want a validation error", raw, err)
+			}
+			return
+		}
+		if q.Get("min_price") != "" && filter.MinPrice == nil {
+			t.Fatalf("parseListFilter(%q) dropped min_price", raw)
+		}
+		if q.Get("created_to") != "" && filter.CreatedTo.IsZero() {
+			t.Fatalf("parseListFilter(%q) dropped created_to", raw)
+		}
+	})
+}
+
+func FuzzParsePickupQuery(f *testing.F) {
+	for _, seed := range filterSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		q, err := url.ParseQuery(raw)
+		if err != nil {
+			return
+		}
+		pq, err := parsePickupQuery(q)
+		if err != nil {
+			if !errors.Is(err, apperr.ErrValidation) {
+				t.Fatalf("parsePickupQuery(%q) error = %v, want a validation error", raw, err)
+			}
+			return
+		}
+		if pq == nil && (q.Get("store_id") != "" || q.Get("near") != "") {
+			t.Fatalf("parsePickupQuery(%q) ignored the pickup parameters", raw)
+		}
+	})
+}
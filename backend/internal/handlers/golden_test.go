@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/config"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/repository/repotest"
+	"sample-backend/internal/service"
+)
+
+// go test ./internal/handlers -run TestGolden -update で testdata/golden を書き直す
+var update = flag.Bool("update", false, "rewrite the golden files under testdata/golden")
+
+// goldenQuestions は承認済みの質問数だけを返す QuestionRepository (詳細の question_count に使う)
+type goldenQuestions struct {
+	repository.QuestionRepository
+	counts map[int]int
+}
+
+func (q goldenQuestions) CountQuestions(ctx context.Context, productID int, status string) (int, error) {
+	return q.counts[productID], nil
+}
+
+// goldenProducts は golden ファイルの元になる製品。登録日時も固定してレスポンスを毎回同じにする
+func goldenProducts() []models.Product {
+	base := time.Date(2025, 4, 1, 9, 0, 0, 0, time.UTC)
+	categories := []string{"pc", "phone", "audio"}
+	brands := []string{"acme", "globex"}
+	products := make([]models.Product, 12)
+	for i := range products {
+		products[i] = models.Product{
+			Name:          "product " + string(rune('A'+i)),
+			Category:      categories[i%len(categories)],
+			Brand:         brands[i%len(brands)],
+			Model:         "M-" + string(rune('A'+i)),
+			Description:   "golden fixture",
+			Price:         float64(500 * (i + 1)),
+			SKU:           "SKU-" + string(rune('A'+i)),
+			CreatedAt:     base.AddDate(0, 0, i),
+			ReviewCount:   i % 4,
+			RatingAverage: float64(i%4) * 1.25,
+			Stock:         10 - i%10,
+		}
+	}
+	return products
+}
+
+// newGoldenMux はサーバーと同じパターンで製品の読み取りのルートを登録する
+func newGoldenMux(repo *repotest.Fake) *http.ServeMux {
+	h := NewProductHandler(
+		service.NewProductService(repo, &config.Config{}),
+		service.NewQuestionService(repo, goldenQuestions{counts: map[int]int{1: 3}}),
+		nil,
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/products", h.GetProducts)
+	mux.HandleFunc("GET /api/products/facets", h.GetFacets)
+	mux.HandleFunc("GET /api/products/batch", h.GetProductsBatch)
+	mux.HandleFunc("GET /api/products/{id}", h.GetProduct)
+	mux.HandleFunc("GET /api/products/sku/{sku}", h.GetProductBySKU)
+	return mux
+}
+
+// canonicalJSON はキーの順序と空白をそろえた JSON を返す (フィールドの並びの違いは契約の変更として扱わない)
+func canonicalJSON(t *testing.T, body []byte) []byte {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		t.Fatalf("response is not JSON: %v\n%s", err, body)
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(out, '\n')
+}
+
+func TestGoldenResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		// err が nil でなければリポジトリのすべての呼び出しが失敗する
+		err    error
+		status int
+	}{
+		{"list_default", "/api/products", nil, http.StatusOK},
+		{"list_filtered", "/api/products?category=pc&sort=price_desc&page=1&limit=2", nil, http.StatusOK},
+		{"list_last_page", "/api/products?page=3&limit=5", nil, http.StatusOK},
+		{"list_cursor", "/api/products?cursor=djE6NQ&limit=3&include_total=true", nil, http.StatusOK},
+		{"facets", "/api/products/facets", nil, http.StatusOK},
+		{"batch", "/api/products/batch?ids=3,1,99", nil, http.StatusOK},
+		{"detail", "/api/products/1", nil, http.StatusOK},
+		{"detail_by_sku", "/api/products/sku/sku-b", nil, http.StatusOK},
+		{"error_not_found", "/api/products/99", nil, http.StatusNotFound},
+		{"error_invalid_id", "/api/products/abc", nil, http.StatusBadRequest},
+		{"error_invalid_filter", "/api/products?min_price=cheap", nil, http.StatusBadRequest},
+		{"error_invalid_cursor", "/api/products?cursor=%21%21", nil, http.StatusBadRequest},
+		{"error_unavailable", "/api/products", apperr.Unavailable("Database unavailable", nil), http.StatusServiceUnavailable},
+		{"error_internal", "/api/products/1", errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repotest.NewFake(goldenProducts()...)
+			repo.SetError(tt.err)
+
+			w := httptest.NewRecorder()
+			newGoldenMux(repo).ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body = %s", w.Code, tt.status, w.Body)
+			}
+			got := canonicalJSON(t, w.Body.Bytes())
+
+			path := filepath.Join("testdata", "golden", tt.name+".json")
+			if *update {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("response differs from %s (run with -update if the change is intended)\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
@@ -2,22 +2,21 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
 	"net/http"
 	"time"
 
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"fmt"
+
+	"sample-backend/internal/reqlog"
 )
 
 func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
-	log.Printf("[API] Health check request from %s", r.RemoteAddr)
+	reqlog.From(r.Context()).Printf("[API] Health check request from %s", r.RemoteAddr)
 
 	// トレースの開始
-	tracer := otel.Tracer("product-search-backend")
 	_, span := tracer.Start(r.Context(), "health_check")
 	defer span.End()
 
@@ -27,12 +26,11 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
         span.RecordError(err)
         span.SetStatus(codes.Error, "Health check failed")
         span.SetAttributes(attribute.String("error.type", "test_error"))
-        log.Printf("[ERROR] Test error triggered: %v", err)
-        http.Error(w, "Health check failed", http.StatusInternalServerError)
+        reqlog.From(r.Context()).Printf("[ERROR] Test error triggered: %v", err)
+        writeError(w, r, err)
         return
     }
 
-	setJSONHeaders(w)
 	response := map[string]string{
 		"status":    "ok",
 		"timestamp": time.Now().Format(time.RFC3339),
@@ -42,15 +40,10 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	span.SetAttributes(attribute.String("response.status", "ok"))
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[ERROR] Failed to encode health response: %v", err)
+		reqlog.From(r.Context()).Printf("[ERROR] Failed to encode health response: %v", err)
 		return
 	}
 
 	duration := time.Since(start)
-	log.Printf("[API] Health check completed in %v", duration)
-}
-
-func setJSONHeaders(w http.ResponseWriter) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.Header().Set("Cache-Control", "no-cache")
+	reqlog.From(r.Context()).Printf("[API] Health check completed in %v", duration)
 }
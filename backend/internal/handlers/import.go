@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
+)
+
+// maxImportFileSize はアップロードできるファイルの最大バイト数
+const maxImportFileSize = 100 << 20
+
+// ImportProducts は multipart/form-data の file に添付した CSV または NDJSON の製品を一括登録する。
+// ファイルはメモリやディスクに保存せず、受け取りながら登録する。形式は format パラメータで指定し、
+// 省略した場合はファイル名の拡張子か Content-Type から決める。
+// 管理用リスナー (mTLS) だけで公開するため、クライアント証明書の検証を認可とする
+func (h *ProductHandler) ImportProducts(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "import_products_request")
+	defer span.End()
+
+	// ファイル以外のパートや multipart の区切りの分だけ上限に余裕を持たせる
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize+1<<20)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, r, apperr.Validation("The request must be multipart/form-data with a file"))
+		return
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			writeError(w, r, apperr.Validation("file is required"))
+			return
+		}
+		if err != nil {
+			reqlog.From(ctx).Printf("[ERROR] Failed to read multipart body: %v", err)
+			writeError(w, r, apperr.Validation("Invalid multipart body"))
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		format := importFormat(r.URL.Query().Get("format"), part.FileName(), part.Header.Get("Content-Type"))
+		span.SetAttributes(attribute.String("import.format", format))
+
+		summary, err := h.svc.ImportProducts(ctx, format, &sizeLimitReader{r: part, n: maxImportFileSize})
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(summary); err != nil {
+			reqlog.From(ctx).Printf("[ERROR] Failed to encode import response: %v", err)
+		}
+		return
+	}
+}
+
+// importFormat は一括登録の形式を決める。決められなければ空文字 (サービスが検証エラーにする)
+func importFormat(param, filename, contentType string) string {
+	if param != "" {
+		return strings.ToLower(param)
+	}
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return service.ImportCSV
+	case ".ndjson", ".jsonl":
+		return service.ImportNDJSON
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return service.ImportCSV
+	case "application/x-ndjson", "application/jsonl":
+		return service.ImportNDJSON
+	}
+	return ""
+}
+
+// sizeLimitReader は n バイトを超えて読もうとすると service.ErrImportTooLarge を返す
+type sizeLimitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *sizeLimitReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, service.ErrImportTooLarge
+	}
+	// 上限ちょうどのファイルと超えたファイルを区別するため 1 バイト余分に読む
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n + int(l.n), service.ErrImportTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"sample-backend/internal/health"
+	"sample-backend/internal/reqlog"
+)
+
+// Pinger は readyz が疎通を確かめる依存先 (*sqlx.DB など)
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// ProbeHandler はロードバランサーやオーケストレーターが使う死活確認 (healthz) と
+// 受け付け可否 (readyz) を返す
+type ProbeHandler struct {
+	db        Pinger
+	readiness *health.Readiness
+	timeout   time.Duration
+}
+
+func NewProbeHandler(db Pinger, readiness *health.Readiness, timeout time.Duration) *ProbeHandler {
+	return &ProbeHandler{db: db, readiness: readiness, timeout: timeout}
+}
+
+type probeResponse struct {
+	Status string `json:"status"`
+	// 受け付けられない理由 (依存先: 理由)
+	Failing []string `json:"failing,omitempty"`
+}
+
+// Liveness はプロセスが応答できれば 200 を返す。依存先は確かめない
+// (DB の障害で再起動を繰り返さないようにするため)
+func (h *ProbeHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, r, http.StatusOK, probeResponse{Status: "ok"})
+}
+
+// Readiness は終了処理中でなく、死活監視で異常が無く、DB に ping が通れば 200 を返す。それ以外は 503
+func (h *ProbeHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "readiness_check")
+	defer span.End()
+
+	if failing := h.readiness.Failing(); len(failing) > 0 {
+		writeProbe(w, r, http.StatusServiceUnavailable, probeResponse{Status: "unavailable", Failing: failing})
+		return
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	if err := h.db.PingContext(pctx); err != nil {
+		reqlog.From(ctx).Printf("[HEALTH] Database ping failed: %v", err)
+		writeProbe(w, r, http.StatusServiceUnavailable, probeResponse{Status: "unavailable", Failing: []string{"database: " + err.Error()}})
+		return
+	}
+	writeProbe(w, r, http.StatusOK, probeResponse{Status: "ok"})
+}
+
+func writeProbe(w http.ResponseWriter, r *http.Request, status int, resp probeResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		reqlog.From(r.Context()).Printf("[ERROR] Failed to encode probe response: %v", err)
+	}
+}
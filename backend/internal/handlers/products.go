@@ -2,141 +2,263 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
-	"math"
 	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/jmoiron/sqlx"
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 
+	"sample-backend/internal/apperr"
 	"sample-backend/internal/models"
+	"sample-backend/internal/pagination"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
 )
 
 type ProductHandler struct {
-	db *sqlx.DB
+	svc *service.ProductService
+	// 製品詳細に添える質問数
+	questions *service.QuestionService
+	// as_of を指定した製品詳細
+	history *service.HistoryService
 }
 
-func NewProductHandler(db *sqlx.DB) *ProductHandler {
-	return &ProductHandler{db: db}
+func NewProductHandler(svc *service.ProductService, questions *service.QuestionService, history *service.HistoryService) *ProductHandler {
+	return &ProductHandler{svc: svc, questions: questions, history: history}
 }
 
 func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
-	start := time.Now()
-	log.Printf("[API] Get products request from %s", r.RemoteAddr)
-
 	// トレースの開始
-	tracer := otel.Tracer("product-search-backend")
 	ctx, span := tracer.Start(r.Context(), "get_products")
 	defer span.End()
 
-	setJSONHeaders(w)
-
-	// ページネーションパラメータの取得
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
-	log.Printf("[API] Request params - page: %s, limit: %s", pageStr, limitStr)
-
-	page, err := strconv.Atoi(pageStr)
-	if err != nil || page < 1 {
-		page = 1
-	}
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 || limit > 100 {
-		limit = 10
-	}
-
-	offset := (page - 1) * limit
-	log.Printf("[API] Processed params - page: %d, limit: %d, offset: %d", page, limit, offset)
-
-	// // 総件数を取得
-	// log.Println("[DB] Executing count query...")
-	// var totalCount int
-	// err = h.db.Get(&totalCount, "SELECT COUNT(*) FROM products")
-	// if err != nil {
-	// 	log.Printf("[DB ERROR] Failed to get total count: %v", err)
-	// 	span.SetAttributes(attribute.String("error", err.Error()))
-	// 	http.Error(w, "Internal server error", http.StatusInternalServerError)
-	// 	return
-	// }
-	// log.Printf("[DB] Total products count: %d", totalCount)
-
-    // 総件数取得用の子スパンを追加（親のコンテキストを使用）
-    _, countSpan := tracer.Start(ctx, "database_count_query")
-    defer countSpan.End()
-    countSpan.SetAttributes(attribute.String("query_type", "COUNT"))
-
-    var totalCount int
-    err = h.db.Get(&totalCount, "SELECT COUNT(*) FROM products")
-    if err != nil {
-        span.SetAttributes(attribute.String("error", err.Error()))
-        countSpan.SetAttributes(attribute.String("error", err.Error()))
-        // エラーハンドリング...
-        return
-    }
-    countSpan.SetAttributes(attribute.Int("total_count", totalCount))
-
-	// // 製品データを取得
-	// log.Printf("[DB] Executing products query with limit: %d, offset: %d", limit, offset)
-	// products := []models.Product{}
-	// query := "SELECT id, name, category, brand, model, description, price, created_at FROM products ORDER BY id LIMIT ? OFFSET ?"
-	// err = h.db.Select(&products, query, limit, offset)
-	// if err != nil {
-	// 	log.Printf("[DB ERROR] Failed to get products: %v", err)
-	// 	span.SetAttributes(attribute.String("error", err.Error()))
-	// 	http.Error(w, "Internal server error", http.StatusInternalServerError)
-	// 	return
-	// }
-	// log.Printf("[DB] Retrieved %d products", len(products))
-
-    _, productsSpan := tracer.Start(ctx, "database_products_query")
-    defer productsSpan.End()
-    productsSpan.SetAttributes(
-        attribute.String("query_type", "SELECT"),
-        attribute.Int("limit", limit),
-        attribute.Int("offset", offset),
-    )
-
-    products := []models.Product{}
-    query := "SELECT id, name, category, brand, model, description, price, created_at FROM products ORDER BY id LIMIT ? OFFSET ?"
-    err = h.db.Select(&products, query, limit, offset)
-    if err != nil {
-        span.SetAttributes(attribute.String("error", err.Error()))
-        productsSpan.SetAttributes(attribute.String("error", err.Error()))
-        // エラーハンドリング...
-        return
-    }
-    productsSpan.SetAttributes(attribute.Int("returned_count", len(products)))
-
-
-	totalPages := int(math.Ceil(float64(totalCount) / float64(limit)))
-	log.Printf("[API] Calculated total pages: %d", totalPages)
-
-	span.SetAttributes(
-		attribute.Int("page", page),
-		attribute.Int("limit", limit),
-		// ↑上の2行を追加
-		attribute.Int("total_count", totalCount),
-		attribute.Int("total_pages", totalPages),
-		attribute.Int("returned_count", len(products)),
-	)
-
-	response := models.PaginatedResponse{
-		Products:   products,
-		Page:       page,
-		Limit:      limit,
-		TotalPages: totalPages,
-		Count:      totalCount,
+	// ページネーションパラメータの取得 (クエリ文字列のパースは 1 回だけ行う。範囲外はサービスが丸める)
+	query := r.URL.Query()
+	paging := pagination.ParseQuery(query)
+
+	// 絞り込みと並び順 (登録日時の範囲指定は products のパーティションプルーニングが効く)
+	filter, err := parseListFilter(query)
+	if err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Invalid list filter: %v", err)
+		writeError(w, r, err)
+		return
+	}
+
+	// スパンが記録されない (トレース無効・非サンプル) ときは属性を組み立てない
+	recording := span.IsRecording()
+	if recording && filter.HasCreatedRange() {
+		span.SetAttributes(attribute.Bool("partition_pruning", true))
+		if !filter.CreatedFrom.IsZero() {
+			span.SetAttributes(attribute.String("created_from", filter.CreatedFrom.Format(time.RFC3339)))
+		}
+		if !filter.CreatedTo.IsZero() {
+			span.SetAttributes(attribute.String("created_to", filter.CreatedTo.Format(time.RFC3339)))
+		}
 	}
 
+	// 店舗での受け取り (store_id または near / radius)
+	pickup, err := parsePickupQuery(query)
+	if err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Invalid pickup filter: %v", err)
+		writeError(w, r, err)
+		return
+	}
+	if recording && pickup != nil {
+		span.SetAttributes(attribute.Bool("pickup_filter", true))
+	}
+
+	// カーソル方式 (cursor を指定すると page は無視し、include_total=true のときだけ総件数を数える)
+	list := service.ListRequest{
+		Paging:       paging,
+		Filter:       filter,
+		Pickup:       pickup,
+		Cursor:       query.Get("cursor"),
+		IncludeTotal: query.Get("include_total") == "true",
+	}
+	if recording && list.Cursor != "" {
+		span.SetAttributes(attribute.Bool("cursor_pagination", true))
+	}
+
+	result, err := h.svc.ListProducts(ctx, list)
+	if err != nil {
+		span.SetAttributes(attribute.String("error", err.Error()))
+		writeError(w, r, err)
+		return
+	}
+
+	if cached := result.Cached; cached != nil {
+		if recording {
+			span.SetAttributes(
+				attribute.Int("page", result.Page),
+				attribute.Int("limit", result.Limit),
+				attribute.Bool("cache.hit", true),
+				attribute.Int("total_count", cached.TotalCount),
+				attribute.Int("total_pages", cached.TotalPages),
+				attribute.Int("returned_count", cached.Returned),
+			)
+		}
+		if _, err := w.Write(cached.Body); err != nil {
+			reqlog.From(ctx).Printf("[ERROR] Failed to write cached products response: %v", err)
+		}
+		return
+	}
+
+	response := result.Response
+	if recording {
+		span.SetAttributes(
+			attribute.Int("page", result.Page),
+			attribute.Int("limit", result.Limit),
+			attribute.Bool("cache.hit", false),
+			attribute.Int("total_count", response.Count),
+			attribute.Int("total_pages", response.TotalPages),
+			attribute.Int("returned_count", len(response.Products)),
+		)
+	}
+
+	// 完了ログはアクセスログミドルウェアが非同期に出力する
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("[ERROR] Failed to encode products response: %v", err)
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode products response: %v", err)
+	}
+}
+
+func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "get_product")
+	defer span.End()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, apperr.Validation("Invalid product id"))
+		return
+	}
+
+	// ?as_of=RFC3339 でその時点の内容と価格を返す (未来の時刻は現在の内容と同じ)
+	var asOf time.Time
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		if asOf, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, r, apperr.Validation("as_of must be an RFC 3339 timestamp"))
+			return
+		}
+		span.SetAttributes(attribute.String("as_of", v))
+	}
+
+	var response models.ProductDetail
+	if !asOf.IsZero() && asOf.Before(time.Now()) {
+		rev, err := h.history.GetProductAsOf(ctx, id, asOf)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		response.Product = rev.Product
+		response.AsOf = &asOf
+		response.EffectiveFrom = &rev.ValidFrom
+	} else {
+		product, err := h.svc.GetProduct(ctx, id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		response.Product = *product
+	}
+	// 質問数は常に現在の件数
+	if response.QuestionCount, err = h.questions.CountForProduct(ctx, id); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	duration := time.Since(start)
-	log.Printf("[API] Get products completed in %v - returned %d products", duration, len(products))
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode product response: %v", err)
+	}
+}
+
+// GetProductBySKU は SKU を指定して製品を返す (SKU で管理する外部システムとの連携用)
+func (h *ProductHandler) GetProductBySKU(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "get_product_by_sku")
+	defer span.End()
+
+	product, err := h.svc.GetProductBySKU(ctx, r.PathValue("sku"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(product); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode product response: %v", err)
+	}
+}
+
+// decodeProductRequest は製品の登録・更新の内容を読み取る。
+// 書き込みは API キーで認証したクライアントと admin のユーザーだけに許可する
+func decodeProductRequest(w http.ResponseWriter, r *http.Request) (models.ProductRequest, error) {
+	var req models.ProductRequest
+	if err := authorizeProductWrite(r.Context()); err != nil {
+		return req, err
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPostBodySize)).Decode(&req); err != nil {
+		reqlog.From(r.Context()).Printf("[ERROR] Failed to decode request body: %v", err)
+		return req, apperr.Validation("Invalid request body")
+	}
+	return req, nil
+}
+
+func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "create_product")
+	defer span.End()
+
+	req, err := decodeProductRequest(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	product, err := h.svc.CreateProduct(ctx, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	span.SetAttributes(attribute.Int("product.id", product.ID))
+	writeCreated(w, r, product)
+}
+
+func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "update_product")
+	defer span.End()
+
+	id, err := pathID(r, "product")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	span.SetAttributes(attribute.Int("product.id", id))
+	req, err := decodeProductRequest(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	product, err := h.svc.UpdateProduct(ctx, id, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(product); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode product response: %v", err)
+	}
+}
+
+func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "delete_product")
+	defer span.End()
+
+	id, err := pathID(r, "product")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	span.SetAttributes(attribute.Int("product.id", id))
+	if err := authorizeProductWrite(ctx); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.svc.DeleteProduct(ctx, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
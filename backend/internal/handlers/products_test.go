@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/auth"
+	"sample-backend/internal/config"
+	"sample-backend/internal/middleware"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository/repotest"
+	"sample-backend/internal/service"
+)
+
+// newTestProductHandler は DB の代わりに repotest.Fake を読むハンドラーを返す (ページキャッシュは使わない)
+func newTestProductHandler(n int) (*ProductHandler, *repotest.Fake) {
+	products := make([]models.Product, n)
+	for i := range products {
+		products[i] = models.Product{Name: "product", Category: "pc", Price: float64(1000 * (i + 1))}
+	}
+	repo := repotest.NewFake(products...)
+	return NewProductHandler(service.NewProductService(repo, &config.Config{}), nil, nil), repo
+}
+
+func TestGetProductsPagination(t *testing.T) {
+	h, _ := newTestProductHandler(25)
+
+	w := httptest.NewRecorder()
+	h.GetProducts(w, httptest.NewRequest(http.MethodGet, "/api/products?page=3&limit=10", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+	var resp models.PaginatedResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Count != 25 || resp.TotalPages != 3 || resp.Page != 3 || len(resp.Products) != 5 || resp.Products[0].ID != 21 {
+		t.Errorf("got count=%d totalPages=%d page=%d len=%d first=%d, want 25, 3, 3, 5, 21",
+			resp.Count, resp.TotalPages, resp.Page, len(resp.Products), resp.Products[0].ID)
+	}
+}
+
+func TestGetProductsErrorMapping(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"unavailable", apperr.Unavailable("Database unavailable", nil), http.StatusServiceUnavailable},
+		{"unclassified", errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, repo := newTestProductHandler(1)
+			repo.SetError(tt.err)
+
+			w := httptest.NewRecorder()
+			h.GetProducts(w, httptest.NewRequest(http.MethodGet, "/api/products", nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", got)
+			}
+		})
+	}
+}
+
+// 総件数・総ページ数・ページの丸めの境界
+func TestGetProductsPageMath(t *testing.T) {
+	tests := []struct {
+		name                            string
+		products                        int
+		query                           string
+		page, limit, totalPages, length int
+		first                           int
+	}{
+		{"empty", 0, "", 1, 10, 0, 0, 0},
+		{"exact multiple", 20, "?page=2&limit=10", 2, 10, 2, 10, 11},
+		{"past the last page", 20, "?page=3&limit=10", 3, 10, 2, 0, 0},
+		{"one per page", 3, "?page=3&limit=1", 3, 1, 3, 1, 3},
+		{"limit above max", 150, "?limit=1000", 1, 10, 15, 10, 1},
+		{"limit at max", 150, "?limit=100", 1, 100, 2, 100, 1},
+		{"zero and negative", 5, "?page=-5&limit=0", 1, 10, 1, 5, 1},
+		{"not a number", 5, "?page=x&limit=y", 1, 10, 1, 5, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestProductHandler(tt.products)
+
+			w := httptest.NewRecorder()
+			h.GetProducts(w, httptest.NewRequest(http.MethodGet, "/api/products"+tt.query, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+			}
+			var resp models.PaginatedResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Count != tt.products || resp.Page != tt.page || resp.Limit != tt.limit ||
+				resp.TotalPages != tt.totalPages || len(resp.Products) != tt.length {
+				t.Errorf("got count=%d page=%d limit=%d totalPages=%d len=%d, want %d, %d, %d, %d, %d",
+					resp.Count, resp.Page, resp.Limit, resp.TotalPages, len(resp.Products),
+					tt.products, tt.page, tt.limit, tt.totalPages, tt.length)
+			}
+			if tt.length > 0 && resp.Products[0].ID != tt.first {
+				t.Errorf("first id = %d, want %d", resp.Products[0].ID, tt.first)
+			}
+		})
+	}
+}
+
+// リポジトリのエラーと不正なパラメータの HTTP ステータスへの対応
+func TestGetProductStatusMapping(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		err    error
+		status int
+	}{
+		{"found", "/api/products/1", nil, http.StatusOK},
+		{"not found", "/api/products/99", nil, http.StatusNotFound},
+		{"not found from repository", "/api/products/1", apperr.NotFound("Product not found"), http.StatusNotFound},
+		{"invalid id", "/api/products/abc", nil, http.StatusBadRequest},
+		{"zero id", "/api/products/0", nil, http.StatusBadRequest},
+		{"invalid as_of", "/api/products/1?as_of=yesterday", nil, http.StatusBadRequest},
+		{"unavailable", "/api/products/1", apperr.Unavailable("Database unavailable", nil), http.StatusServiceUnavailable},
+		{"unclassified", "/api/products/1", errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := repotest.NewFake(models.Product{Name: "product", Category: "pc", Price: 1000})
+			repo.SetError(tt.err)
+			mux := newGoldenMux(repo)
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d, body = %s", w.Code, tt.status, w.Body)
+			}
+			if w.Code == http.StatusServiceUnavailable && w.Header().Get("Retry-After") == "" {
+				t.Error("503 without Retry-After")
+			}
+		})
+	}
+}
+
+// 一覧はサーバーと同じく middleware.Conditional を通すと ETag と Cache-Control が付き、
+// If-None-Match が一致すれば 304 になる。内容が変われば ETag も変わり、エラーには付けない
+func TestGetProductsCacheHeaders(t *testing.T) {
+	h, repo := newTestProductHandler(3)
+	handler := middleware.Conditional("public, max-age=5")(http.HandlerFunc(h.GetProducts))
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/products", nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	first := get(nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("status = %d, ETag = %q", first.Code, etag)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "public, max-age=5" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+
+	revalidated := get(http.Header{"If-None-Match": {etag}})
+	if revalidated.Code != http.StatusNotModified || revalidated.Body.Len() != 0 {
+		t.Errorf("If-None-Match: status = %d, body length = %d, want 304 and empty", revalidated.Code, revalidated.Body.Len())
+	}
+
+	if err := repo.Create(context.Background(), &models.Product{Name: "new", Category: "pc", Price: 1}); err != nil {
+		t.Fatal(err)
+	}
+	changed := get(http.Header{"If-None-Match": {etag}})
+	if changed.Code != http.StatusOK || changed.Header().Get("ETag") == etag {
+		t.Errorf("after a change: status = %d, ETag = %q (old %q)", changed.Code, changed.Header().Get("ETag"), etag)
+	}
+
+	repo.SetError(errors.New("boom"))
+	failed := get(nil)
+	if failed.Code != http.StatusInternalServerError || failed.Header().Get("ETag") != "" || failed.Header().Get("Cache-Control") != "" {
+		t.Errorf("error response: status = %d, ETag = %q, Cache-Control = %q", failed.Code, failed.Header().Get("ETag"), failed.Header().Get("Cache-Control"))
+	}
+}
+
+func TestCreateProduct(t *testing.T) {
+	h, repo := newTestProductHandler(0)
+	created := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	repo.Now = func() time.Time { return created }
+	body := `{"name":"laptop","category":"pc","brand":"acme","model":"X1","description":"d","price":120000,"sku":"x1-001"}`
+
+	// 認証なしは 401 で、リポジトリまで届かない
+	w := httptest.NewRecorder()
+	h.CreateProduct(w, httptest.NewRequest(http.MethodPost, "/api/products", strings.NewReader(body)))
+	if w.Code != http.StatusUnauthorized || repo.Calls("Create") != 0 {
+		t.Fatalf("without credentials: status = %d, Create calls = %d", w.Code, repo.Calls("Create"))
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/products", strings.NewReader(body))
+	r = r.WithContext(auth.WithClient(r.Context(), "test-client"))
+	w = httptest.NewRecorder()
+	h.CreateProduct(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+	}
+	var got models.Product
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 1 || got.SKU != "X1-001" || !got.CreatedAt.Equal(created) {
+		t.Errorf("got id=%d sku=%q created_at=%v, want 1, X1-001, %v", got.ID, got.SKU, got.CreatedAt, created)
+	}
+}
+
+// BenchmarkGetProducts は既定の一覧と絞り込み付きの一覧のハンドラーの割り当てを計る
+// (トレースは無効で、スパンの属性を組み立てない経路)。repotest.Fake が呼び出しごとに製品を並べ替えて
+// コピーする分も含むため、製品は 100 件にとどめて比べる。
+//
+//	go test ./internal/handlers -run '^$' -bench GetProducts -benchmem
+func BenchmarkGetProducts(b *testing.B) {
+	for _, bm := range []struct {
+		name   string
+		target string
+	}{
+		{"default", "/api/products"},
+		{"filtered", "/api/products?page=2&limit=20&category=pc&min_price=5000&sort=price_desc"},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			h, _ := newTestProductHandler(100)
+			r := httptest.NewRequest(http.MethodGet, bm.target, nil)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				w := httptest.NewRecorder()
+				h.GetProducts(w, r)
+				if w.Code != http.StatusOK {
+					b.Fatalf("status = %d", w.Code)
+				}
+			}
+		})
+	}
+}
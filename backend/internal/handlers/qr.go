@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/cache"
+	"sample-backend/internal/config"
+	"sample-backend/internal/qrcode"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
+)
+
+// QR コードの画像の大きさ (ピクセル) の範囲と、生成した画像を保持する期間
+const (
+	qrMinSize  = 64
+	qrMaxSize  = 2048
+	qrCacheTTL = 24 * time.Hour
+)
+
+type qrKey struct {
+	id     int
+	format string
+	size   int
+	level  qrcode.Level
+}
+
+// QRHandler は製品ページへのリンクを QR コード (PNG / SVG) で返す。
+// 印刷物や店頭の表示に使う画像で内容は URL だけで決まるため、生成した画像はキャッシュする
+type QRHandler struct {
+	svc         *service.ProductService
+	productURL  string
+	defaultSize int
+	level       qrcode.Level
+	images      *cache.TTL[qrKey, []byte]
+}
+
+func NewQRHandler(svc *service.ProductService, cfg *config.Config) *QRHandler {
+	level, err := qrcode.ParseLevel(cfg.QRErrorCorrection)
+	if err != nil {
+		log.Printf("[QR] %v, using M", err)
+		level = qrcode.Medium
+	}
+	return &QRHandler{
+		svc:         svc,
+		productURL:  cfg.QRProductURL,
+		defaultSize: cfg.QRDefaultSize,
+		level:       level,
+		images:      cache.NewTTL[qrKey, []byte](qrCacheTTL, cfg.QRCacheSize),
+	}
+}
+
+func (h *QRHandler) GetProductQR(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "get_product_qr")
+	defer span.End()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		writeError(w, r, apperr.Validation("Invalid product id"))
+		return
+	}
+
+	query := r.URL.Query()
+	key := qrKey{id: id, format: "png", size: h.defaultSize, level: h.level}
+	if v := query.Get("format"); v != "" {
+		key.format = strings.ToLower(v)
+		if key.format != "png" && key.format != "svg" {
+			writeError(w, r, apperr.Validation("format must be png or svg"))
+			return
+		}
+	}
+	if v := query.Get("size"); v != "" {
+		if key.size, err = strconv.Atoi(v); err != nil || key.size < qrMinSize || key.size > qrMaxSize {
+			writeError(w, r, apperr.Validation("size must be between 64 and 2048"))
+			return
+		}
+	}
+	if v := query.Get("ecc"); v != "" {
+		if key.level, err = qrcode.ParseLevel(v); err != nil {
+			writeError(w, r, apperr.Validation("ecc must be one of L, M, Q, H"))
+			return
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("product.id", id),
+		attribute.String("qr.format", key.format),
+		attribute.Int("qr.size", key.size),
+		attribute.String("qr.ecc", key.level.String()),
+	)
+
+	body, ok := h.images.Get(key)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
+	if !ok {
+		// 存在しない製品の画像は作らない
+		if _, err := h.svc.GetProduct(ctx, id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if body, err = h.render(key); err != nil {
+			reqlog.From(ctx).Printf("[ERROR] Failed to render QR code for product %d: %v", id, err)
+			writeError(w, r, err)
+			return
+		}
+		h.images.Set(key, body)
+	}
+
+	contentType := "image/png"
+	if key.format == "svg" {
+		contentType = "image/svg+xml"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	if _, err := w.Write(body); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to write QR code response: %v", err)
+	}
+}
+
+func (h *QRHandler) render(key qrKey) ([]byte, error) {
+	url := strings.ReplaceAll(h.productURL, "{id}", strconv.Itoa(key.id))
+	code, err := qrcode.Encode([]byte(url), key.level)
+	if err != nil {
+		return nil, err
+	}
+	if key.format == "svg" {
+		return code.SVG(key.size), nil
+	}
+	return code.PNG(key.size)
+}
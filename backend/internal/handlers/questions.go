@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/auth"
+	"sample-backend/internal/models"
+	"sample-backend/internal/pagination"
+	"sample-backend/internal/recommend"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
+)
+
+// maxPostBodySize は質問・回答の投稿で読むリクエストボディの上限
+const maxPostBodySize = 64 << 10
+
+// QuestionHandler は製品の Q&A を扱う。公開 API と管理用リスナーの両方から使う
+type QuestionHandler struct {
+	svc *service.QuestionService
+}
+
+func NewQuestionHandler(svc *service.QuestionService) *QuestionHandler {
+	return &QuestionHandler{svc: svc}
+}
+
+// pathID はパスの {id} を正の整数として読む
+func pathID(r *http.Request, what string) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		return 0, apperr.Validation("Invalid " + what + " id")
+	}
+	return id, nil
+}
+
+func decodePost(w http.ResponseWriter, r *http.Request) (models.PostRequest, error) {
+	var req models.PostRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPostBodySize)).Decode(&req); err != nil {
+		reqlog.From(r.Context()).Printf("[ERROR] Failed to decode request body: %v", err)
+		return req, apperr.Validation("Invalid request body")
+	}
+	return req, nil
+}
+
+func writeCreated(w http.ResponseWriter, r *http.Request, v interface{}) {
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		reqlog.From(r.Context()).Printf("[ERROR] Failed to encode response: %v", err)
+	}
+}
+
+// ListQuestions は製品の公開中の質問を回答付きで返す
+func (h *QuestionHandler) ListQuestions(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "list_questions")
+	defer span.End()
+
+	id, err := pathID(r, "product")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	span.SetAttributes(attribute.Int("product.id", id))
+
+	response, err := h.svc.ListQuestions(ctx, id, pagination.ParseQuery(r.URL.Query()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode questions response: %v", err)
+	}
+}
+
+// AskQuestion は質問を投稿する。管理者が承認するまでは公開されない
+func (h *QuestionHandler) AskQuestion(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "ask_question")
+	defer span.End()
+
+	id, err := pathID(r, "product")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	span.SetAttributes(attribute.Int("product.id", id))
+
+	req, err := decodePost(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	question, err := h.svc.AskQuestion(ctx, id, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeCreated(w, r, question)
+}
+
+// SellerAnswer は出品者の回答を投稿する。API キーで認証したクライアントを回答者とする
+func (h *QuestionHandler) SellerAnswer(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "seller_answer")
+	defer span.End()
+
+	client, ok := auth.ClientFrom(ctx)
+	if !ok {
+		writeError(w, r, apperr.Unauthorized("An API key is required to answer questions"))
+		return
+	}
+	h.answer(w, r, models.AnswerBySeller, client)
+}
+
+// AdminAnswer は管理者の回答を投稿する (管理用リスナー)。回答者の名前が無ければ "admin" とする
+func (h *QuestionHandler) AdminAnswer(w http.ResponseWriter, r *http.Request) {
+	h.answer(w, r, models.AnswerByAdmin, "")
+}
+
+func (h *QuestionHandler) answer(w http.ResponseWriter, r *http.Request, role, author string) {
+	ctx := r.Context()
+	id, err := pathID(r, "question")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	req, err := decodePost(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if author != "" {
+		req.Author = author
+	} else if req.Author == "" {
+		req.Author = role
+	}
+
+	answer, err := h.svc.Answer(ctx, id, role, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeCreated(w, r, answer)
+}
+
+// MarkQuestionHelpful は質問に「役に立った」を付ける
+func (h *QuestionHandler) MarkQuestionHelpful(w http.ResponseWriter, r *http.Request) {
+	h.markHelpful(w, r, repository.VoteQuestion)
+}
+
+// MarkAnswerHelpful は回答に「役に立った」を付ける
+func (h *QuestionHandler) MarkAnswerHelpful(w http.ResponseWriter, r *http.Request) {
+	h.markHelpful(w, r, repository.VoteAnswer)
+}
+
+func (h *QuestionHandler) markHelpful(w http.ResponseWriter, r *http.Request, target string) {
+	ctx, span := tracer.Start(r.Context(), "mark_helpful")
+	defer span.End()
+
+	id, err := pathID(r, target)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	span.SetAttributes(attribute.String("qa.target", target), attribute.Int("qa.id", id))
+
+	if err := h.svc.MarkHelpful(ctx, target, id, r.Header.Get(recommend.VisitorIDHeader)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListForModeration は状態 (?status=、既定は pending) ごとに全製品の質問を返す (管理用リスナー)
+func (h *QuestionHandler) ListForModeration(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "list_questions_for_moderation")
+	defer span.End()
+
+	query := r.URL.Query()
+	response, err := h.svc.ListForModeration(ctx, query.Get("status"), pagination.ParseQuery(query))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode questions response: %v", err)
+	}
+}
+
+// ModerateQuestion は質問の公開状態を {"status": "approved"} のように変える (管理用リスナー)
+func (h *QuestionHandler) ModerateQuestion(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "moderate_question")
+	defer span.End()
+
+	id, err := pathID(r, "question")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPostBodySize)).Decode(&req); err != nil {
+		writeError(w, r, apperr.Validation("Invalid request body"))
+		return
+	}
+	span.SetAttributes(attribute.Int("qa.id", id), attribute.String("status", req.Status))
+
+	if err := h.svc.Moderate(ctx, id, req.Status); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteAnswer は回答を削除する (管理用リスナー)
+func (h *QuestionHandler) DeleteAnswer(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "delete_answer")
+	defer span.End()
+
+	id, err := pathID(r, "answer")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.svc.DeleteAnswer(ctx, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
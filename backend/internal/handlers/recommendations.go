@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
+)
+
+type RecommendationHandler struct {
+	svc *service.RecommendationService
+}
+
+func NewRecommendationHandler(svc *service.RecommendationService) *RecommendationHandler {
+	return &RecommendationHandler{svc: svc}
+}
+
+func (h *RecommendationHandler) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "get_recommendations")
+	defer span.End()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, r, apperr.Validation("Invalid product id"))
+		return
+	}
+	// 範囲外の件数はサービスが丸める
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	response, err := h.svc.GetRecommendations(ctx, id, limit)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode recommendations response: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"sample-backend/internal/reindex"
+	"sample-backend/internal/reqlog"
+)
+
+// ReindexHandler は検索用テーブルの作り直し (管理用リスナーのみで公開) を扱う
+type ReindexHandler struct {
+	runner *reindex.Runner
+}
+
+func NewReindexHandler(runner *reindex.Runner) *ReindexHandler {
+	return &ReindexHandler{runner: runner}
+}
+
+// StartReindex は作り直しを開始して 202 を返す。進み具合は GetReindexStatus で確認する
+func (h *ReindexHandler) StartReindex(w http.ResponseWriter, r *http.Request) {
+	progress, err := h.runner.Start()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(progress); err != nil {
+		reqlog.From(r.Context()).Printf("[ERROR] Failed to encode reindex status: %v", err)
+	}
+}
+
+func (h *ReindexHandler) GetReindexStatus(w http.ResponseWriter, r *http.Request) {
+	if err := json.NewEncoder(w).Encode(h.runner.Status()); err != nil {
+		reqlog.From(r.Context()).Printf("[ERROR] Failed to encode reindex status: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/pagination"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
+)
+
+// ReviewHandler は製品のレビューを扱う
+type ReviewHandler struct {
+	svc *service.ReviewService
+}
+
+func NewReviewHandler(svc *service.ReviewService) *ReviewHandler {
+	return &ReviewHandler{svc: svc}
+}
+
+// ListReviews は製品のレビューを新しい順に返す
+func (h *ReviewHandler) ListReviews(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "list_reviews")
+	defer span.End()
+
+	id, err := pathID(r, "product")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	span.SetAttributes(attribute.Int("product.id", id))
+
+	response, err := h.svc.ListReviews(ctx, id, pagination.ParseQuery(r.URL.Query()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode reviews response: %v", err)
+	}
+}
+
+// PostReview はレビューを投稿する。{"author": ..., "rating": 1〜5, "title": ..., "body": ...}
+func (h *ReviewHandler) PostReview(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "post_review")
+	defer span.End()
+
+	id, err := pathID(r, "product")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	span.SetAttributes(attribute.Int("product.id", id))
+
+	var req models.ReviewRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPostBodySize)).Decode(&req); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to decode request body: %v", err)
+		writeError(w, r, apperr.Validation("Invalid request body"))
+		return
+	}
+	review, err := h.svc.PostReview(ctx, id, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeCreated(w, r, review)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
+)
+
+// SaleHandler はタイムセールを扱う。開催中の一覧は公開し、登録と削除は管理用リスナーのみで公開する
+type SaleHandler struct {
+	svc *service.SaleService
+}
+
+func NewSaleHandler(svc *service.SaleService) *SaleHandler {
+	return &SaleHandler{svc: svc}
+}
+
+// GetActiveSales は開催中と開始間近のセールを返す。
+// セールの開始時にアクセスが集中するため、メモリ上の JSON をそのまま返し CDN にも 1 秒だけキャッシュさせる
+func (h *SaleHandler) GetActiveSales(w http.ResponseWriter, r *http.Request) {
+	_, span := tracer.Start(r.Context(), "get_active_sales")
+	defer span.End()
+
+	body, err := h.svc.ActiveJSON()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=1")
+	if _, err := w.Write(body); err != nil {
+		reqlog.From(r.Context()).Printf("[ERROR] Failed to write active sales response: %v", err)
+	}
+}
+
+// ListSales は登録済みのセールを新しい順に返す (管理用)
+func (h *SaleHandler) ListSales(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "list_sales")
+	defer span.End()
+
+	list, err := h.svc.Recent(ctx)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"sales": list}); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode sales response: %v", err)
+	}
+}
+
+func (h *SaleHandler) CreateSale(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "create_sale")
+	defer span.End()
+
+	var req models.SaleRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPostBodySize)).Decode(&req); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to decode request body: %v", err)
+		writeError(w, r, apperr.Validation("Invalid request body"))
+		return
+	}
+	span.SetAttributes(attribute.Int("sale.items", len(req.Items)))
+
+	sale, err := h.svc.Create(ctx, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeCreated(w, r, sale)
+}
+
+func (h *SaleHandler) DeleteSale(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "delete_sale")
+	defer span.End()
+
+	id, err := pathID(r, "sale")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.svc.Delete(ctx, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/auth"
+	"sample-backend/internal/models"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/service"
+)
+
+// StockHandler は製品の在庫を扱う
+type StockHandler struct {
+	svc *service.StockService
+}
+
+func NewStockHandler(svc *service.StockService) *StockHandler {
+	return &StockHandler{svc: svc}
+}
+
+// AdjustStock は在庫を {"delta": 10} のように増減する。製品の書き込みと同じく API キーか admin のユーザーに限る
+func (h *StockHandler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "adjust_stock")
+	defer span.End()
+
+	id, err := pathID(r, "product")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	span.SetAttributes(attribute.Int("product.id", id))
+	if err := authorizeProductWrite(ctx); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var req models.StockAdjustRequest
+	if err := decodeStockRequest(w, r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response, err := h.svc.Adjust(ctx, id, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode stock response: %v", err)
+	}
+}
+
+// Reserve は在庫から {"quantity": 2} を引き当てる。在庫が足りなければ 409 と現在の在庫数を返す。
+// 誰でも在庫を 0 にできないよう、API キーのクライアントか JWT で認証したユーザーに限る
+func (h *StockHandler) Reserve(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "reserve_stock")
+	defer span.End()
+
+	id, err := pathID(r, "product")
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	span.SetAttributes(attribute.Int("product.id", id))
+	if err := authorizeReserve(ctx); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	var req models.ReserveRequest
+	if err := decodeStockRequest(w, r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	response, err := h.svc.Reserve(ctx, id, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode stock response: %v", err)
+	}
+}
+
+func decodeStockRequest(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPostBodySize)).Decode(v); err != nil {
+		reqlog.From(r.Context()).Printf("[ERROR] Failed to decode request body: %v", err)
+		return apperr.Validation("Invalid request body")
+	}
+	return nil
+}
+
+// authorizeReserve は在庫の引き当てを API キーで認証したクライアントか、JWT で認証したユーザー (役割は問わない) に許可する
+func authorizeReserve(ctx context.Context) error {
+	if _, ok := auth.ClientFrom(ctx); ok {
+		return nil
+	}
+	if _, ok := auth.UserFrom(ctx); ok {
+		return nil
+	}
+	return apperr.Unauthorized("An API key or a login token is required to reserve stock")
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+)
+
+// SupplierHandler は製品の仕入れ情報 (管理用リスナーのみで公開) を扱う。
+// 暗号化はモデルの型が担うため、ここでは平文として読み書きする
+type SupplierHandler struct {
+	repo repository.SupplierRepository
+}
+
+func NewSupplierHandler(repo repository.SupplierRepository) *SupplierHandler {
+	return &SupplierHandler{repo: repo}
+}
+
+func (h *SupplierHandler) GetSupplierInfo(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "get_supplier_info")
+	defer span.End()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		writeError(w, r, apperr.Validation("Invalid product id"))
+		return
+	}
+	span.SetAttributes(attribute.Int("product.id", id))
+
+	info, err := h.repo.GetSupplierInfo(ctx, id)
+	if err != nil {
+		if !errors.Is(err, apperr.ErrNotFound) {
+			reqlog.From(ctx).Printf("[DB ERROR] Failed to get supplier info: %v", err)
+			span.SetAttributes(attribute.String("error", err.Error()))
+		}
+		writeError(w, r, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		reqlog.From(ctx).Printf("[ERROR] Failed to encode supplier info: %v", err)
+	}
+}
+
+func (h *SupplierHandler) PutSupplierInfo(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "put_supplier_info")
+	defer span.End()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		writeError(w, r, apperr.Validation("Invalid product id"))
+		return
+	}
+	span.SetAttributes(attribute.Int("product.id", id))
+
+	var info models.SupplierInfo
+	if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
+		writeError(w, r, apperr.Validation("Invalid request body"))
+		return
+	}
+	if info.SupplierCost < 0 {
+		writeError(w, r, apperr.Validation("supplier_cost must be >= 0"))
+		return
+	}
+	info.ProductID = id
+
+	if err := h.repo.SaveSupplierInfo(ctx, &info); err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to save supplier info: %v", err)
+		span.SetAttributes(attribute.String("error", err.Error()))
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
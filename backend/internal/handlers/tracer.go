@@ -0,0 +1,7 @@
+package handlers
+
+import "go.opentelemetry.io/otel"
+
+// tracer はハンドラー共通のトレーサー。リクエストごとに otel.Tracer を引かないよう
+// パッケージで 1 つだけ取得しておく (プロバイダーが後から設定されても委譲される)
+var tracer = otel.Tracer("product-search-backend")
@@ -0,0 +1,48 @@
+// Package health はプロセスがリクエストを受け付けられる状態かどうか (readiness) を管理する
+package health
+
+import (
+	"sort"
+	"sync"
+)
+
+// Readiness は依存先 (DB など) ごとの状態をまとめる。1 つでも異常があれば準備ができていないとみなす
+type Readiness struct {
+	mu sync.RWMutex
+	// 依存先の名前 → 異常の理由 (正常なら含まない)
+	failing map[string]string
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{failing: map[string]string{}}
+}
+
+// Set は依存先の状態を更新する。reason が空なら正常
+func (r *Readiness) Set(component, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if reason == "" {
+		delete(r.failing, component)
+		return
+	}
+	r.failing[component] = reason
+}
+
+// Ready はすべての依存先が正常なら true を返す
+func (r *Readiness) Ready() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.failing) == 0
+}
+
+// Failing は異常のある依存先と理由を名前順に返す
+func (r *Readiness) Failing() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.failing))
+	for name, reason := range r.failing {
+		out = append(out, name+": "+reason)
+	}
+	sort.Strings(out)
+	return out
+}
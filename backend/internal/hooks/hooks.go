@@ -0,0 +1,150 @@
+// Package hooks はリクエストの各段階 (開始・SQL の実行・応答・エラー) に処理を差し込む登録先。
+// メトリクスや監査などの横断的な機能は Registry に関数を登録するだけで、ハンドラーを編集せずに追加できる。
+// 登録はサーバーの起動前 (app.New の中) に済ませること
+package hooks
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Query は実行した 1 回分の SQL
+type Query struct {
+	Kind     string // "query" または "exec"
+	Query    string
+	Duration time.Duration
+	Rows     int64
+	Err      error
+}
+
+// Response はハンドラーが返した応答
+type Response struct {
+	// マッチしたルートのパターン (どのルートにもマッチしなければ空)
+	Route    string
+	Status   int
+	Bytes    int
+	Duration time.Duration
+}
+
+// Registry は登録された関数の一覧。nil の Registry は何もしない
+type Registry struct {
+	mu           sync.RWMutex
+	requestStart []func(r *http.Request)
+	dbQuery      []func(ctx context.Context, q Query)
+	response     []func(r *http.Request, res Response)
+	errs         []func(r *http.Request, status int, err error)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// OnRequestStart はリクエストを受け付けたとき (ルーティングの前) に呼ぶ関数を登録する
+func (h *Registry) OnRequestStart(fn func(r *http.Request)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requestStart = append(h.requestStart, fn)
+}
+
+// OnDBQuery は SQL を実行するたびに呼ぶ関数を登録する。DB に接続する前に登録したものだけが有効になる
+func (h *Registry) OnDBQuery(fn func(ctx context.Context, q Query)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dbQuery = append(h.dbQuery, fn)
+}
+
+// OnResponse はハンドラーが応答を返し終えたときに呼ぶ関数を登録する
+func (h *Registry) OnResponse(fn func(r *http.Request, res Response)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.response = append(h.response, fn)
+}
+
+// OnError はハンドラーがエラーを返したとき (panic を含む) に呼ぶ関数を登録する
+func (h *Registry) OnError(fn func(r *http.Request, status int, err error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.errs = append(h.errs, fn)
+}
+
+// HasDBQuery は SQL の実行を通知する先が登録されているかを返す
+func (h *Registry) HasDBQuery() bool {
+	if h == nil {
+		return false
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.dbQuery) > 0
+}
+
+// RequestStart は OnRequestStart で登録した関数を呼ぶ
+func (h *Registry) RequestStart(r *http.Request) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	fns := h.requestStart
+	h.mu.RUnlock()
+	for _, fn := range fns {
+		fn(r)
+	}
+}
+
+// DBQuery は OnDBQuery で登録した関数を呼ぶ
+func (h *Registry) DBQuery(ctx context.Context, q Query) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	fns := h.dbQuery
+	h.mu.RUnlock()
+	for _, fn := range fns {
+		fn(ctx, q)
+	}
+}
+
+// Response は OnResponse で登録した関数を呼ぶ
+func (h *Registry) Response(r *http.Request, res Response) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	fns := h.response
+	h.mu.RUnlock()
+	for _, fn := range fns {
+		fn(r, res)
+	}
+}
+
+// Error は OnError で登録した関数を呼ぶ
+func (h *Registry) Error(r *http.Request, status int, err error) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	fns := h.errs
+	h.mu.RUnlock()
+	for _, fn := range fns {
+		fn(r, status, err)
+	}
+}
+
+type registryKey struct{}
+
+// NewContext は Registry を載せたコンテキストを返す
+func NewContext(ctx context.Context, h *Registry) context.Context {
+	return context.WithValue(ctx, registryKey{}, h)
+}
+
+// FromContext はコンテキストに載っている Registry を返す。載っていなければ nil (何もしない)
+func FromContext(ctx context.Context) *Registry {
+	h, _ := ctx.Value(registryKey{}).(*Registry)
+	return h
+}
+
+// ReportError はリクエストのコンテキストにある Registry へエラーを通知する
+func ReportError(r *http.Request, status int, err error) {
+	FromContext(r.Context()).Error(r, status, err)
+}
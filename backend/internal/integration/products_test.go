@@ -0,0 +1,114 @@
+// Package integration は実際の MySQL (TEST_DSN) に対してハンドラーを httptest で呼ぶ結合テスト。
+// TEST_DSN が無ければスキップする。docker で MySQL を立てて実行するには scripts/integration-test.sh を使う
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"sample-backend/internal/config"
+	"sample-backend/internal/dbtest"
+	"sample-backend/internal/handlers"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/service"
+)
+
+// newMux はアプリと同じリポジトリとサービスで製品の読み取りのルートを登録する (キャッシュは使わない)
+func newMux(t *testing.T) *http.ServeMux {
+	db := dbtest.Open(t)
+	products := repository.NewProductRepository(db)
+	h := handlers.NewProductHandler(
+		service.NewProductService(products, &config.Config{}),
+		service.NewQuestionService(products, repository.NewQuestionRepository(db)),
+		service.NewHistoryService(repository.NewProductHistoryRepository(db)),
+	)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/products", h.GetProducts)
+	mux.HandleFunc("GET /api/products/{id}", h.GetProduct)
+	return mux
+}
+
+func get(t *testing.T, mux http.Handler, target string, v interface{}) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	if v != nil && w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("GET %s: %v\n%s", target, err, w.Body)
+		}
+	}
+	return w.Code
+}
+
+func TestGetProductsMySQL(t *testing.T) {
+	mux := newMux(t)
+	var total int
+	if err := dbtest.Open(t).Get(&total, "SELECT COUNT(*) FROM products"); err != nil {
+		t.Fatal(err)
+	}
+	if total == 0 {
+		t.Fatal("no seed products (mysql/init/init.sql)")
+	}
+
+	var resp models.PaginatedResponse
+	if code := get(t, mux, "/api/products?page=1&limit=5", &resp); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if resp.Count != total || resp.Page != 1 || len(resp.Products) != min(5, total) {
+		t.Errorf("got count=%d page=%d len=%d, want %d, 1, %d", resp.Count, resp.Page, len(resp.Products), total, min(5, total))
+	}
+	for i := 1; i < len(resp.Products); i++ {
+		if resp.Products[i-1].ID >= resp.Products[i].ID {
+			t.Errorf("products not in id order: %d then %d", resp.Products[i-1].ID, resp.Products[i].ID)
+		}
+	}
+
+	// 最後のページの続きは空
+	var past models.PaginatedResponse
+	if code := get(t, mux, "/api/products?page=100000&limit=5", &past); code != http.StatusOK || len(past.Products) != 0 {
+		t.Errorf("page past the end: status = %d, len = %d", code, len(past.Products))
+	}
+}
+
+func TestGetProductsCategoryMySQL(t *testing.T) {
+	mux := newMux(t)
+	db := dbtest.Open(t)
+	var category string
+	var want int
+	if err := db.QueryRow("SELECT category, COUNT(*) FROM products GROUP BY category ORDER BY category LIMIT 1").Scan(&category, &want); err != nil {
+		t.Fatal(err)
+	}
+
+	var resp models.PaginatedResponse
+	if code := get(t, mux, "/api/products?limit=100&category="+category, &resp); code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if resp.Count != want {
+		t.Errorf("count = %d, want %d", resp.Count, want)
+	}
+	for _, p := range resp.Products {
+		if p.Category != category {
+			t.Errorf("product %d has category %q, want %q", p.ID, p.Category, category)
+		}
+	}
+}
+
+func TestGetProductMySQL(t *testing.T) {
+	mux := newMux(t)
+	var id int
+	if err := dbtest.Open(t).Get(&id, "SELECT MIN(id) FROM products"); err != nil {
+		t.Fatal(err)
+	}
+
+	var detail models.ProductDetail
+	if code := get(t, mux, "/api/products/"+strconv.Itoa(id), &detail); code != http.StatusOK || detail.ID != id {
+		t.Errorf("GET /api/products/%d: status = %d, id = %d", id, code, detail.ID)
+	}
+	if code := get(t, mux, "/api/products/999999999", nil); code != http.StatusNotFound {
+		t.Errorf("missing product: status = %d, want 404", code)
+	}
+}
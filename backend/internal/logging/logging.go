@@ -0,0 +1,164 @@
+// Package logging はログのレベルと出力形式 (従来の 1 行テキスト / slog の text / JSON) を扱う。
+//
+// 各行は従来どおり "[TAG] メッセージ" の形で出力すればよく、レベルはタグから決める
+// (ERROR・FATAL・PANIC を含めばエラー、WARN・SLOW なら警告、DEBUG ならデバッグ、それ以外は情報)。
+// 標準の log パッケージへの出力もここを通るので、呼び出し側を書き換えずにレベルで絞り込める
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 出力形式
+const (
+	// FormatPlain は従来の 1 行テキスト (属性は末尾に key=value で付ける)
+	FormatPlain = "plain"
+	FormatText  = "text"
+	FormatJSON  = "json"
+)
+
+// plainTimeFormat は log.LstdFlags と同じ時刻の書式
+const plainTimeFormat = "2006/01/02 15:04:05 "
+
+type settings struct {
+	level slog.Level
+	// nil なら plain
+	handler slog.Handler
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// current は Setup で決めた設定。Setup を呼ばないコマンドでは nil のままで、標準の log にそのまま出力する
+var current atomic.Pointer[settings]
+
+// Setup はログのレベルと形式を設定し、標準の log パッケージの出力もそれに従わせる
+func Setup(level, format string) error {
+	lv, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	s := &settings{level: lv, out: os.Stderr}
+	opts := &slog.HandlerOptions{Level: lv}
+	switch strings.ToLower(format) {
+	case FormatPlain, "":
+	case FormatText:
+		s.handler = slog.NewTextHandler(os.Stderr, opts)
+	case FormatJSON:
+		s.handler = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("unknown log format %q (plain, text or json)", format)
+	}
+	current.Store(s)
+
+	if s.handler != nil {
+		slog.SetDefault(slog.New(s.handler))
+	}
+	// 時刻はここで付けるので log パッケージでは付けない (SetDefault の後に差し替える)
+	log.SetFlags(0)
+	log.SetOutput(lineWriter{})
+	return nil
+}
+
+// ParseLevel は debug / info / warn / error を slog.Level にする
+func ParseLevel(s string) (slog.Level, error) {
+	var lv slog.Level
+	if err := lv.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (debug, info, warn or error)", s)
+	}
+	return lv, nil
+}
+
+// Output は msg を出力する。attrs は text / JSON では属性として、plain ではメッセージの末尾に key=value として付ける。
+// calldepth は log.Output と同じで、Setup の前 (標準の log にそのまま出力する場合) にだけ使う
+func Output(calldepth int, msg string, attrs ...slog.Attr) {
+	s := current.Load()
+	if s == nil {
+		log.Output(calldepth+1, msg+formatAttrs(attrs))
+		return
+	}
+	s.emit(msg, attrs)
+}
+
+func (s *settings) emit(msg string, attrs []slog.Attr) {
+	level, tag, text := parseTag(msg)
+	if level < s.level {
+		return
+	}
+
+	if s.handler == nil {
+		line := time.Now().Format(plainTimeFormat) + msg + formatAttrs(attrs) + "\n"
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		io.WriteString(s.out, line)
+		return
+	}
+
+	r := slog.NewRecord(time.Now(), level, text, 0)
+	if tag != "" {
+		r.AddAttrs(slog.String("tag", tag))
+	}
+	r.AddAttrs(attrs...)
+	s.handler.Handle(context.Background(), r)
+}
+
+// parseTag は "[TAG] メッセージ" をレベル・タグ・メッセージに分ける。タグが無ければ情報レベル
+func parseTag(msg string) (slog.Level, string, string) {
+	if !strings.HasPrefix(msg, "[") {
+		return slog.LevelInfo, "", msg
+	}
+	end := strings.IndexByte(msg, ']')
+	if end < 0 {
+		return slog.LevelInfo, "", msg
+	}
+	tag, text := msg[1:end], strings.TrimSpace(msg[end+1:])
+
+	switch {
+	case strings.Contains(tag, "ERROR"), strings.Contains(tag, "FATAL"), strings.Contains(tag, "PANIC"):
+		return slog.LevelError, tag, text
+	case strings.Contains(tag, "WARN"), strings.Contains(tag, "SLOW"):
+		return slog.LevelWarn, tag, text
+	case strings.Contains(tag, "DEBUG"):
+		return slog.LevelDebug, tag, text
+	}
+	return slog.LevelInfo, tag, text
+}
+
+// formatAttrs は plain 形式で末尾に付ける " key=value ..." を返す。空の値は省く
+func formatAttrs(attrs []slog.Attr) string {
+	var b strings.Builder
+	for _, a := range attrs {
+		value := a.Value.String()
+		if value == "" {
+			continue
+		}
+		b.WriteByte(' ')
+		b.WriteString(a.Key)
+		b.WriteByte('=')
+		if strings.ContainsAny(value, " \"=") {
+			fmt.Fprintf(&b, "%q", value)
+			continue
+		}
+		b.WriteString(value)
+	}
+	return b.String()
+}
+
+// lineWriter は標準の log パッケージの出力を 1 行ずつ受け取って Output に渡す
+type lineWriter struct{}
+
+func (lineWriter) Write(p []byte) (int, error) {
+	if s := current.Load(); s != nil {
+		s.emit(strings.TrimSuffix(string(p), "\n"), nil)
+	}
+	return len(p), nil
+}
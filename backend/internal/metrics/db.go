@@ -0,0 +1,133 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+)
+
+var (
+	dbQueryDuration = NewHistogramVec("db_query_duration_seconds", "Duration of product repository queries.", DefBuckets, "operation")
+	dbQueryErrors   = NewCounterVec("db_query_errors_total", "Product repository queries that returned an error.", "operation")
+)
+
+// observe は操作にかかった時間と失敗を記録する。defer で呼ぶため、エラーは名前付きの戻り値へのポインタで受け取る。
+// 見つからない (ErrNotFound) のは DB の失敗ではないので数えない
+func observe(op string, start time.Time, errp *error) {
+	dbQueryDuration.With(op).Observe(time.Since(start).Seconds())
+	if err := *errp; err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		dbQueryErrors.With(op).Inc()
+	}
+}
+
+// RegisterDBStats は接続プールの状態を公開する。公開するたびに db.Stats を読む
+func RegisterDBStats(db *sql.DB) {
+	NewGaugeFunc("db_connections_open", "Open connections in the database pool.", func() float64 {
+		return float64(db.Stats().OpenConnections)
+	})
+	NewGaugeFunc("db_connections_in_use", "Connections currently in use.", func() float64 {
+		return float64(db.Stats().InUse)
+	})
+	NewCounterFunc("db_connection_waits_total", "Times a query waited for a free connection.", func() float64 {
+		return float64(db.Stats().WaitCount)
+	})
+	NewCounterFunc("db_connection_wait_seconds_total", "Total time spent waiting for a free connection.", func() float64 {
+		return db.Stats().WaitDuration.Seconds()
+	})
+}
+
+// instrumentedRepository は製品リポジトリの操作ごとの所要時間を記録する
+type instrumentedRepository struct {
+	next repository.ProductRepository
+}
+
+// WrapRepository は操作ごとの所要時間を記録する ProductRepository を返す。
+// キャッシュに当たった分を含めないよう、DB に問い合わせるリポジトリを直接包む
+func WrapRepository(next repository.ProductRepository) repository.ProductRepository {
+	return &instrumentedRepository{next: next}
+}
+
+func (r *instrumentedRepository) Count(ctx context.Context, filter repository.ListFilter) (n int, err error) {
+	defer observe("count", time.Now(), &err)
+	return r.next.Count(ctx, filter)
+}
+
+func (r *instrumentedRepository) List(ctx context.Context, filter repository.ListFilter, limit, offset int) (products []models.Product, err error) {
+	defer observe("list", time.Now(), &err)
+	return r.next.List(ctx, filter, limit, offset)
+}
+
+func (r *instrumentedRepository) ListAfter(ctx context.Context, filter repository.ListFilter, afterID, limit int) (products []models.Product, err error) {
+	defer observe("list_after", time.Now(), &err)
+	return r.next.ListAfter(ctx, filter, afterID, limit)
+}
+
+func (r *instrumentedRepository) CountBy(ctx context.Context, filter repository.ListFilter, column string, limit int) (counts []models.FacetCount, err error) {
+	defer observe("count_by_"+column, time.Now(), &err)
+	return r.next.CountBy(ctx, filter, column, limit)
+}
+
+func (r *instrumentedRepository) CountByPrice(ctx context.Context, filter repository.ListFilter, bounds []float64) (buckets []models.PriceBucket, err error) {
+	defer observe("count_by_price", time.Now(), &err)
+	return r.next.CountByPrice(ctx, filter, bounds)
+}
+
+func (r *instrumentedRepository) Get(ctx context.Context, id int) (p *models.Product, err error) {
+	defer observe("get", time.Now(), &err)
+	return r.next.Get(ctx, id)
+}
+
+func (r *instrumentedRepository) GetMany(ctx context.Context, ids []int) (products []models.Product, err error) {
+	defer observe("get_many", time.Now(), &err)
+	return r.next.GetMany(ctx, ids)
+}
+
+func (r *instrumentedRepository) GetBySKU(ctx context.Context, sku string) (p *models.Product, err error) {
+	defer observe("get_by_sku", time.Now(), &err)
+	return r.next.GetBySKU(ctx, sku)
+}
+
+func (r *instrumentedRepository) SearchCount(ctx context.Context, q repository.SearchQuery) (n int, err error) {
+	defer observe("search_count", time.Now(), &err)
+	return r.next.SearchCount(ctx, q)
+}
+
+func (r *instrumentedRepository) Search(ctx context.Context, q repository.SearchQuery, limit, offset int) (products []models.Product, err error) {
+	defer observe("search", time.Now(), &err)
+	return r.next.Search(ctx, q, limit, offset)
+}
+
+func (r *instrumentedRepository) FullTextCount(ctx context.Context, keyword string) (n int, err error) {
+	defer observe("fulltext_count", time.Now(), &err)
+	return r.next.FullTextCount(ctx, keyword)
+}
+
+func (r *instrumentedRepository) FullText(ctx context.Context, keyword string, limit, offset int) (products []models.Product, err error) {
+	defer observe("fulltext", time.Now(), &err)
+	return r.next.FullText(ctx, keyword, limit, offset)
+}
+
+func (r *instrumentedRepository) Create(ctx context.Context, p *models.Product) (err error) {
+	defer observe("create", time.Now(), &err)
+	return r.next.Create(ctx, p)
+}
+
+func (r *instrumentedRepository) CreateBatch(ctx context.Context, products []models.Product) (err error) {
+	defer observe("create_batch", time.Now(), &err)
+	return r.next.CreateBatch(ctx, products)
+}
+
+func (r *instrumentedRepository) Update(ctx context.Context, p *models.Product) (old *models.Product, err error) {
+	defer observe("update", time.Now(), &err)
+	return r.next.Update(ctx, p)
+}
+
+func (r *instrumentedRepository) Delete(ctx context.Context, id int) (err error) {
+	defer observe("delete", time.Now(), &err)
+	return r.next.Delete(ctx, id)
+}
@@ -0,0 +1,282 @@
+// Package metrics は Prometheus のテキスト形式で公開する集計値 (カウンター・ゲージ・ヒストグラム) を扱う。
+//
+// トレースは個々のリクエストの調査に使い、こちらはレイテンシやエラー率の推移を見るために使う。
+// 系列はラベルの値ごとに作られるので、ラベルにはルートのパターンや操作名など種類が限られる値だけを使うこと
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets はレイテンシ (秒) のヒストグラムの既定の区切り
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// sample は 1 つの系列の値
+type sample interface {
+	// write は name の系列の値を書き出す。labels は `k="v",...` の形 (ラベルが無ければ空文字)
+	write(w io.Writer, name, labels string)
+}
+
+type series struct {
+	labels string
+	s      sample
+}
+
+// family は名前が同じでラベルの値が異なる系列の集まり
+type family struct {
+	name, help, kind string
+	labels           []string
+	newSample        func() sample
+
+	mu     sync.RWMutex
+	series map[string]*series
+}
+
+var registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+func register(name, help, kind string, labels []string, newSample func() sample) *family {
+	f := &family{name: name, help: help, kind: kind, labels: labels, newSample: newSample, series: map[string]*series{}}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	for _, other := range registry.families {
+		if other.name == name {
+			panic("metrics: duplicate metric " + name)
+		}
+	}
+	registry.families = append(registry.families, f)
+	return f
+}
+
+// get はラベルの値に対応する系列を返す。初めての値なら作る
+func (f *family) get(values []string) sample {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	f.mu.RLock()
+	s, ok := f.series[key]
+	f.mu.RUnlock()
+	if ok {
+		return s.s
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[key]; ok {
+		return s.s
+	}
+	pairs := make([]string, len(values))
+	for i, v := range values {
+		pairs[i] = f.labels[i] + `="` + escapeLabel(v) + `"`
+	}
+	s = &series{labels: strings.Join(pairs, ","), s: f.newSample()}
+	f.series[key] = s
+	return s.s
+}
+
+func (f *family) write(w io.Writer) {
+	f.mu.RLock()
+	list := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		list = append(list, s)
+	}
+	f.mu.RUnlock()
+	if len(list) == 0 {
+		return
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].labels < list[j].labels })
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+	for _, s := range list {
+		s.s.write(w, f.name, s.labels)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Counter は増えるだけの値
+type Counter struct {
+	v atomic.Uint64
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+func (c *Counter) write(w io.Writer, name, labels string) {
+	fmt.Fprintf(w, "%s%s %d\n", name, braces(labels), c.v.Load())
+}
+
+// CounterVec はラベルの値ごとの Counter
+type CounterVec struct {
+	f *family
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{f: register(name, help, "counter", labels, func() sample { return new(Counter) })}
+}
+
+// With はラベルの値 (NewCounterVec に渡した順) に対応する Counter を返す
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.f.get(values).(*Counter)
+}
+
+// Gauge は増減する値
+type Gauge struct {
+	v atomic.Int64
+}
+
+func (g *Gauge) Inc() {
+	g.v.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.v.Add(-1)
+}
+
+func (g *Gauge) write(w io.Writer, name, labels string) {
+	fmt.Fprintf(w, "%s%s %d\n", name, braces(labels), g.v.Load())
+}
+
+// GaugeVec はラベルの値ごとの Gauge
+type GaugeVec struct {
+	f *family
+}
+
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{f: register(name, help, "gauge", labels, func() sample { return new(Gauge) })}
+}
+
+// With はラベルの値 (NewGaugeVec に渡した順) に対応する Gauge を返す
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return v.f.get(values).(*Gauge)
+}
+
+// Histogram は観測値の分布。区切りごとの件数と合計を持つ
+type Histogram struct {
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *Histogram) write(w io.Writer, name, labels string) {
+	h.mu.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	count, sum := h.count, h.sum
+	h.mu.Unlock()
+
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+	// Prometheus のバケットは累積の件数
+	var cumulative uint64
+	for i, le := range h.buckets {
+		cumulative += counts[i]
+		fmt.Fprintf(w, "%s_bucket{%s%sle=\"%s\"} %d\n", name, labels, sep, formatFloat(le), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, braces(labels), formatFloat(sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, braces(labels), count)
+}
+
+// HistogramVec はラベルの値ごとの Histogram
+type HistogramVec struct {
+	f *family
+}
+
+// NewHistogramVec は buckets (昇順) を区切りとする HistogramVec を返す
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &HistogramVec{f: register(name, help, "histogram", labels, func() sample {
+		return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+	})}
+}
+
+// With はラベルの値 (NewHistogramVec に渡した順) に対応する Histogram を返す
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.f.get(values).(*Histogram)
+}
+
+// funcSample は公開するたびに fn を呼んで値を求める系列
+type funcSample func() float64
+
+func (fn funcSample) write(w io.Writer, name, labels string) {
+	fmt.Fprintf(w, "%s%s %s\n", name, braces(labels), formatFloat(fn()))
+}
+
+// NewGaugeFunc は公開するたびに fn の値を返すゲージを登録する (接続プールの状態など)
+func NewGaugeFunc(name, help string, fn func() float64) {
+	register(name, help, "gauge", nil, func() sample { return funcSample(fn) }).get(nil)
+}
+
+// NewCounterFunc は公開するたびに fn の値を返すカウンターを登録する。fn は減らない値を返すこと
+func NewCounterFunc(name, help string, fn func() float64) {
+	register(name, help, "counter", nil, func() sample { return funcSample(fn) }).get(nil)
+}
+
+// Handler は登録したすべての値を Prometheus のテキスト形式で返す
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registry.mu.Lock()
+		families := append([]*family(nil), registry.families...)
+		registry.mu.Unlock()
+		sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		for _, f := range families {
+			f.write(bw)
+		}
+		bw.Flush()
+	})
+}
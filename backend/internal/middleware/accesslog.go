@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sample-backend/internal/logging"
+	"sample-backend/internal/reqlog"
+)
+
+// AccessLogConfig はアクセスログの出力方針
+type AccessLogConfig struct {
+	// 正常なリクエストを記録する割合 (0.0〜1.0)。エラーと遅いリクエストは常に記録する
+	SampleRate float64
+	// これ以上かかったリクエストは常に記録する
+	SlowThreshold time.Duration
+	// 書き込み待ちのバッファ数。溢れた分は捨てて件数だけ数える
+	BufferSize int
+}
+
+type accessEntry struct {
+	id       string
+	method   string
+	path     string
+	remote   string
+	status   int
+	bytes    int
+	duration time.Duration
+}
+
+// AccessLogger はアクセスログをリクエストのゴルーチンから切り離して非同期に書き出す
+type AccessLogger struct {
+	cfg     AccessLogConfig
+	entries chan accessEntry
+	dropped atomic.Int64
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func NewAccessLogger(cfg AccessLogConfig) *AccessLogger {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1024
+	}
+
+	l := &AccessLogger{
+		cfg:     cfg,
+		entries: make(chan accessEntry, cfg.BufferSize),
+		done:    make(chan struct{}),
+	}
+	go l.run()
+
+	log.Printf("[ACCESS] Async access log enabled - sample_rate: %.2f, slow: %v, buffer: %d", cfg.SampleRate, cfg.SlowThreshold, cfg.BufferSize)
+	return l
+}
+
+// Middleware はリクエストごとの結果を計測してアクセスログに渡す
+func (l *AccessLogger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newStatusRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		duration := time.Since(start)
+		if !l.shouldLog(rec.status, duration) {
+			return
+		}
+
+		entry := accessEntry{
+			id:       reqlog.RequestID(r.Context()),
+			method:   r.Method,
+			path:     r.URL.RequestURI(),
+			remote:   r.RemoteAddr,
+			status:   rec.status,
+			bytes:    rec.bytes,
+			duration: duration,
+		}
+		select {
+		case l.entries <- entry:
+		default:
+			l.dropped.Add(1)
+		}
+	})
+}
+
+// Close は溜まっているログを書き出してから終了する。サーバーがリクエストの受け付けを止めた後に呼び出す
+func (l *AccessLogger) Close() {
+	l.closeOnce.Do(func() {
+		close(l.entries)
+		<-l.done
+	})
+}
+
+func (l *AccessLogger) shouldLog(status int, duration time.Duration) bool {
+	if status >= http.StatusBadRequest {
+		return true
+	}
+	if l.cfg.SlowThreshold > 0 && duration >= l.cfg.SlowThreshold {
+		return true
+	}
+	if l.cfg.SampleRate >= 1 {
+		return true
+	}
+	return l.cfg.SampleRate > 0 && rand.Float64() < l.cfg.SampleRate
+}
+
+func (l *AccessLogger) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-l.entries:
+			if !ok {
+				l.reportDropped()
+				return
+			}
+			tag := "[ACCESS]"
+			if e.status >= http.StatusInternalServerError {
+				tag = "[ACCESS ERROR]"
+			} else if l.cfg.SlowThreshold > 0 && e.duration >= l.cfg.SlowThreshold {
+				tag = "[ACCESS SLOW]"
+			}
+			logging.Output(2, fmt.Sprintf("%s %s %s %d %dB %v from %s", tag, e.method, e.path, e.status, e.bytes, e.duration, e.remote),
+				slog.String("request_id", e.id))
+		case <-ticker.C:
+			l.reportDropped()
+		}
+	}
+}
+
+func (l *AccessLogger) reportDropped() {
+	if n := l.dropped.Swap(0); n > 0 {
+		log.Printf("[ACCESS] Dropped %d log entries (buffer full)", n)
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IPAllowlist はルートグループごとに接続元 IP を CIDR で制限するミドルウェアを返す。
+// cidrs が空ならすべて許可する。拒否したリクエストは監査ログに残して 403 を返す
+func IPAllowlist(group string, cidrs []string, trust *ProxyTrust) func(http.Handler) http.Handler {
+	nets := parseCIDRs(group, cidrs)
+	if len(nets) > 0 {
+		log.Printf("[ALLOWLIST] %s restricted to %v", group, cidrs)
+	}
+
+	return func(next http.Handler) http.Handler {
+		if len(nets) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := ClientIP(r, trust)
+			if !containsIP(nets, ip) {
+				log.Printf("[AUDIT] Blocked %s %s from %s (group: %s, not in allowlist)", r.Method, r.URL.Path, ip, group)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// parseCIDRs は CIDR 表記 (単独の IP アドレスも可) を読み取る
+func parseCIDRs(group string, cidrs []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if !strings.Contains(c, "/") {
+			if ip := net.ParseIP(c); ip != nil {
+				bits := 128
+				if ip.To4() != nil {
+					bits = 32
+				}
+				nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			log.Printf("[ALLOWLIST] Ignoring invalid CIDR %q for %s", c, group)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func containsIP(nets []*net.IPNet, s string) bool {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package middleware
+
+import (
+	"expvar"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sample-backend/internal/clock"
+	"sample-backend/internal/reqlog"
+)
+
+// 検知理由ごとの件数 (管理用リスナーの /debug/vars で参照できる)
+var anomalyRequests = expvar.NewMap("anomaly_requests_total")
+
+const (
+	// この長さ以上でエントロピーの高いパラメータ値はランダムな文字列の注入とみなす
+	entropyMinLength = 24
+	entropyThreshold = 4.5
+	maxQueryLength   = 2048
+	maxPageParam     = 100000
+	maxLimitParam    = 1000
+)
+
+var (
+	suspiciousAgent   = regexp.MustCompile(`(?i)sqlmap|nikto|nmap|masscan|zgrab|acunetix|nessus`)
+	suspiciousPayload = regexp.MustCompile(`(?i)\$\{jndi:|<script|\.\./|union\s+select|/etc/passwd`)
+)
+
+// AnomalyConfig は異常リクエスト検知の設定
+type AnomalyConfig struct {
+	// "flag" はログと集計のみ、"throttle" は同じ IP からの異常が続いたら 429 を返す
+	Mode string
+	// throttle モードで 1 分間に許容する異常リクエスト数
+	ThrottleLimit int
+	TrustProxy    *ProxyTrust
+	// 集計期間の判定に使う Clock (nil ならシステム時刻)
+	Clock clock.Clock
+}
+
+type anomalyWindow struct {
+	start time.Time
+	count int
+}
+
+// AnomalyDetector は経験則による簡易 WAF。正常なリクエストには何もしない
+type AnomalyDetector struct {
+	cfg AnomalyConfig
+
+	mu      sync.Mutex
+	windows map[string]*anomalyWindow
+}
+
+func NewAnomalyDetector(cfg AnomalyConfig) *AnomalyDetector {
+	if cfg.ThrottleLimit <= 0 {
+		cfg.ThrottleLimit = 20
+	}
+	cfg.Clock = clock.OrReal(cfg.Clock)
+	log.Printf("[ANOMALY] Anomaly detection enabled - mode: %s, throttle limit: %d/min", cfg.Mode, cfg.ThrottleLimit)
+	return &AnomalyDetector{cfg: cfg, windows: make(map[string]*anomalyWindow)}
+}
+
+func (d *AnomalyDetector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reasons := detectAnomalies(r)
+		if len(reasons) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := ClientIP(r, d.cfg.TrustProxy)
+		for _, reason := range reasons {
+			anomalyRequests.Add(reason, 1)
+		}
+		reqlog.From(r.Context()).Printf("[ANOMALY] %s %s from %s - reasons: %s", r.Method, r.URL.Path, ip, strings.Join(reasons, ","))
+
+		if d.cfg.Mode == "throttle" && d.exceeded(ip) {
+			anomalyRequests.Add("throttled", 1)
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// exceeded は ip の直近 1 分間の異常リクエスト数を数え、上限を超えたら true を返す
+func (d *AnomalyDetector) exceeded(ip string) bool {
+	now := d.cfg.Clock.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// 大量の IP から来た場合に備え、古い記録はまとめて捨てる
+	if len(d.windows) > 10000 {
+		for k, win := range d.windows {
+			if now.Sub(win.start) > time.Minute {
+				delete(d.windows, k)
+			}
+		}
+	}
+
+	win, ok := d.windows[ip]
+	if !ok || now.Sub(win.start) > time.Minute {
+		win = &anomalyWindow{start: now}
+		d.windows[ip] = win
+	}
+	win.count++
+	return win.count > d.cfg.ThrottleLimit
+}
+
+func detectAnomalies(r *http.Request) []string {
+	var reasons []string
+
+	if len(r.URL.RawQuery) > maxQueryLength {
+		reasons = append(reasons, "oversized_query")
+	}
+
+	query := r.URL.Query()
+	for key, values := range query {
+		for _, v := range values {
+			if len(v) >= entropyMinLength && shannonEntropy(v) > entropyThreshold {
+				reasons = append(reasons, "high_entropy_param")
+				break
+			}
+			if suspiciousPayload.MatchString(v) || suspiciousPayload.MatchString(key) {
+				reasons = append(reasons, "suspicious_param")
+				break
+			}
+		}
+	}
+
+	if absurdNumber(query.Get("page"), maxPageParam) || absurdNumber(query.Get("limit"), maxLimitParam) ||
+		absurdNumber(query.Get("offset"), maxPageParam*maxLimitParam) {
+		reasons = append(reasons, "absurd_pagination")
+	}
+
+	ua := r.Header.Get("User-Agent")
+	if ua == "" || suspiciousAgent.MatchString(ua) {
+		reasons = append(reasons, "suspicious_user_agent")
+	}
+	for _, values := range r.Header {
+		for _, v := range values {
+			if suspiciousPayload.MatchString(v) {
+				reasons = append(reasons, "suspicious_header")
+				return reasons
+			}
+		}
+	}
+
+	return reasons
+}
+
+// absurdNumber は数値パラメータが負・上限超過・数値として長すぎる場合に true を返す
+func absurdNumber(v string, max int) bool {
+	if v == "" {
+		return false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return len(v) > 10
+	}
+	return n < 0 || n > max
+}
+
+// shannonEntropy は文字あたりのシャノンエントロピー (ビット) を返す
+func shannonEntropy(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, c := range s {
+		counts[c]++
+		total++
+	}
+	var h float64
+	for _, n := range counts {
+		p := float64(n) / float64(total)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
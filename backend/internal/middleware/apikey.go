@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sample-backend/internal/auth"
+	"sample-backend/internal/reqlog"
+)
+
+// APIKeyHeader はクライアントが API キーを送るヘッダー
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuth は X-API-Key ヘッダーでクライアントを識別する。
+// ヘッダーが無いリクエストは匿名として通し、不正なキーは失敗として記録して 401 を返す。
+// 失敗が続いたキーと IP アドレスは Lockout によって一定時間 429 で締め出す
+type APIKeyAuth struct {
+	// キーの SHA-256 からクライアント名を引く (生のキーは保持しない)
+	clients    map[[sha256.Size]byte]string
+	lockout    *auth.Lockout
+	trustProxy *ProxyTrust
+}
+
+// NewAPIKeyAuth は クライアント名 → キー の対応から認証ミドルウェアを作る
+func NewAPIKeyAuth(keys map[string]string, lockout *auth.Lockout, trustProxy *ProxyTrust) *APIKeyAuth {
+	clients := make(map[[sha256.Size]byte]string, len(keys))
+	for name, key := range keys {
+		clients[sha256.Sum256([]byte(key))] = name
+	}
+	log.Printf("[AUTH] API key authentication enabled for %d clients", len(clients))
+	return &APIKeyAuth{clients: clients, lockout: lockout, trustProxy: trustProxy}
+}
+
+func (a *APIKeyAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(APIKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		sum := sha256.Sum256([]byte(key))
+		ipKey := "ip:" + ClientIP(r, a.trustProxy)
+		// 記録には生のキーではなくハッシュの先頭だけを使う
+		idKey := "key:" + hex.EncodeToString(sum[:8])
+
+		for _, k := range []string{ipKey, idKey} {
+			if remaining, locked := a.lockout.Locked(k); locked {
+				reqlog.From(r.Context()).Printf("[AUTH] Blocked request from locked %s (%v remaining)", k, remaining.Round(time.Second))
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+				http.Error(w, "Too many failed authentication attempts", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		name, ok := a.clients[sum]
+		if !ok {
+			for _, k := range []string{ipKey, idKey} {
+				if d := a.lockout.Fail(k); d > 0 {
+					log.Printf("[AUTH] Locked %s for %v after repeated failures", k, d)
+				}
+			}
+			reqlog.From(r.Context()).Printf("[AUTH] Invalid API key from %s", ipKey)
+			http.Error(w, "Invalid API key", http.StatusUnauthorized)
+			return
+		}
+
+		a.lockout.Succeed(idKey)
+		a.lockout.Succeed(ipKey)
+		if fields := reqlog.FieldsFrom(r.Context()); fields != nil {
+			fields.User = name
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithClient(r.Context(), name)))
+	})
+}
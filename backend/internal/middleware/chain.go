@@ -0,0 +1,29 @@
+package middleware
+
+import "net/http"
+
+// Middleware はハンドラーを包んで共通処理を加える
+type Middleware func(http.Handler) http.Handler
+
+// Chain は複数のミドルウェアを 1 つにまとめる。先頭が最も外側 (最初に処理する) になる。
+// 設定で無効にしたミドルウェアは nil のまま渡せば読み飛ばす
+func Chain(mws ...Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			if mws[i] != nil {
+				h = mws[i](h)
+			}
+		}
+		return h
+	}
+}
+
+// JSONHeaders は JSON API の既定のレスポンスヘッダーを設定する。
+// エラー時は http.Error が Content-Type を text/plain に上書きする
+func JSONHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"sample-backend/internal/chaos"
+)
+
+// ChaosConfig は障害注入の設定。割合はいずれも 0.0〜1.0 で、リクエストごとに独立に判定する
+type ChaosConfig struct {
+	// Latency だけ応答を遅らせる割合
+	Latency     time.Duration
+	LatencyRate float64
+	// 応答を返さずに接続を切る割合
+	DropRate float64
+	// DB アクセスを失敗させる割合 (chaos.WrapRepository を通したリポジトリが対象)
+	DBErrorRate float64
+}
+
+// Chaos は設定した割合のリクエストに遅延・切断・DB エラーを注入する。
+// タイムアウトやリトライの動作確認用で、本番環境では有効にしない
+func Chaos(cfg ChaosConfig) func(http.Handler) http.Handler {
+	log.Printf("[CHAOS] Fault injection enabled - latency: %v (%.2f), drop: %.2f, db_error: %.2f",
+		cfg.Latency, cfg.LatencyRate, cfg.DropRate, cfg.DBErrorRate)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if cfg.DropRate > 0 && rand.Float64() < cfg.DropRate {
+				chaos.Injected.Add("drop", 1)
+				// net/http は ErrAbortHandler を受けると応答せずに接続を閉じる
+				panic(http.ErrAbortHandler)
+			}
+
+			if cfg.Latency > 0 && cfg.LatencyRate > 0 && rand.Float64() < cfg.LatencyRate {
+				chaos.Injected.Add("latency", 1)
+				select {
+				case <-time.After(cfg.Latency):
+				case <-r.Context().Done():
+					return
+				}
+			}
+
+			if cfg.DBErrorRate > 0 && rand.Float64() < cfg.DBErrorRate {
+				chaos.Injected.Add("db_error", 1)
+				r = r.WithContext(chaos.WithDBFault(r.Context()))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ProxyTrust は X-Forwarded-For / X-Real-IP を信頼する接続元 (nginx などのリバースプロキシ) の CIDR。
+// nil なら転送ヘッダーを読まず、接続元の IP アドレスをそのまま使う
+type ProxyTrust struct {
+	nets []*net.IPNet
+}
+
+// NewProxyTrust は enabled で、有効な CIDR が 1 つ以上あるときだけ ProxyTrust を返す。
+// プロキシを限定せずにヘッダーを信頼すると、どのクライアントも好きな IP アドレスを名乗れるため
+func NewProxyTrust(enabled bool, cidrs []string) *ProxyTrust {
+	if !enabled {
+		return nil
+	}
+	nets := parseCIDRs("trusted_proxies", cidrs)
+	if len(nets) == 0 {
+		return nil
+	}
+	return &ProxyTrust{nets: nets}
+}
+
+func (t *ProxyTrust) trusted(ip string) bool {
+	return t != nil && containsIP(t.nets, ip)
+}
+
+// ClientIP はリクエスト元の IP アドレスを返す。接続元が信頼するプロキシの場合だけ転送ヘッダーを読み、
+// X-Forwarded-For を右から辿って最初の信頼しないアドレスを使う (左側はクライアントが自由に書ける)。
+// X-Forwarded-For が無ければプロキシが付ける X-Real-IP を使う
+func ClientIP(r *http.Request, trust *ProxyTrust) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !trust.trusted(peer) {
+		return peer
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(v, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if net.ParseIP(hops[i]) == nil {
+			// 読めないアドレスより左は信頼できない
+			return peer
+		}
+		if !trust.trusted(hops[i]) {
+			return hops[i]
+		}
+	}
+	if len(hops) > 0 {
+		// すべて信頼するプロキシなら最も外側のアドレス
+		return hops[0]
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+		return ip
+	}
+	return peer
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trust := NewProxyTrust(true, []string{"172.30.0.10", "10.0.0.0/8"})
+
+	tests := []struct {
+		name   string
+		trust  *ProxyTrust
+		remote string
+		xff    string
+		realIP string
+		want   string
+	}{
+		{name: "ヘッダーを信頼しない", trust: nil, remote: "172.30.0.10:5000", xff: "1.2.3.4", want: "172.30.0.10"},
+		{name: "信頼しない接続元のヘッダーは無視する", trust: trust, remote: "203.0.113.9:5000", xff: "1.2.3.4", realIP: "1.2.3.4", want: "203.0.113.9"},
+		{name: "右端の信頼しないアドレス", trust: trust, remote: "172.30.0.10:5000", xff: "6.6.6.6, 198.51.100.7", want: "198.51.100.7"},
+		{name: "信頼するプロキシを飛ばす", trust: trust, remote: "172.30.0.10:5000", xff: "198.51.100.7, 10.1.2.3", want: "198.51.100.7"},
+		{name: "読めないアドレスより左は使わない", trust: trust, remote: "172.30.0.10:5000", xff: "6.6.6.6, garbage", want: "172.30.0.10"},
+		{name: "X-Real-IP", trust: trust, remote: "172.30.0.10:5000", realIP: "198.51.100.7", want: "198.51.100.7"},
+		{name: "CIDR が無ければ信頼しない", trust: NewProxyTrust(true, nil), remote: "172.30.0.10:5000", xff: "1.2.3.4", want: "172.30.0.10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/products", nil)
+			r.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := ClientIP(r, tt.trust); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rs/cors"
+)
+
+// CORSConfig はクロスオリジンリクエストの許可設定
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	MaxAge           time.Duration
+	AllowCredentials bool
+}
+
+// CORS は設定に従って CORS ヘッダーを付与し、プリフライトリクエストには
+// ルーティングに渡さずに 204 で応答するミドルウェアを返す
+func CORS(cfg CORSConfig) func(http.Handler) http.Handler {
+	allowCredentials := cfg.AllowCredentials
+	for _, origin := range cfg.AllowedOrigins {
+		// ワイルドカードのまま資格情報を許可すると任意のオリジンを反射してしまう
+		if origin == "*" && allowCredentials {
+			log.Println("[CORS] AllowCredentials is ignored because AllowedOrigins contains \"*\"")
+			allowCredentials = false
+		}
+	}
+
+	c := cors.New(cors.Options{
+		AllowedOrigins:       cfg.AllowedOrigins,
+		AllowedMethods:       cfg.AllowedMethods,
+		AllowedHeaders:       cfg.AllowedHeaders,
+		ExposedHeaders:       cfg.ExposedHeaders,
+		MaxAge:               int(cfg.MaxAge / time.Second),
+		AllowCredentials:     allowCredentials,
+		OptionsSuccessStatus: http.StatusNoContent,
+	})
+
+	log.Printf("[CORS] Origins: %v, methods: %v, headers: %v, max-age: %v, credentials: %t",
+		cfg.AllowedOrigins, cfg.AllowedMethods, cfg.AllowedHeaders, cfg.MaxAge, allowCredentials)
+	return c.Handler
+}
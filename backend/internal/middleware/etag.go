@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"bytes"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Conditional は GET のレスポンスに本文から求めた弱い ETag を付け、If-None-Match が一致すれば
+// 本文を送らずに 304 Not Modified を返す。本文にはタイムセールや店舗の在庫など製品の行以外の情報も含まれるので、
+// 行の更新日時ではなく組み立てたレスポンスそのものから求める (DB への問い合わせは減らないが、転送量は減る)。
+// cacheControl が空でなければ成功したレスポンスの Cache-Control をその値にする
+func Conditional(cacheControl string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buf := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(buf, r)
+			if buf.status != http.StatusOK {
+				// エラーなどはそのまま返す (キャッシュさせない)
+				w.WriteHeader(buf.status)
+				w.Write(buf.body.Bytes())
+				return
+			}
+
+			h := fnv.New64a()
+			h.Write(buf.body.Bytes())
+			etag := `W/"` + strconv.FormatUint(h.Sum64(), 36) + `"`
+			w.Header().Set("ETag", etag)
+			if cacheControl != "" {
+				w.Header().Set("Cache-Control", cacheControl)
+			}
+			if etagMatches(r.Header.Get("If-None-Match"), etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(buf.body.Bytes())
+		})
+	}
+}
+
+// etagMatches は If-None-Match のいずれかが etag と一致するかを返す。
+// If-None-Match は弱い比較なので W/ の有無は区別しない
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponse はハンドラーの出力を送らずに溜める。ヘッダーは元の ResponseWriter のものをそのまま使う
+// (Flusher などは辿らせない。途中で送ると ETag を付けられないため)
+type bufferedResponse struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.wroteHeader {
+		return
+	}
+	b.status = status
+	b.wroteHeader = true
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"sample-backend/internal/hooks"
+)
+
+// Hooks はリクエストの開始と応答を Registry に通知し、ハンドラーからエラーを通知できるよう
+// Registry をコンテキストに載せる。panic も 500 として通知されるよう Recover より外側に置く
+func Hooks(h *hooks.Registry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			r = r.WithContext(hooks.NewContext(r.Context(), h))
+			h.RequestStart(r)
+
+			rec := newStatusRecorder(w)
+			next.ServeHTTP(rec, r)
+
+			h.Response(r, hooks.Response{
+				Route:    RoutePattern(r.Context()),
+				Status:   rec.status,
+				Bytes:    rec.bytes,
+				Duration: time.Since(start),
+			})
+		})
+	}
+}
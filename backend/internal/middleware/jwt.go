@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"sample-backend/internal/auth"
+	"sample-backend/internal/reqlog"
+)
+
+// JWTAuth は Authorization: Bearer のトークンを検証し、トークンのユーザーをコンテキストに設定する。
+// ヘッダーが無いリクエストは匿名として通し (公開の読み取りはそのまま使える)、
+// 署名が正しくないか期限の切れたトークンは 401 を返す。役割による判定は各ハンドラーが行う
+func JWTAuth(keys *auth.KeySet) Middleware {
+	log.Println("[AUTH] JWT authentication enabled")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "Authorization must be a Bearer token", http.StatusUnauthorized)
+				return
+			}
+
+			u, err := keys.Verify(strings.TrimSpace(token))
+			if err != nil {
+				reqlog.From(r.Context()).Printf("[AUTH] Rejected bearer token: %v", err)
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+
+			if fields := reqlog.FieldsFrom(r.Context()); fields != nil {
+				fields.User = u.Username
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), u)))
+		})
+	}
+}
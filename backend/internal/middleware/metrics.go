@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"sample-backend/internal/metrics"
+)
+
+// unmatchedRoute はどのルートにもマッチしなかったリクエストのラベル (パスをそのまま使うと系列が増え続ける)
+const unmatchedRoute = "unmatched"
+
+var (
+	httpRequests = metrics.NewCounterVec("http_requests_total", "HTTP requests by route and status code.", "route", "code")
+	httpDuration = metrics.NewHistogramVec("http_request_duration_seconds", "HTTP request latency by route.", metrics.DefBuckets, "route")
+	httpInFlight = metrics.NewGaugeVec("http_requests_in_flight", "HTTP requests currently being served by route.", "route")
+)
+
+// Metrics はルートごとのリクエスト数・レイテンシを記録する。ルートは Route が決めるので TrackRoute の内側に置く。
+// 処理中のリクエスト数はルートが決まってから数える必要があるため Route で記録する
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := newStatusRecorder(w)
+
+		next.ServeHTTP(rec, r)
+
+		route := RoutePattern(r.Context())
+		if route == "" {
+			route = unmatchedRoute
+		}
+		httpRequests.With(route, strconv.Itoa(rec.status)).Inc()
+		httpDuration.With(route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// trackInFlight は pattern の処理中のリクエスト数を 1 増やし、終わったときに呼ぶ関数を返す
+func trackInFlight(pattern string) func() {
+	g := httpInFlight.With(pattern)
+	g.Inc()
+	return g.Dec
+}
@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sample-backend/internal/auth"
+	"sample-backend/internal/clock"
+	"sample-backend/internal/metrics"
+	"sample-backend/internal/reqlog"
+)
+
+var (
+	rateLimited      = metrics.NewCounterVec("rate_limited_requests_total", "Requests rejected by the rate limiter by route.", "route")
+	rateLimitAllowed = metrics.NewCounterVec("rate_limit_allowed_requests_total", "Requests that passed the rate limiter by route.", "route")
+)
+
+const (
+	// rateLimitSweepInterval ごとに、満タンに戻ったバケットを捨てる (捨てても挙動は変わらない)
+	rateLimitSweepInterval = time.Minute
+	// defaultPolicyName はルートごとの設定が無いルートが共有するバケットの名前
+	defaultPolicyName = "default"
+)
+
+// RatePolicy はトークンバケットの設定。1 秒あたり Rate 個補充し、最大 Burst 個まで貯める。
+// Rate が 0 のポリシーは制限しない
+type RatePolicy struct {
+	Rate  float64
+	Burst int
+}
+
+func (p RatePolicy) String() string {
+	if p.Rate <= 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%g/s,%d", p.Rate, p.Burst)
+}
+
+// ParseRatePolicy は "10/s" や "600/m,50" (回数/単位,バースト) の形式を読む。単位は s / m / h。
+// バーストを省略すると単位あたりの回数と同じにする。"off" と "0" は制限しない
+func ParseRatePolicy(s string) (RatePolicy, error) {
+	s = strings.TrimSpace(s)
+	if s == "off" || s == "0" {
+		return RatePolicy{}, nil
+	}
+	spec, burstText, hasBurst := strings.Cut(s, ",")
+	countText, unit, ok := strings.Cut(strings.TrimSpace(spec), "/")
+	if !ok {
+		return RatePolicy{}, fmt.Errorf("rate limit %q must look like 10/s or 600/m,50", s)
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(countText))
+	if err != nil || count <= 0 {
+		return RatePolicy{}, fmt.Errorf("rate limit %q must have a positive count", s)
+	}
+	var per time.Duration
+	switch strings.TrimSpace(unit) {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return RatePolicy{}, fmt.Errorf("rate limit %q must use s, m or h", s)
+	}
+	burst := count
+	if hasBurst {
+		if burst, err = strconv.Atoi(strings.TrimSpace(burstText)); err != nil || burst <= 0 {
+			return RatePolicy{}, fmt.Errorf("rate limit %q must have a positive burst", s)
+		}
+	}
+	return RatePolicy{Rate: float64(count) / per.Seconds(), Burst: burst}, nil
+}
+
+// RouteMatcher はリクエストがマッチするルートのパターンを返す (*http.ServeMux)
+type RouteMatcher interface {
+	Handler(r *http.Request) (http.Handler, string)
+}
+
+// RateLimitConfig はレート制限の設定
+type RateLimitConfig struct {
+	// ルートごとの設定が無いルートに使う (Rate が 0 なら制限しない)。これらのルートは 1 つのバケットを共有する
+	Default RatePolicy
+	// ルートのパターン ("GET /api/products/search" など) ごとの設定。ルートごとに別のバケットを使う
+	Routes map[string]RatePolicy
+	// ルートのパターンを求める ServeMux
+	Mux        RouteMatcher
+	TrustProxy *ProxyTrust
+	// 補充の計算に使う Clock (nil ならシステム時刻)
+	Clock clock.Clock
+}
+
+type bucketKey struct {
+	policy, client string
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter はクライアント (API キーで認証したクライアント名、なければ IP アドレス) ごとのトークンバケットで
+// リクエストを制限し、使い切ったら 429 と Retry-After を返す。バケットはプロセスごとに持つので、
+// 複数台で動かす場合の上限は台数倍になる
+type RateLimiter struct {
+	cfg RateLimitConfig
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*tokenBucket
+	lastSweep time.Time
+}
+
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	cfg.Clock = clock.OrReal(cfg.Clock)
+	log.Printf("[RATELIMIT] Rate limiting enabled - default: %v, %d route policies", cfg.Default, len(cfg.Routes))
+	return &RateLimiter{cfg: cfg, buckets: make(map[bucketKey]*tokenBucket), lastSweep: cfg.Clock.Now()}
+}
+
+// Middleware はクライアントを識別できるよう認証のミドルウェアの内側に置く
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := l.cfg.Mux.Handler(r)
+		name, policy := defaultPolicyName, l.cfg.Default
+		if p, ok := l.cfg.Routes[route]; ok {
+			name, policy = route, p
+		}
+		if policy.Rate <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		client := "ip:" + ClientIP(r, l.cfg.TrustProxy)
+		if c, ok := auth.ClientFrom(r.Context()); ok {
+			client = "client:" + c
+		}
+		wait, ok := l.take(bucketKey{policy: name, client: client}, policy)
+		if !ok {
+			rateLimited.With(route).Inc()
+			reqlog.From(r.Context()).Printf("[RATELIMIT] Rejected %s %s from %s (policy %s: %v)", r.Method, r.URL.Path, client, name, policy)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		rateLimitAllowed.With(route).Inc()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take はバケットからトークンを 1 つ取る。足りなければ次のトークンが貯まるまでの時間と false を返す
+func (l *RateLimiter) take(key bucketKey, policy RatePolicy) (time.Duration, bool) {
+	now := l.cfg.Clock.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimitSweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(policy.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(policy.Burst), b.tokens+now.Sub(b.last).Seconds()*policy.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / policy.Rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// sweep は満タンに戻ったはずのバケットを捨てる。l.mu を持って呼ぶこと
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		policy := l.cfg.Default
+		if p, ok := l.cfg.Routes[key.policy]; ok {
+			policy = p
+		}
+		if policy.Rate <= 0 || b.tokens+now.Sub(b.last).Seconds()*policy.Rate >= float64(policy.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"sample-backend/internal/hooks"
+	"sample-backend/internal/reqlog"
+)
+
+// Recover はハンドラーの panic を 500 に変換し、スタックトレースをログに残す。
+// http.ErrAbortHandler (意図的な切断) はそのまま net/http に渡す
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := newStatusRecorder(w)
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			reqlog.From(r.Context()).Printf("[PANIC] %s %s: %v", r.Method, r.URL.Path, v)
+			log.Printf("[PANIC] Stack trace:\n%s", debug.Stack())
+			hooks.ReportError(r, http.StatusInternalServerError, fmt.Errorf("panic: %v", v))
+			if !rec.wroteHeader {
+				http.Error(rec, "Internal server error", http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
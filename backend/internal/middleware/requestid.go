@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"sample-backend/internal/reqlog"
+)
+
+// RequestIDHeader はリクエスト ID を受け渡すヘッダー
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength は受け付けるリクエスト ID の最大長。これを超える値や使えない文字を含む値は採番し直す
+const maxRequestIDLength = 64
+
+// RequestID はリクエスト ID を決めてレスポンスヘッダーに返し、reqlog の識別情報としてコンテキストに載せる。
+// 上流 (ロードバランサーやフロントエンド) から X-Request-ID が届けばそれを引き継ぐ。
+// アクセスログにも ID を付けるため AccessLogger より外側に置く
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		fields := &reqlog.Fields{RequestID: id}
+		next.ServeHTTP(w, r.WithContext(reqlog.NewContext(r.Context(), fields)))
+	})
+}
+
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b[:])
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
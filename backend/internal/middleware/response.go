@@ -0,0 +1,34 @@
+package middleware
+
+import "net/http"
+
+// statusRecorder はハンドラーが書き込んだステータスコードとバイト数を記録する
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+	// ステータスを書き込み済みか (以降はヘッダーを変えられない)
+	wroteHeader bool
+}
+
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.wroteHeader = true
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap は http.ResponseController から元の ResponseWriter (Flusher など) を辿れるようにする
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"sample-backend/internal/reqlog"
+)
+
+type routeKey struct{}
+
+// TrackRoute はマッチしたルートのパターンを書き込む場所をコンテキストに用意する。
+// 外側のミドルウェア (アクセスログ・メトリクス) が RoutePattern を参照できるよう最も外側に置く
+func TrackRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		holder := new(string)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, holder)))
+	})
+}
+
+// RoutePattern はリクエストがマッチしたルートのパターン ("GET /api/products/{id}" など) を返す。
+// ルートが決まる前やどのルートにもマッチしなかった場合は空文字列
+func RoutePattern(ctx context.Context) string {
+	if holder, ok := ctx.Value(routeKey{}).(*string); ok {
+		return *holder
+	}
+	return ""
+}
+
+// Route はハンドラーにルートのパターンを結び付ける。パスの値ではなくパターンを
+// スパン名やメトリクスのラベルに使うことで、ID ごとに系列が増えないようにする
+func Route(pattern string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if holder, ok := r.Context().Value(routeKey{}).(*string); ok {
+			*holder = pattern
+		}
+		if fields := reqlog.FieldsFrom(r.Context()); fields != nil {
+			fields.Route = pattern
+		}
+		span := trace.SpanFromContext(r.Context())
+		if span.IsRecording() {
+			span.SetName(pattern)
+			span.SetAttributes(attribute.String("http.route", pattern))
+		}
+		defer trackInFlight(pattern)()
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// SecurityHeadersConfig は全レスポンスに付与するセキュリティ関連ヘッダーの設定
+type SecurityHeadersConfig struct {
+	ContentSecurityPolicy string
+	FrameOptions          string
+	ReferrerPolicy        string
+	// パスのプレフィックスごとの CSP。Swagger UI や管理画面のように HTML を返すルート向け
+	CSPOverrides map[string]string
+}
+
+// SecurityHeaders は X-Content-Type-Options などのヘッダーを付与するミドルウェアを返す。
+// CSP はリクエストパスに最も長く一致するプレフィックスの設定を優先する
+func SecurityHeaders(cfg SecurityHeadersConfig) func(http.Handler) http.Handler {
+	prefixes := make([]string, 0, len(cfg.CSPOverrides))
+	for prefix := range cfg.CSPOverrides {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	cspFor := func(path string) string {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(path, prefix) {
+				return cfg.CSPOverrides[prefix]
+			}
+		}
+		return cfg.ContentSecurityPolicy
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			if cfg.FrameOptions != "" {
+				h.Set("X-Frame-Options", cfg.FrameOptions)
+			}
+			if cfg.ReferrerPolicy != "" {
+				h.Set("Referrer-Policy", cfg.ReferrerPolicy)
+			}
+			if csp := cspFor(r.URL.Path); csp != "" {
+				h.Set("Content-Security-Policy", csp)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
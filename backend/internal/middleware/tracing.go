@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("product-search-backend")
+
+// Tracing はリクエスト全体をサーバースパンで包む。ハンドラーのスパンはこの子になり、
+// 認証やレート制限で弾かれたリクエストもトレースに残る。スパン名は Route でルートのパターンに置き換わる
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracer.Start(r.Context(), "http_request", trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		rec := newStatusRecorder(w)
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		if span.IsRecording() {
+			span.SetAttributes(
+				attribute.String("http.method", r.Method),
+				attribute.String("http.target", r.URL.RequestURI()),
+				attribute.Int("http.status_code", rec.status),
+			)
+		}
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
@@ -1,6 +1,13 @@
 package models
 
-import "time"
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"sample-backend/internal/fieldcrypt"
+)
 
 type Product struct {
 	ID          int       `json:"id" db:"id"`
@@ -10,7 +17,52 @@ type Product struct {
 	Model       string    `json:"model" db:"model"`
 	Description string    `json:"description" db:"description"`
 	Price       float64   `json:"price" db:"price"`
+	SKU         string    `json:"sku,omitempty" db:"sku"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	// レビューの件数と評価の平均 (レビューの投稿時に products に集計する。レビューが無ければ 0)
+	ReviewCount   int     `json:"review_count" db:"review_count"`
+	RatingAverage float64 `json:"rating_average" db:"rating_average"`
+	// オンライン販売の在庫数 (店舗ごとの在庫は store_stock で別に持つ)
+	Stock int `json:"stock" db:"stock"`
+	// 開催中のタイムセール (DB の列ではなく、レスポンスを返す直前にサービスが付ける)
+	Sale *ProductSale `json:"sale,omitempty" db:"-"`
+	// 店舗での受け取りの可否 (一覧を店舗・位置で絞り込んだときだけ付ける)
+	Pickup []PickupAvailability `json:"pickup,omitempty" db:"-"`
+}
+
+// ProductSale は製品に適用中のタイムセール。Price は元の価格のまま返す
+type ProductSale struct {
+	SaleID    int       `json:"sale_id"`
+	SalePrice float64   `json:"sale_price"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+}
+
+// ProductRequest は製品の登録・更新の内容。更新ではすべての項目を置き換える
+type ProductRequest struct {
+	Name        string   `json:"name"`
+	Category    string   `json:"category"`
+	Brand       string   `json:"brand"`
+	Model       string   `json:"model"`
+	Description string   `json:"description"`
+	Price       *float64 `json:"price"`
+	SKU         string   `json:"sku"`
+}
+
+// ImportSummary は製品の一括登録の結果
+type ImportSummary struct {
+	Inserted int `json:"inserted"`
+	// 検証や SKU の重複で登録しなかった行数
+	Skipped int              `json:"skipped"`
+	Errors  []ImportRowError `json:"errors"`
+	// Errors は先頭から一定数までしか返さない。省略した行があれば true
+	ErrorsTruncated bool `json:"errors_truncated,omitempty"`
+}
+
+// ImportRowError は一括登録で登録しなかった行とその理由。Line はファイルの行番号 (1 始まり)
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
 }
 
 type SearchRequest struct {
@@ -20,10 +72,362 @@ type SearchRequest struct {
 	Limit   int    `json:"limit"`
 }
 
+// PaginatedResponse は製品一覧のレスポンス。カーソル方式 (?cursor=) では page は 0 で、
+// include_total=true を指定しなかった場合は totalPages と count も 0 になる
 type PaginatedResponse struct {
 	Products   []Product `json:"products"`
 	Page       int       `json:"page"`
 	Limit      int       `json:"limit"`
 	TotalPages int       `json:"totalPages"`
 	Count      int       `json:"count"`
+	// 一覧に適用した絞り込みと並び順 (既定の一覧では省略)
+	Filters *AppliedFilters `json:"filters,omitempty"`
+	// 次のページを読むカーソル (?cursor=)。ID 順の一覧で次のページがある場合だけ返す
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// BatchProductsResponse は ID を指定してまとめて取得した製品。
+// products は ID をキーにしたオブジェクトで、キーはリクエストで指定した順に並ぶ
+type BatchProductsResponse struct {
+	Products ProductsByID `json:"products"`
+	// 存在しなかった ID (リクエストの順)
+	NotFound []int `json:"not_found"`
+}
+
+// ProductsByID は JSON では ID をキーにしたオブジェクトとして、スライスの順に書き出す
+// (map だとキーが並べ替えられてしまうため)
+type ProductsByID []Product
+
+func (ps ProductsByID) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i := range ps {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(`"` + strconv.Itoa(ps[i].ID) + `":`)
+		v, err := json.Marshal(&ps[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// AppliedFilters は一覧に適用した絞り込みと並び順。フロントエンドが絞り込み条件の表示に使う
+type AppliedFilters struct {
+	Category    string     `json:"category,omitempty"`
+	Brand       string     `json:"brand,omitempty"`
+	MinPrice    *float64   `json:"min_price,omitempty"`
+	MaxPrice    *float64   `json:"max_price,omitempty"`
+	CreatedFrom *time.Time `json:"created_from,omitempty"`
+	CreatedTo   *time.Time `json:"created_to,omitempty"`
+	StoreIDs    []int      `json:"store_ids,omitempty"`
+	Sort        string     `json:"sort"`
+}
+
+// FacetCount は絞り込みの値 (カテゴリ・ブランド) ごとの製品数
+type FacetCount struct {
+	Value string `json:"value" db:"value"`
+	Count int    `json:"count" db:"count"`
+}
+
+// PriceBucket は価格帯ごとの製品数。Min 以上 Max 未満で、最も高い価格帯は Max を省略する
+type PriceBucket struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+// FacetsResponse は一覧の絞り込みの候補と件数。各項目は自身の絞り込みを外して数える
+// (category を指定しても、ほかのカテゴリに切り替えた場合の件数を返す)
+type FacetsResponse struct {
+	Categories  []FacetCount  `json:"categories"`
+	Brands      []FacetCount  `json:"brands"`
+	PriceRanges []PriceBucket `json:"price_ranges"`
+	// 一覧に適用した絞り込み (絞り込みが無ければ省略)
+	Filters *AppliedFilters `json:"filters,omitempty"`
+}
+
+// SupplierInfo は製品の仕入れ情報。原価と連絡先は暗号化して保存される
+type SupplierInfo struct {
+	ProductID      int                `json:"product_id" db:"product_id"`
+	SupplierCost   fieldcrypt.Float64 `json:"supplier_cost" db:"supplier_cost"`
+	PartnerContact fieldcrypt.String  `json:"partner_contact" db:"partner_contact"`
+	UpdatedAt      time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// Recommendation は一緒に閲覧されることの多い製品と、その関連の強さ (0〜1)
+type Recommendation struct {
+	Product
+	Score float64 `json:"score"`
+}
+
+type RecommendationsResponse struct {
+	ProductID       int              `json:"product_id"`
+	Recommendations []Recommendation `json:"recommendations"`
+}
+
+// ProductDetail は製品詳細のレスポンス。公開中の質問の件数を添える。
+// as_of を指定した場合は、その時点の内容と、その内容になった日時 (EffectiveFrom) を返す
+type ProductDetail struct {
+	Product
+	QuestionCount int        `json:"question_count"`
+	AsOf          *time.Time `json:"as_of,omitempty"`
+	EffectiveFrom *time.Time `json:"effective_from,omitempty"`
+}
+
+// ProductRevision は製品のある時点の内容
+type ProductRevision struct {
+	Product
+	Deleted   bool      `db:"deleted"`
+	ValidFrom time.Time `db:"valid_from"`
+}
+
+// 質問の公開状態
+const (
+	QuestionPending  = "pending"
+	QuestionApproved = "approved"
+	QuestionRejected = "rejected"
+)
+
+// 回答者の種類
+const (
+	AnswerBySeller = "seller"
+	AnswerByAdmin  = "admin"
+)
+
+// Question は製品への質問。Answers は公開中の質問にだけ付ける
+type Question struct {
+	ID           int       `json:"id" db:"id"`
+	ProductID    int       `json:"product_id" db:"product_id"`
+	Author       string    `json:"author" db:"author"`
+	Body         string    `json:"body" db:"body"`
+	Status       string    `json:"status" db:"status"`
+	HelpfulCount int       `json:"helpful_count" db:"helpful_count"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	Answers      []Answer  `json:"answers" db:"-"`
+}
+
+// Answer は質問への出品者・管理者の回答
+type Answer struct {
+	ID           int       `json:"id" db:"id"`
+	QuestionID   int       `json:"question_id" db:"question_id"`
+	Author       string    `json:"author" db:"author"`
+	Role         string    `json:"role" db:"role"`
+	Body         string    `json:"body" db:"body"`
+	HelpfulCount int       `json:"helpful_count" db:"helpful_count"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// PostRequest は質問・回答の投稿内容
+type PostRequest struct {
+	Author string `json:"author"`
+	Body   string `json:"body"`
+}
+
+type QuestionsResponse struct {
+	Questions  []Question `json:"questions"`
+	Page       int        `json:"page"`
+	Limit      int        `json:"limit"`
+	TotalPages int        `json:"totalPages"`
+	Count      int        `json:"count"`
+}
+
+// Review は製品のレビュー。Rating は 1〜5
+type Review struct {
+	ID        int       `json:"id" db:"id"`
+	ProductID int       `json:"product_id" db:"product_id"`
+	Author    string    `json:"author" db:"author"`
+	Rating    int       `json:"rating" db:"rating"`
+	Title     string    `json:"title" db:"title"`
+	Body      string    `json:"body" db:"body"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ReviewRequest はレビューの投稿内容。Title は省略できる
+type ReviewRequest struct {
+	Author string `json:"author"`
+	Rating int    `json:"rating"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+}
+
+// ReviewsResponse は製品のレビューの一覧。件数と平均は製品に集計した値
+type ReviewsResponse struct {
+	ProductID     int      `json:"product_id"`
+	ReviewCount   int      `json:"review_count"`
+	RatingAverage float64  `json:"rating_average"`
+	Reviews       []Review `json:"reviews"`
+	Page          int      `json:"page"`
+	Limit         int      `json:"limit"`
+	TotalPages    int      `json:"totalPages"`
+	Count         int      `json:"count"`
+}
+
+// StockAdjustRequest は在庫の増減。入荷なら正、棚卸しでの減少なら負の数を指定する
+type StockAdjustRequest struct {
+	Delta int `json:"delta"`
+}
+
+// ReserveRequest は在庫の引き当て (注文の確定時に在庫から引く数)
+type ReserveRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+// StockResponse は在庫を変えた結果
+type StockResponse struct {
+	ProductID int `json:"product_id"`
+	// 変更前と変更後の在庫数
+	Previous int `json:"previous"`
+	Stock    int `json:"stock"`
+	// 引き当てでセールの在庫も確保した場合のセール (セール価格で販売する)
+	Sale *ProductSale `json:"sale,omitempty"`
+}
+
+// 通知の種類と配信方法
+const (
+	AlertRestock   = "restock"
+	AlertPriceDrop = "price_drop"
+
+	AlertEmail   = "email"
+	AlertWebhook = "webhook"
+)
+
+// AlertSubscription は製品の再入荷・値下がりの通知の購読
+type AlertSubscription struct {
+	ID            int             `json:"id" db:"id"`
+	ProductID     int             `json:"product_id" db:"product_id"`
+	Kind          string          `json:"kind" db:"kind"`
+	Channel       string          `json:"channel" db:"channel"`
+	Target        string          `json:"target" db:"target"`
+	BaselinePrice float64         `json:"baseline_price" db:"baseline_price"`
+	TargetPrice   *float64        `json:"target_price,omitempty" db:"target_price"`
+	Status        string          `json:"status" db:"status"`
+	Token         string          `json:"unsubscribe_token" db:"unsubscribe_token"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	FiredAt       *time.Time      `json:"fired_at,omitempty" db:"fired_at"`
+	Deliveries    []AlertDelivery `json:"deliveries" db:"-"`
+}
+
+// AlertDelivery は通知の配信記録
+type AlertDelivery struct {
+	ID        int        `json:"id" db:"id"`
+	Event     string     `json:"event" db:"event"`
+	Price     float64    `json:"price" db:"price"`
+	Status    string     `json:"status" db:"status"`
+	Attempts  int        `json:"attempts" db:"attempts"`
+	LastError string     `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	SentAt    *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+}
+
+// AlertRequest は通知の購読の申し込み。Target はメールアドレスか Webhook の URL
+type AlertRequest struct {
+	Kind        string   `json:"kind"`
+	Channel     string   `json:"channel"`
+	Target      string   `json:"target"`
+	TargetPrice *float64 `json:"target_price"`
+}
+
+// Sale はタイムセール。期間中は Items の製品を SalePrice で販売し、製品ごとに StockCap 個までとする
+type Sale struct {
+	ID        int        `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	StartsAt  time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt    time.Time  `json:"ends_at" db:"ends_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	Items     []SaleItem `json:"items" db:"-"`
+}
+
+// SaleItem はタイムセールの対象製品
+type SaleItem struct {
+	ProductID   int     `json:"product_id" db:"product_id"`
+	ProductName string  `json:"product_name" db:"product_name"`
+	Price       float64 `json:"price" db:"price"`
+	SalePrice   float64 `json:"sale_price" db:"sale_price"`
+	StockCap    int     `json:"stock_cap" db:"stock_cap"`
+	Sold        int     `json:"sold" db:"sold"`
+}
+
+// Remaining はセールの残り在庫数
+func (i SaleItem) Remaining() int {
+	return max(0, i.StockCap-i.Sold)
+}
+
+// SaleRequest はタイムセールの登録内容
+type SaleRequest struct {
+	Name     string    `json:"name"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Items    []struct {
+		ProductID int     `json:"product_id"`
+		SalePrice float64 `json:"sale_price"`
+		StockCap  int     `json:"stock_cap"`
+	} `json:"items"`
+}
+
+// ActiveSalesResponse は開催中と開始間近のタイムセール。
+// 時刻の基準はレスポンスの Date ヘッダーとし、カウントダウンはクライアントが計算する
+type ActiveSalesResponse struct {
+	Active   []Sale `json:"active"`
+	Upcoming []Sale `json:"upcoming"`
+}
+
+// Store は受け取りに対応した店舗。DistanceKM は位置を指定して探したときだけ入る
+type Store struct {
+	ID         int      `json:"id" db:"id"`
+	Name       string   `json:"name" db:"name"`
+	Address    string   `json:"address" db:"address"`
+	Latitude   float64  `json:"latitude" db:"latitude"`
+	Longitude  float64  `json:"longitude" db:"longitude"`
+	DistanceKM *float64 `json:"distance_km,omitempty" db:"distance_km"`
+}
+
+// StoreStock は店舗ごとの在庫数
+type StoreStock struct {
+	StoreID   int `json:"store_id" db:"store_id"`
+	ProductID int `json:"product_id" db:"product_id"`
+	Quantity  int `json:"quantity" db:"quantity"`
+}
+
+// PickupAvailability は製品をその店舗で受け取れるか。近い店舗から順に並べる
+type PickupAvailability struct {
+	StoreID    int      `json:"store_id"`
+	StoreName  string   `json:"store_name"`
+	DistanceKM *float64 `json:"distance_km,omitempty"`
+	Quantity   int      `json:"quantity"`
+}
+
+// User は JWT でログインするユーザー。Role は auth.RoleAdmin か auth.RoleUser
+type User struct {
+	ID           int       `json:"id" db:"id"`
+	Username     string    `json:"username" db:"username"`
+	PasswordHash string    `json:"-" db:"password_hash"`
+	Role         string    `json:"role" db:"role"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// UserRequest はユーザーの登録内容 (管理用)。Role を省略すると一般ユーザーになる
+type UserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+// LoginRequest はログインの内容
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse は発行したトークン。Authorization: Bearer <token> として送る
+type LoginResponse struct {
+	Token     string `json:"token"`
+	TokenType string `json:"token_type"`
+	// トークンの有効期間 (秒)
+	ExpiresIn int  `json:"expires_in"`
+	User      User `json:"user"`
 }
@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SignatureHeader は Webhook の本文の HMAC-SHA256 ("sha256=" + 16 進) を送るヘッダー
+const SignatureHeader = "X-Signature"
+
+// Message は 1 件の通知の内容
+type Message struct {
+	Event          string  `json:"event"`
+	SubscriptionID int     `json:"subscription_id"`
+	ProductID      int     `json:"product_id"`
+	ProductName    string  `json:"product_name"`
+	Price          float64 `json:"price"`
+	UnsubscribeURL string  `json:"unsubscribe_url"`
+}
+
+// Sender は通知を 1 件届ける。target は購読のメールアドレスか Webhook の URL
+type Sender interface {
+	Send(ctx context.Context, target string, m Message) error
+}
+
+// subject はメールの件名
+func (m Message) subject() string {
+	if m.Event == EventRestocked {
+		return "【再入荷】" + m.ProductName
+	}
+	return "【値下がり】" + m.ProductName
+}
+
+func (m Message) text() string {
+	var b strings.Builder
+	if m.Event == EventRestocked {
+		fmt.Fprintf(&b, "お知らせを登録していた「%s」が再入荷しました。\r\n", m.ProductName)
+	} else {
+		fmt.Fprintf(&b, "お知らせを登録していた「%s」が値下がりしました。\r\n", m.ProductName)
+	}
+	fmt.Fprintf(&b, "現在の価格: %.0f円\r\n\r\n", m.Price)
+	fmt.Fprintf(&b, "このお知らせの配信を停止する: %s\r\n", m.UnsubscribeURL)
+	return b.String()
+}
+
+// SMTPSender はメールで通知する
+type SMTPSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPSender は addr (host:port) の SMTP サーバーから送る Sender を返す。username が空なら認証しない
+func NewSMTPSender(addr, from, username, password string) *SMTPSender {
+	s := &SMTPSender{addr: addr, from: from}
+	if username != "" {
+		host, _, _ := strings.Cut(addr, ":")
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+// Send はメールを送る (net/smtp はコンテキストに対応していないため、打ち切りは SMTP サーバーのタイムアウトに任せる)
+func (s *SMTPSender) Send(_ context.Context, target string, m Message) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", target)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", m.subject()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "List-Unsubscribe: <%s>\r\n", m.UnsubscribeURL)
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(m.text())
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{target}, msg.Bytes())
+}
+
+// logSender は SMTP サーバーが設定されていない環境で、送る代わりにログに出す
+type logSender struct{}
+
+func (logSender) Send(_ context.Context, target string, m Message) error {
+	log.Printf("[NOTIFY] SMTP not configured, email to %s: %s", target, m.subject())
+	return nil
+}
+
+// WebhookSender は JSON で POST して通知する。secret があれば本文の署名を付ける
+type WebhookSender struct {
+	client *http.Client
+	secret []byte
+}
+
+func NewWebhookSender(secret string, timeout time.Duration) *WebhookSender {
+	w := &WebhookSender{client: &http.Client{
+		Timeout: timeout,
+		// 購読の URL からの転送先は検証していないため追わない
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}}
+	if secret != "" {
+		w.secret = []byte(secret)
+	}
+	return w
+}
+
+func (w *WebhookSender) Send(ctx context.Context, target string, m Message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.secret != nil {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
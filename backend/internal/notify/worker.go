@@ -0,0 +1,333 @@
+// Package notify は製品の再入荷・値下がりの通知の評価と配信を行う。
+// 購読の登録と解除は repository.AlertRepository が扱い、ここでは条件を満たした購読の発火
+// (fired にして alert_deliveries に配信待ちを積む) と、配信待ちの送信・再送を行う
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// 製品の変化のイベント
+const (
+	EventPriceChanged = "price_changed"
+	EventRestocked    = "restocked"
+)
+
+const (
+	// 配信に失敗したら 1 分、2 分、4 分…と間隔を空けて再送し、この回数で諦める
+	maxAttempts  = 5
+	retryBackoff = time.Minute
+	deliverBatch = 100
+	sweepBatch   = 1000
+	// 複数のインスタンスが同じ通知を送らないための MySQL のロック名
+	workerLockName = "alert_delivery_worker"
+	maxErrorLength = 1000
+)
+
+// Event は製品の価格・在庫の変化。製品を書き換える処理が Worker.Publish で通知する
+type Event struct {
+	Kind      string
+	ProductID int
+	// 変化した後の価格 (再入荷でも通知に載せる)
+	Price float64
+}
+
+// Config は通知の配信方針
+type Config struct {
+	// 値下がりの見回りと配信待ちの送信の間隔
+	Interval time.Duration
+	// 解除用 URL のひな形。{token} を購読のトークンに置き換える
+	UnsubscribeURL string
+	// 配信方法 (models.AlertEmail / models.AlertWebhook) ごとの送信先
+	Senders map[string]Sender
+}
+
+// Worker は購読の条件を評価して通知を配信する
+type Worker struct {
+	db     *sqlx.DB
+	cfg    Config
+	events chan Event
+	kick   chan struct{}
+}
+
+func NewWorker(db *sqlx.DB, cfg Config) *Worker {
+	if cfg.Senders == nil {
+		cfg.Senders = map[string]Sender{}
+	}
+	return &Worker{
+		db:     db,
+		cfg:    cfg,
+		events: make(chan Event, 1024),
+		kick:   make(chan struct{}, 1),
+	}
+}
+
+// EmailSender は SMTP の設定に応じたメールの Sender を返す。addr が空ならログに出すだけにする
+func EmailSender(addr, from, username, password string) Sender {
+	if addr == "" {
+		return logSender{}
+	}
+	return NewSMTPSender(addr, from, username, password)
+}
+
+// Publish は製品の変化を評価待ちに積む。リクエストを待たせないよう、溢れたら捨てる
+// (値下がりは定期的な見回りでも拾う)。nil の Worker では何もしない
+func (w *Worker) Publish(e Event) {
+	if w == nil {
+		return
+	}
+	select {
+	case w.events <- e:
+	default:
+		log.Printf("[NOTIFY] Event queue full, dropped %s for product %d", e.Kind, e.ProductID)
+	}
+}
+
+// Start は評価と配信のゴルーチンを起動する
+func (w *Worker) Start() {
+	if w.cfg.Interval <= 0 {
+		log.Println("[NOTIFY] Alert worker disabled")
+		return
+	}
+
+	go func() {
+		for e := range w.events {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := w.evaluate(ctx, e); err != nil {
+				log.Printf("[NOTIFY ERROR] Failed to evaluate %s for product %d: %v", e.Kind, e.ProductID, err)
+			}
+			cancel()
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(w.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), w.cfg.Interval+time.Minute)
+			if err := w.runExclusive(ctx); err != nil {
+				log.Printf("[NOTIFY ERROR] Alert worker failed: %v", err)
+			}
+			cancel()
+
+			select {
+			case <-ticker.C:
+			case <-w.kick:
+			}
+		}
+	}()
+	log.Printf("[NOTIFY] Alert worker started - interval: %v, channels: %d", w.cfg.Interval, len(w.cfg.Senders))
+}
+
+// evaluate はイベントで条件を満たした購読を発火する
+func (w *Worker) evaluate(ctx context.Context, e Event) error {
+	var query string
+	args := []interface{}{e.ProductID}
+	switch e.Kind {
+	case EventPriceChanged:
+		query = `SELECT id FROM alert_subscriptions
+			WHERE product_id = ? AND kind = 'price_drop' AND status = 'active'
+			AND ((target_price IS NULL AND ? < baseline_price) OR ? <= target_price)`
+		args = append(args, e.Price, e.Price)
+	case EventRestocked:
+		query = "SELECT id FROM alert_subscriptions WHERE product_id = ? AND kind = 'restock' AND status = 'active'"
+	default:
+		return fmt.Errorf("unknown event %q", e.Kind)
+	}
+
+	var ids []int
+	if err := w.db.SelectContext(ctx, &ids, query, args...); err != nil {
+		return err
+	}
+	fired := 0
+	for _, id := range ids {
+		ok, err := w.fire(ctx, id, e.Kind, e.Price)
+		if err != nil {
+			return err
+		}
+		if ok {
+			fired++
+		}
+	}
+	if fired > 0 {
+		log.Printf("[NOTIFY] %s for product %d fired %d alerts", e.Kind, e.ProductID, fired)
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// fire は購読を fired にして配信待ちを積む。他のインスタンスが先に発火していたら false
+func (w *Worker) fire(ctx context.Context, subscriptionID int, event string, price float64) (bool, error) {
+	tx, err := w.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"UPDATE alert_subscriptions SET status = 'fired', fired_at = CURRENT_TIMESTAMP WHERE id = ? AND status = 'active'", subscriptionID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO alert_deliveries (subscription_id, event, price) VALUES (?, ?, ?)", subscriptionID, event, price); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// runExclusive は MySQL の名前付きロックを取れたときだけ見回りと配信を行う
+func (w *Worker) runExclusive(ctx context.Context) error {
+	conn, err := w.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked int
+	if err := conn.GetContext(ctx, &locked, "SELECT GET_LOCK(?, 0)", workerLockName); err != nil {
+		return fmt.Errorf("failed to acquire worker lock: %w", err)
+	}
+	if locked != 1 {
+		return nil
+	}
+	defer conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", workerLockName)
+
+	if err := w.sweepPriceDrops(ctx); err != nil {
+		return fmt.Errorf("failed to sweep price drops: %w", err)
+	}
+	return w.deliver(ctx)
+}
+
+// sweepPriceDrops はイベントを経由しない価格の変更 (取りこぼしや DB の直接更新) も拾うため、
+// 現在の価格で値下がりの購読を評価し直す
+func (w *Worker) sweepPriceDrops(ctx context.Context) error {
+	var due []struct {
+		ID    int     `db:"id"`
+		Price float64 `db:"price"`
+	}
+	err := w.db.SelectContext(ctx, &due, `SELECT s.id, p.price
+		FROM alert_subscriptions s
+		JOIN products p ON p.id = s.product_id
+		WHERE s.kind = 'price_drop' AND s.status = 'active'
+		AND ((s.target_price IS NULL AND p.price < s.baseline_price) OR p.price <= s.target_price)
+		LIMIT ?`, sweepBatch)
+	if err != nil {
+		return err
+	}
+	for _, d := range due {
+		if _, err := w.fire(ctx, d.ID, EventPriceChanged, d.Price); err != nil {
+			return err
+		}
+	}
+	if len(due) > 0 {
+		log.Printf("[NOTIFY] Price sweep fired %d alerts", len(due))
+	}
+	return nil
+}
+
+type pendingDelivery struct {
+	ID          int     `db:"id"`
+	Event       string  `db:"event"`
+	Price       float64 `db:"price"`
+	Attempts    int     `db:"attempts"`
+	SubID       int     `db:"subscription_id"`
+	SubStatus   string  `db:"sub_status"`
+	Channel     string  `db:"channel"`
+	Target      string  `db:"target"`
+	Token       string  `db:"unsubscribe_token"`
+	ProductID   int     `db:"product_id"`
+	ProductName string  `db:"product_name"`
+}
+
+// deliver は送信時刻になった配信待ちを送る
+func (w *Worker) deliver(ctx context.Context) error {
+	var pending []pendingDelivery
+	err := w.db.SelectContext(ctx, &pending, `SELECT d.id, d.event, d.price, d.attempts, d.subscription_id,
+			s.status AS sub_status, s.channel, s.target, s.unsubscribe_token, s.product_id, p.name AS product_name
+		FROM alert_deliveries d
+		JOIN alert_subscriptions s ON s.id = d.subscription_id
+		JOIN products p ON p.id = s.product_id
+		WHERE d.status = 'pending' AND d.next_attempt_at <= CURRENT_TIMESTAMP
+		ORDER BY d.id
+		LIMIT ?`, deliverBatch)
+	if err != nil {
+		return err
+	}
+
+	sent, failed := 0, 0
+	for _, d := range pending {
+		err := w.send(ctx, d)
+		if err := w.record(ctx, d, err); err != nil {
+			return err
+		}
+		if err != nil {
+			failed++
+			log.Printf("[NOTIFY ERROR] Delivery %d (%s) attempt %d failed: %v", d.ID, d.Channel, d.Attempts+1, err)
+		} else {
+			sent++
+		}
+	}
+	if len(pending) > 0 {
+		log.Printf("[NOTIFY] Delivered %d alerts, %d failed", sent, failed)
+	}
+	return nil
+}
+
+func (w *Worker) send(ctx context.Context, d pendingDelivery) error {
+	if d.SubStatus == "unsubscribed" {
+		return errUnsubscribed
+	}
+	sender, ok := w.cfg.Senders[d.Channel]
+	if !ok {
+		return fmt.Errorf("no sender for channel %q", d.Channel)
+	}
+	return sender.Send(ctx, d.Target, Message{
+		Event:          d.Event,
+		SubscriptionID: d.SubID,
+		ProductID:      d.ProductID,
+		ProductName:    d.ProductName,
+		Price:          d.Price,
+		UnsubscribeURL: strings.ReplaceAll(w.cfg.UnsubscribeURL, "{token}", d.Token),
+	})
+}
+
+var errUnsubscribed = errors.New("subscription was cancelled before delivery")
+
+// record は送信の結果を配信記録に書き込む。失敗は上限まで間隔を空けて再送する
+func (w *Worker) record(ctx context.Context, d pendingDelivery, sendErr error) error {
+	attempts := d.Attempts + 1
+	if sendErr == nil {
+		_, err := w.db.ExecContext(ctx,
+			"UPDATE alert_deliveries SET status = 'sent', attempts = ?, last_error = '', sent_at = CURRENT_TIMESTAMP WHERE id = ?",
+			attempts, d.ID)
+		return err
+	}
+
+	msg := sendErr.Error()
+	if len(msg) > maxErrorLength {
+		msg = strings.ToValidUTF8(msg[:maxErrorLength], "")
+	}
+	status := "pending"
+	if attempts >= maxAttempts || sendErr == errUnsubscribed {
+		status = "failed"
+	}
+	retryAt := time.Now().Add(retryBackoff << (attempts - 1))
+	_, err := w.db.ExecContext(ctx,
+		"UPDATE alert_deliveries SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ? WHERE id = ?",
+		status, attempts, msg, retryAt, d.ID)
+	return err
+}
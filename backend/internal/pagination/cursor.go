@@ -0,0 +1,89 @@
+package pagination
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"sample-backend/internal/apperr"
+)
+
+// cursorPrefix はカーソルの形式の版。形式を変えたときに古いカーソルを誤って解釈しないよう付けておく
+const cursorPrefix = "v1:"
+
+// ErrInvalidCursor はカーソルが壊れているか、このサーバーが発行したものではない (apperr.ErrValidation)
+var ErrInvalidCursor = apperr.Validation("Invalid cursor")
+
+// EncodeCursor は afterID の次の行から読むことを表すカーソルを返す。
+// クライアントには中身に依存させないため、不透明な文字列として渡す
+func EncodeCursor(afterID int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(afterID)))
+}
+
+// DecodeCursor は EncodeCursor が返したカーソルから ID を取り出す
+func DecodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	s, ok := strings.CutPrefix(string(raw), cursorPrefix)
+	if !ok {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil || id < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+// CursorPage はカーソル方式の一覧の 1 ページ分の結果
+type CursorPage[T any] struct {
+	Items []T
+	Limit int
+	// 次のページのカーソル。最後のページなら空
+	NextCursor string
+	// 総件数。数えなかった場合は nil
+	Count *int
+}
+
+// AfterFunc は afterID より後の行を ID 順に最大 limit 件返す
+type AfterFunc[T any] func(ctx context.Context, afterID, limit int) ([]T, error)
+
+// FetchAfter は afterID より後の 1 ページ分を取得して CursorPage を組み立てる。
+// 次のページがあるかは 1 件多く読んで判定するので、総件数は数えなくてよい。
+// count を渡した場合だけ総件数も並列に取得する。id は行の ID を返す関数
+func FetchAfter[T any](ctx context.Context, afterID, limit int, count CountFunc, list AfterFunc[T], id func(T) int) (*CursorPage[T], error) {
+	var total int
+	var items []T
+
+	g, gctx := errgroup.WithContext(ctx)
+	if count != nil {
+		g.Go(func() error {
+			var err error
+			total, err = count(gctx)
+			return err
+		})
+	}
+	g.Go(func() error {
+		var err error
+		items, err = list(gctx, afterID, limit+1)
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	page := &CursorPage[T]{Items: items, Limit: limit}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.NextCursor = EncodeCursor(id(page.Items[limit-1]))
+	}
+	if count != nil {
+		page.Count = &total
+	}
+	return page, nil
+}
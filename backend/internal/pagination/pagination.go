@@ -0,0 +1,109 @@
+// Package pagination はページ番号と件数によるオフセット方式の一覧取得をまとめる。
+// リソースごとの一覧は件数の取得と 1 ページ分の取得だけを渡せばよく、
+// パラメータの丸め・OFFSET の計算・総ページ数の算出はここで行う。
+// 深いページを OFFSET で読み飛ばさずに済むよう、ID をカーソルにした方式 (cursor.go) も用意する
+package pagination
+
+import (
+	"context"
+	"math"
+	"net/url"
+	"strconv"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// 既定の件数と上限。リソースごとに変える場合は Normalize に別の値を渡す
+const (
+	// DefaultLimit はフロントエンドの既定の一覧で使われる 1 ページあたりの件数
+	DefaultLimit = 10
+	// MaxLimit は 1 ページあたりの最大件数
+	MaxLimit = 100
+)
+
+// Request はクライアントが指定したページ番号と件数
+type Request struct {
+	Page  int
+	Limit int
+}
+
+// ParseQuery は page / limit パラメータを読み取る。数値でない値は 0 とし、Normalize で丸める
+func ParseQuery(q url.Values) Request {
+	page, _ := strconv.Atoi(q.Get("page"))
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	return Request{Page: page, Limit: limit}
+}
+
+// Normalize はページ番号を 1 以上に、件数を 1〜maxLimit に丸める。範囲外の件数は defaultLimit とする
+func (r Request) Normalize(defaultLimit, maxLimit int) Request {
+	if r.Page < 1 {
+		r.Page = 1
+	}
+	if r.Limit < 1 || r.Limit > maxLimit {
+		r.Limit = defaultLimit
+	}
+	return r
+}
+
+// Offset は読み飛ばす件数を返す。Normalize した後に呼び出すこと。
+// 極端に大きいページ番号で掛け算があふれて負にならないよう、上限で止める (空のページになる)
+func (r Request) Offset() int {
+	if r.Page-1 > math.MaxInt/r.Limit {
+		return math.MaxInt
+	}
+	return (r.Page - 1) * r.Limit
+}
+
+// Page は一覧の 1 ページ分の結果
+type Page[T any] struct {
+	Items      []T
+	Page       int
+	Limit      int
+	TotalPages int
+	Count      int
+}
+
+// TotalPages は総件数から総ページ数を求める
+func TotalPages(count, limit int) int {
+	if limit < 1 {
+		return 0
+	}
+	return int(math.Ceil(float64(count) / float64(limit)))
+}
+
+// CountFunc は条件に一致する総件数を返す
+type CountFunc func(ctx context.Context) (int, error)
+
+// ListFunc は limit / offset で指定した 1 ページ分を返す
+type ListFunc[T any] func(ctx context.Context, limit, offset int) ([]T, error)
+
+// Fetch は総件数と 1 ページ分を並列に取得して Page を組み立てる。
+// 2 つは互いに依存しないため別々のプール接続で実行し、どちらかが失敗すればもう一方も取り消す。
+// req は Normalize 済みであること
+func Fetch[T any](ctx context.Context, req Request, count CountFunc, list ListFunc[T]) (*Page[T], error) {
+	var total int
+	var items []T
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		var err error
+		total, err = count(gctx)
+		return err
+	})
+	g.Go(func() error {
+		var err error
+		items, err = list(gctx, req.Limit, req.Offset())
+		return err
+	})
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return &Page[T]{
+		Items:      items,
+		Page:       req.Page,
+		Limit:      req.Limit,
+		TotalPages: TotalPages(total, req.Limit),
+		Count:      total,
+	}, nil
+}
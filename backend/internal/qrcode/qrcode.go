@@ -0,0 +1,483 @@
+// Package qrcode は URL などの短い文字列を QR コード (JIS X 0510 / ISO 18004) に符号化する。
+// 製品ページへのリンク用途に絞り、8 ビットバイトモード・型番 1〜10 だけに対応する
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Level は誤り訂正レベル
+type Level int
+
+const (
+	Low      Level = iota // 約 7% の欠損を復元できる
+	Medium                // 約 15%
+	Quartile              // 約 25%
+	High                  // 約 30%
+)
+
+// ErrTooLong はデータが対応している最大の型番に収まらない
+var ErrTooLong = errors.New("qrcode: data too long")
+
+// ParseLevel は "L" / "M" / "Q" / "H" を誤り訂正レベルに変換する
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return Low, nil
+	case "M":
+		return Medium, nil
+	case "Q":
+		return Quartile, nil
+	case "H":
+		return High, nil
+	}
+	return 0, fmt.Errorf("qrcode: unknown error correction level %q", s)
+}
+
+func (l Level) String() string {
+	return [...]string{"L", "M", "Q", "H"}[l]
+}
+
+// formatBits は形式情報に埋め込むレベルの値
+func (l Level) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+// Code は符号化済みの QR コード。モジュールは Size × Size で、クワイエットゾーンを含まない
+type Code struct {
+	Version int
+	Level   Level
+	Size    int
+	modules [][]bool
+}
+
+// Dark は (x, y) のモジュールが暗 (黒) かを返す
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// Encode はデータを収まる最小の型番で符号化する
+func Encode(data []byte, level Level) (*Code, error) {
+	version := 0
+	for v := 1; v <= maxVersion; v++ {
+		if len(data) <= capacity(v, level) {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+	return build(data, version, level, -1), nil
+}
+
+// build は型番とマスクを決めて符号化する。mask が負なら失点の最も少ないマスクを選ぶ。
+// data は version に収まっていること
+func build(data []byte, version int, level Level, mask int) *Code {
+	b := newBuilder(version, level)
+	b.drawFunctionPatterns()
+	b.drawCodewords(b.addECCAndInterleave(dataCodewords(data, version, level)))
+
+	if mask < 0 {
+		bestPenalty := -1
+		for m := 0; m < 8; m++ {
+			b.applyMask(m)
+			b.drawFormatBits(m)
+			if p := b.penalty(); bestPenalty < 0 || p < bestPenalty {
+				mask, bestPenalty = m, p
+			}
+			b.applyMask(m) // XOR なのでもう一度かけると元に戻る
+		}
+	}
+	b.applyMask(mask)
+	b.drawFormatBits(mask)
+
+	return &Code{Version: version, Level: level, Size: b.size, modules: b.modules}
+}
+
+// capacity はバイトモードで格納できる最大のバイト数
+func capacity(version int, level Level) int {
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	return (totalDataCodewords(version, level)*8 - 4 - countBits) / 8
+}
+
+// dataCodewords はモード指示子・文字数・データ・終端・埋め草を並べたデータコード語を返す
+func dataCodewords(data []byte, version int, level Level) []byte {
+	var bb bitBuffer
+	bb.append(0x4, 4) // 8 ビットバイトモード
+	if version >= 10 {
+		bb.append(len(data), 16)
+	} else {
+		bb.append(len(data), 8)
+	}
+	for _, c := range data {
+		bb.append(int(c), 8)
+	}
+
+	capBits := totalDataCodewords(version, level) * 8
+	bb.append(0, min(4, capBits-len(bb)))
+	bb.append(0, (8-len(bb)%8)%8)
+	for pad := 0xEC; len(bb) < capBits; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	out := make([]byte, len(bb)/8)
+	for i, bit := range bb {
+		if bit {
+			out[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+	return out
+}
+
+type bitBuffer []bool
+
+func (bb *bitBuffer) append(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*bb = append(*bb, (v>>i)&1 != 0)
+	}
+}
+
+// builder はモジュールの配置を組み立てる
+type builder struct {
+	version  int
+	level    Level
+	size     int
+	modules  [][]bool
+	function [][]bool // 機能パターン (マスクとデータ配置の対象外)
+}
+
+func newBuilder(version int, level Level) *builder {
+	size := version*4 + 17
+	b := &builder{version: version, level: level, size: size}
+	b.modules = make([][]bool, size)
+	b.function = make([][]bool, size)
+	for i := range b.modules {
+		b.modules[i] = make([]bool, size)
+		b.function[i] = make([]bool, size)
+	}
+	return b
+}
+
+func (b *builder) set(x, y int, dark bool) {
+	b.modules[y][x] = dark
+	b.function[y][x] = true
+}
+
+func (b *builder) drawFunctionPatterns() {
+	// タイミングパターン
+	for i := 0; i < b.size; i++ {
+		b.set(6, i, i%2 == 0)
+		b.set(i, 6, i%2 == 0)
+	}
+
+	// 位置検出パターン (分離パターンを含む)
+	b.drawFinder(3, 3)
+	b.drawFinder(b.size-4, 3)
+	b.drawFinder(3, b.size-4)
+
+	// 位置合わせパターン (位置検出パターンと重なる 3 か所は除く)
+	pos := alignmentPositions[b.version]
+	last := len(pos) - 1
+	for i := range pos {
+		for j := range pos {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			b.drawAlignment(pos[i], pos[j])
+		}
+	}
+
+	// 形式情報の領域を確保してから型番情報を描く
+	b.drawFormatBits(0)
+	b.drawVersion()
+}
+
+func (b *builder) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= b.size || y < 0 || y >= b.size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			b.set(x, y, d != 2 && d != 4)
+		}
+	}
+}
+
+func (b *builder) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			b.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits は誤り訂正レベルとマスクを BCH(15,5) で符号化して 2 か所に描く
+func (b *builder) drawFormatBits(mask int) {
+	data := b.level.formatBits()<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 != 0 }
+
+	// 左上
+	for i := 0; i <= 5; i++ {
+		b.set(8, i, bit(i))
+	}
+	b.set(8, 7, bit(6))
+	b.set(8, 8, bit(7))
+	b.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		b.set(14-i, 8, bit(i))
+	}
+
+	// 右上と左下
+	for i := 0; i < 8; i++ {
+		b.set(b.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		b.set(8, b.size-15+i, bit(i))
+	}
+	b.set(8, b.size-8, true) // 常に暗のモジュール
+}
+
+// drawVersion は型番 7 以上で型番情報を BCH(18,6) で符号化して 2 か所に描く
+func (b *builder) drawVersion() {
+	if b.version < 7 {
+		return
+	}
+	rem := b.version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := b.version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 != 0
+		a, c := b.size-11+i%3, i/3
+		b.set(a, c, dark)
+		b.set(c, a, dark)
+	}
+}
+
+// addECCAndInterleave はブロックごとに誤り訂正コード語を付け、規定の順に並べ替える
+func (b *builder) addECCAndInterleave(data []byte) []byte {
+	spec := eccTable[b.version][b.level]
+	divisor := rsDivisor(spec.ecc)
+
+	var blocks, eccs [][]byte
+	offset := 0
+	for _, g := range spec.groups {
+		for i := 0; i < g.blocks; i++ {
+			block := data[offset : offset+g.data]
+			offset += g.data
+			blocks = append(blocks, block)
+			eccs = append(eccs, rsRemainder(block, divisor))
+		}
+	}
+
+	var out []byte
+	longest := spec.groups[len(spec.groups)-1].data
+	for i := 0; i < longest; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				out = append(out, block[i])
+			}
+		}
+	}
+	for i := 0; i < spec.ecc; i++ {
+		for _, ecc := range eccs {
+			out = append(out, ecc[i])
+		}
+	}
+	return out
+}
+
+// drawCodewords は右下から 2 列ずつ上下に折り返しながらコード語のビットを配置する
+func (b *builder) drawCodewords(codewords []byte) {
+	i := 0
+	for right := b.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // 縦のタイミングパターンを飛ばす
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < b.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if upward {
+					y = b.size - 1 - vert
+				}
+				if b.function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				b.modules[y][x] = (codewords[i>>3]>>(7-i&7))&1 != 0
+				i++
+			}
+		}
+	}
+}
+
+func (b *builder) applyMask(mask int) {
+	for y := 0; y < b.size; y++ {
+		for x := 0; x < b.size; x++ {
+			if b.function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				b.modules[y][x] = !b.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty はマスクの評価に使う失点を規格の 4 つの規則で計算する
+func (b *builder) penalty() int {
+	n := b.size
+	at := func(x, y int, vertical bool) bool {
+		if vertical {
+			return b.modules[x][y]
+		}
+		return b.modules[y][x]
+	}
+
+	score := 0
+	for _, vertical := range []bool{false, true} {
+		for y := 0; y < n; y++ {
+			// 規則 1: 同色が 5 個以上続く
+			run := 1
+			for x := 1; x < n; x++ {
+				if at(x, y, vertical) == at(x-1, y, vertical) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+			if run >= 5 {
+				score += run - 2
+			}
+
+			// 規則 3: 位置検出パターンに似た 1:1:3:1:1 の並びと 4 モジュールの明
+			for x := 0; x+10 < n; x++ {
+				if finderLike(func(i int) bool { return at(x+i, y, vertical) }) {
+					score += 40
+				}
+			}
+		}
+	}
+
+	// 規則 2: 2×2 の同色のブロック
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if b.modules[y][x] {
+				dark++
+			}
+			if x+1 < n && y+1 < n {
+				c := b.modules[y][x]
+				if c == b.modules[y][x+1] && c == b.modules[y+1][x] && c == b.modules[y+1][x+1] {
+					score += 3
+				}
+			}
+		}
+	}
+
+	// 規則 4: 暗モジュールの割合が 50% から離れている
+	k := abs(dark*20-n*n*10) / (n * n)
+	return score + k*10
+}
+
+var (
+	finderAfter  = []bool{true, false, true, true, true, false, true, false, false, false, false}
+	finderBefore = []bool{false, false, false, false, true, false, true, true, true, false, true}
+)
+
+func finderLike(at func(i int) bool) bool {
+	match := func(pattern []bool) bool {
+		for i, want := range pattern {
+			if at(i) != want {
+				return false
+			}
+		}
+		return true
+	}
+	return match(finderAfter) || match(finderBefore)
+}
+
+// rsDivisor は次数 degree の Reed-Solomon 生成多項式の係数 (最高次を除く) を返す
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder はデータを生成多項式で割った余り (誤り訂正コード語) を返す
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, d := range data {
+		factor := d ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, c := range divisor {
+			result[i] ^= gfMul(c, factor)
+		}
+	}
+	return result
+}
+
+// gfMul は GF(2^8) (原始多項式 x^8+x^4+x^3+x^2+1) 上の積
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
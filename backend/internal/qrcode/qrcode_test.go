@@ -0,0 +1,174 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// vectorData は既知ベクトルのデータ (testdata/vectors.js と同じ規則) で、URL に数字を足して n バイトにする
+func vectorData(n int) []byte {
+	s := "https://example.com/p/"
+	for i := 0; len(s) < n; i++ {
+		s += string(rune('0' + i%10))
+	}
+	return []byte(s[:n])
+}
+
+// moduleText はモジュールを '#' (暗) と '.' (明) の行にする (testdata/*.txt と同じ形式)
+func moduleText(c *Code) string {
+	var sb strings.Builder
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Dark(x, y) {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// TestKnownVectors は型番とマスクを固定したモジュールの配置を参照実装の出力 (testdata/*.txt) と比べる。
+// 8 種類のマスクと、型番 1 / 2 (位置合わせパターンの有無)・6 / 7 (型番情報の有無)・9 / 10 (文字数が 16 ビット) を
+// 各誤り訂正レベルで容量いっぱいのデータにして確かめる。生成方法は testdata/vectors.js を参照
+func TestKnownVectors(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("testdata", "vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cases []struct {
+		Name    string
+		Version int
+		Level   string
+		Mask    int
+		Length  int
+	}
+	if err := json.Unmarshal(raw, &cases); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			level, err := ParseLevel(tc.Level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := os.ReadFile(filepath.Join("testdata", tc.Name+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := build(vectorData(tc.Length), tc.Version, level, tc.Mask)
+			if got.Size != 4*tc.Version+17 {
+				t.Fatalf("size = %d, want %d", got.Size, 4*tc.Version+17)
+			}
+			if moduleText(got) != string(want) {
+				t.Errorf("modules differ from testdata/%s.txt\ngot:\n%s\nwant:\n%s", tc.Name, moduleText(got), want)
+			}
+		})
+	}
+}
+
+// バイトモードの容量は規格の表 (JIS X 0510 表 7) のとおり
+func TestCapacity(t *testing.T) {
+	want := map[int][4]int{
+		1:  {17, 14, 11, 7},
+		2:  {32, 26, 20, 14},
+		6:  {134, 106, 74, 58},
+		7:  {154, 122, 86, 64},
+		9:  {230, 180, 130, 98},
+		10: {271, 213, 151, 119},
+	}
+	for version, caps := range want {
+		for level, n := range caps {
+			if got := capacity(version, Level(level)); got != n {
+				t.Errorf("capacity(%d, %s) = %d, want %d", version, Level(level), got, n)
+			}
+		}
+	}
+}
+
+func TestEncodeVersionBoundaries(t *testing.T) {
+	for version := 1; version < maxVersion; version++ {
+		for level := Low; level <= High; level++ {
+			n := capacity(version, level)
+			if c, err := Encode(vectorData(n), level); err != nil || c.Version != version {
+				t.Errorf("Encode(%d bytes, %s) = version %v, %v; want %d", n, level, versionOf(c), err, version)
+			}
+			if c, err := Encode(vectorData(n+1), level); err != nil || c.Version != version+1 {
+				t.Errorf("Encode(%d bytes, %s) = version %v, %v; want %d", n+1, level, versionOf(c), err, version+1)
+			}
+		}
+	}
+	for level := Low; level <= High; level++ {
+		if _, err := Encode(vectorData(capacity(maxVersion, level)+1), level); !errors.Is(err, ErrTooLong) {
+			t.Errorf("Encode past version %d at %s: err = %v, want ErrTooLong", maxVersion, level, err)
+		}
+	}
+}
+
+func versionOf(c *Code) int {
+	if c == nil {
+		return 0
+	}
+	return c.Version
+}
+
+// Encode の出力はいずれかのマスクを固定して作った配置と同じになる (マスクの選択の後に形式情報を書き直している)
+func TestEncodeChoosesWrittenMask(t *testing.T) {
+	data := vectorData(30)
+	c, err := Encode(data, Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for mask := 0; mask < 8; mask++ {
+		if moduleText(build(data, c.Version, Medium, mask)) == moduleText(c) {
+			return
+		}
+	}
+	t.Error("Encode output does not match any fixed-mask encoding")
+}
+
+func TestRender(t *testing.T) {
+	c, err := Encode([]byte("https://example.com/products/1"), Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	side := c.Size + 2*QuietZone
+
+	b, err := c.PNG(300)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scale := 300 / side
+	if got := img.Bounds().Dx(); got != side*scale || got > 300 {
+		t.Errorf("PNG width = %d, want %d", got, side*scale)
+	}
+	// 左上の位置検出パターンの角は暗、クワイエットゾーンは明
+	if r, _, _, _ := img.At(QuietZone*scale, QuietZone*scale).RGBA(); r != 0 {
+		t.Error("finder pattern corner is not dark")
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Error("quiet zone is not light")
+	}
+
+	svg := string(c.SVG(200))
+	if !strings.Contains(svg, `width="200"`) || !strings.Contains(svg, fmt.Sprintf(`viewBox="0 0 %d %d"`, side, side)) {
+		t.Errorf("unexpected SVG header: %.200s", svg)
+	}
+	// 左上の位置検出パターンの上端は 7 モジュールの連続
+	if !strings.Contains(svg, "M4 4h7v1h-7z") {
+		t.Error("SVG does not contain the top edge of the finder pattern")
+	}
+}
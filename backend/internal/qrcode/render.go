@@ -0,0 +1,71 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// QuietZone は周囲に確保する余白のモジュール数 (規格の最小値)
+const QuietZone = 4
+
+// scale は一辺 size ピクセルに収まる 1 モジュールあたりのピクセル数 (最小 1)
+func (c *Code) scale(size int) int {
+	return max(1, size/(c.Size+2*QuietZone))
+}
+
+// PNG は一辺がおよそ size ピクセルの白黒 PNG を返す。
+// モジュールの境界がぼけないよう整数倍に拡大するため、実際の大きさは size 以下になる
+func (c *Code) PNG(size int) ([]byte, error) {
+	scale := c.scale(size)
+	side := (c.Size + 2*QuietZone) * scale
+
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			px, py := (x+QuietZone)*scale, (y+QuietZone)*scale
+			for dy := 0; dy < scale; dy++ {
+				row := img.Pix[(py+dy)*img.Stride+px:]
+				for dx := 0; dx < scale; dx++ {
+					row[dx] = 1
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := enc.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG は一辺 size ピクセルで表示する SVG を返す。暗モジュールを横方向の連続ごとに 1 つの矩形にまとめる
+func (c *Code) SVG(size int) []byte {
+	side := c.Size + 2*QuietZone
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, side, side)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, side, side)
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; {
+			if !c.modules[y][x] {
+				x++
+				continue
+			}
+			start := x
+			for x < c.Size && c.modules[y][x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start+QuietZone, y+QuietZone, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
@@ -0,0 +1,31 @@
+// qrcode_test.go の既知ベクトルを生成する。参照実装には Kazuhiko Arase の QRCode for JavaScript (MIT) を使う
+// (npm に同梱の qrcode-terminal/vendor/QRCode など)。
+//   node testdata/vectors.js <QRCode のディレクトリ>
+// 型番とマスクを固定して makeImpl を呼び、モジュールを '#' (暗) と '.' (明) の行で testdata/*.txt に書き出す
+const fs = require('fs');
+const path = require('path');
+
+const lib = process.argv[2];
+const QRCode = require(path.resolve(lib));
+const QRErrorCorrectLevel = require(path.resolve(lib, 'QRErrorCorrectLevel'));
+
+// qrcode_test.go の vectorData と同じ規則で作るデータ
+function vectorData(n) {
+  let s = 'https://example.com/p/';
+  for (let i = 0; s.length < n; i++) s += String(i % 10);
+  return s.slice(0, n);
+}
+
+const cases = JSON.parse(fs.readFileSync(path.join(__dirname, 'vectors.json'), 'utf8'));
+for (const c of cases) {
+  const qr = new QRCode(c.version, QRErrorCorrectLevel[c.level]);
+  qr.addData(vectorData(c.length));
+  qr.makeImpl(false, c.mask);
+  const rows = [];
+  for (let r = 0; r < qr.getModuleCount(); r++) {
+    let row = '';
+    for (let col = 0; col < qr.getModuleCount(); col++) row += qr.isDark(r, col) ? '#' : '.';
+    rows.push(row);
+  }
+  fs.writeFileSync(path.join(__dirname, c.name + '.txt'), rows.join('\n') + '\n');
+}
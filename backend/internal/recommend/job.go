@@ -0,0 +1,209 @@
+package recommend
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// JobConfig はおすすめの集計方針
+type JobConfig struct {
+	// 集計する間隔
+	Interval time.Duration
+	// 集計対象にする閲覧の期間。これより古い閲覧履歴は削除する
+	Window time.Duration
+	// 1 製品あたりに保存するおすすめの件数
+	TopN int
+}
+
+const (
+	// 1 人の閲覧者から数える製品の上限 (組み合わせ数が閲覧数の 2 乗で増えるのを抑える)
+	maxItemsPerVisitor = 50
+	// これより少ない人数にしか一緒に閲覧されていない組み合わせはおすすめにしない
+	minCoViewers = 2
+	// 複数のインスタンスで同時に集計しないための MySQL のロック名
+	jobLockName = "product_recommendations_job"
+	insertBatch = 500
+)
+
+type pair struct {
+	a, b int
+}
+
+// StartJob は閲覧履歴からおすすめを定期的に集計し直すジョブを起動する
+func StartJob(db *sqlx.DB, cfg JobConfig) {
+	if cfg.Interval <= 0 {
+		log.Println("[RECOMMEND] Recommendation job disabled")
+		return
+	}
+
+	run := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Interval)
+		defer cancel()
+		if err := runExclusive(ctx, db, cfg); err != nil {
+			log.Printf("[RECOMMEND ERROR] Recommendation job failed: %v", err)
+		}
+	}
+
+	go func() {
+		run()
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
+	log.Printf("[RECOMMEND] Recommendation job started - interval: %v, window: %v, top: %d", cfg.Interval, cfg.Window, cfg.TopN)
+}
+
+// runExclusive は MySQL の名前付きロックを取れたときだけ集計する
+func runExclusive(ctx context.Context, db *sqlx.DB, cfg JobConfig) error {
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var locked int
+	if err := conn.GetContext(ctx, &locked, "SELECT GET_LOCK(?, 0)", jobLockName); err != nil {
+		return fmt.Errorf("failed to acquire job lock: %w", err)
+	}
+	if locked != 1 {
+		log.Println("[RECOMMEND] Another instance is computing recommendations, skipping")
+		return nil
+	}
+	defer conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", jobLockName)
+
+	return compute(ctx, db, cfg)
+}
+
+func compute(ctx context.Context, db *sqlx.DB, cfg JobConfig) error {
+	start := time.Now()
+	since := start.Add(-cfg.Window)
+
+	// 古い閲覧履歴を少しずつ削除する (一度に大量に消してロックを長く持たない)
+	for {
+		res, err := db.ExecContext(ctx, "DELETE FROM product_views WHERE viewed_at < ? LIMIT 10000", since)
+		if err != nil {
+			return fmt.Errorf("failed to prune product views: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n < 10000 {
+			break
+		}
+	}
+
+	// 閲覧者ごとに、最近閲覧した製品から順に読む
+	rows, err := db.QueryContext(ctx, `SELECT visitor_id, product_id
+		FROM product_views
+		WHERE viewed_at >= ?
+		GROUP BY visitor_id, product_id
+		ORDER BY visitor_id, MAX(viewed_at) DESC`, since)
+	if err != nil {
+		return fmt.Errorf("failed to read product views: %w", err)
+	}
+	defer rows.Close()
+
+	viewers := map[int]int{}
+	coViews := map[pair]int{}
+	visitors := 0
+	var current string
+	var items []int
+	countVisitor := func() {
+		for i := range items {
+			viewers[items[i]]++
+			for _, b := range items[i+1:] {
+				a := items[i]
+				if a > b {
+					a, b = b, a
+				}
+				coViews[pair{a, b}]++
+			}
+		}
+		visitors++
+	}
+	for rows.Next() {
+		var visitorID string
+		var productID int
+		if err := rows.Scan(&visitorID, &productID); err != nil {
+			return err
+		}
+		if visitorID != current {
+			if len(items) > 0 {
+				countVisitor()
+			}
+			current, items = visitorID, items[:0]
+		}
+		if len(items) < maxItemsPerVisitor {
+			items = append(items, productID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(items) > 0 {
+		countVisitor()
+	}
+
+	// コサイン類似度 (一緒に閲覧した人数 / √(それぞれの閲覧人数の積)) の高い順に上位だけを残す
+	type scored struct {
+		id    int
+		score float64
+	}
+	candidates := map[int][]scored{}
+	for p, n := range coViews {
+		if n < minCoViewers {
+			continue
+		}
+		score := float64(n) / math.Sqrt(float64(viewers[p.a])*float64(viewers[p.b]))
+		candidates[p.a] = append(candidates[p.a], scored{p.b, score})
+		candidates[p.b] = append(candidates[p.b], scored{p.a, score})
+	}
+
+	var args []interface{}
+	for productID, list := range candidates {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].score != list[j].score {
+				return list[i].score > list[j].score
+			}
+			return list[i].id < list[j].id
+		})
+		if len(list) > cfg.TopN {
+			list = list[:cfg.TopN]
+		}
+		for _, c := range list {
+			args = append(args, productID, c.id, c.score)
+		}
+	}
+
+	// 読み手が空の結果を見ないよう、入れ替えは 1 つのトランザクションで行う
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM product_recommendations"); err != nil {
+		return fmt.Errorf("failed to clear recommendations: %w", err)
+	}
+	for i := 0; i < len(args); i += insertBatch * 3 {
+		chunk := args[i:min(i+insertBatch*3, len(args))]
+		query := "INSERT INTO product_recommendations (product_id, recommended_id, score) VALUES " +
+			strings.TrimSuffix(strings.Repeat("(?, ?, ?),", len(chunk)/3), ",")
+		if _, err := tx.ExecContext(ctx, query, chunk...); err != nil {
+			return fmt.Errorf("failed to insert recommendations: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("[RECOMMEND] Computed %d recommendations for %d products from %d visitors in %v",
+		len(args)/3, len(candidates), visitors, time.Since(start).Round(time.Millisecond))
+	return nil
+}
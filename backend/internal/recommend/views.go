@@ -0,0 +1,133 @@
+// Package recommend は製品の閲覧履歴の記録と、そこからの「一緒に閲覧された製品」の集計を扱う。
+// 集計結果は product_recommendations に書き込み、API は repository.RecommendationRepository で読む
+package recommend
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/hooks"
+)
+
+// VisitorIDHeader はフロントエンドが閲覧者を識別するために送る匿名の ID
+const VisitorIDHeader = "X-Visitor-ID"
+
+// productDetailRoute は閲覧として記録するルート
+const productDetailRoute = "GET /api/products/{id}"
+
+const (
+	maxVisitorIDLength = 64
+	viewBatchSize      = 500
+	viewFlushInterval  = time.Second
+)
+
+type view struct {
+	visitorID string
+	productID int
+	at        time.Time
+}
+
+// ViewRecorder は製品詳細の閲覧をリクエストのゴルーチンから切り離し、まとめて product_views に書き込む
+type ViewRecorder struct {
+	db      *sqlx.DB
+	views   chan view
+	dropped atomic.Int64
+}
+
+func NewViewRecorder(db *sqlx.DB, bufferSize int) *ViewRecorder {
+	if bufferSize <= 0 {
+		bufferSize = 4096
+	}
+	r := &ViewRecorder{db: db, views: make(chan view, bufferSize)}
+	go r.run()
+
+	log.Printf("[RECOMMEND] Recording product views (buffer: %d)", bufferSize)
+	return r
+}
+
+// OnResponse は hooks.Registry.OnResponse に登録し、製品詳細を正常に返したリクエストを閲覧として記録する。
+// 閲覧者の ID が無いリクエストは記録しない
+func (r *ViewRecorder) OnResponse(req *http.Request, res hooks.Response) {
+	if res.Route != productDetailRoute || res.Status != http.StatusOK {
+		return
+	}
+	visitorID := req.Header.Get(VisitorIDHeader)
+	if !ValidVisitorID(visitorID) {
+		return
+	}
+	// ルーティングの外側から呼ばれるため PathValue は使えない
+	id, err := strconv.Atoi(strings.TrimPrefix(req.URL.Path, "/api/products/"))
+	if err != nil {
+		return
+	}
+	r.Record(visitorID, id)
+}
+
+// Record は閲覧を書き込み待ちに積む。溢れた分は捨てて件数だけ数える
+func (r *ViewRecorder) Record(visitorID string, productID int) {
+	select {
+	case r.views <- view{visitorID: visitorID, productID: productID, at: time.Now()}:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+func (r *ViewRecorder) run() {
+	ticker := time.NewTicker(viewFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]view, 0, viewBatchSize)
+	for {
+		select {
+		case v := <-r.views:
+			batch = append(batch, v)
+			if len(batch) < viewBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if n := r.dropped.Swap(0); n > 0 {
+				log.Printf("[RECOMMEND] Dropped %d product views (buffer full)", n)
+			}
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		r.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+func (r *ViewRecorder) flush(batch []view) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := "INSERT INTO product_views (visitor_id, product_id, viewed_at) VALUES " +
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?),", len(batch)), ",")
+	args := make([]interface{}, 0, len(batch)*3)
+	for _, v := range batch {
+		args = append(args, v.visitorID, v.productID, v.at)
+	}
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		log.Printf("[RECOMMEND ERROR] Failed to record %d product views: %v", len(batch), err)
+	}
+}
+
+// ValidVisitorID は閲覧者の ID として受け付ける値か (64 文字以内の表示可能な ASCII) を返す
+func ValidVisitorID(id string) bool {
+	if id == "" || len(id) > maxVisitorIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,201 @@
+// Package reindex は検索用の非正規化テーブル (product_search) を products から作り直す。
+// 一括投入やテーブル定義の変更のあと、トリガーの取りこぼしなどで内容がずれたときの復旧に使う。
+//
+// 作り直しは別のテーブル (product_search_new) に ID の範囲ごとに並列で書き込み、
+// 最後に RENAME TABLE で入れ替える。作り直している間の products への書き込みは
+// 一時的なトリガーで新しいテーブルにも反映するため、読み手は常に完全なテーブルを参照する
+package reindex
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/sync/errgroup"
+
+	"sample-backend/internal/apperr"
+)
+
+// ErrRunning は作り直しがすでに実行中 (他のインスタンスを含む)
+var ErrRunning = apperr.Conflict("Reindex is already running", nil)
+
+const (
+	lockName  = "product_search_reindex"
+	liveTable = "product_search"
+	newTable  = "product_search_new"
+)
+
+// 作り直している間、products への書き込みを新しいテーブルにも反映するトリガー。
+// 既存のトリガー (init.sql) のあとに実行し、人気度 (popularity) は範囲ごとのコピーが引き継ぐ
+var mirrorTriggers = []struct{ name, ddl string }{
+	{"products_reindex_ai", `CREATE TRIGGER products_reindex_ai AFTER INSERT ON products FOR EACH ROW FOLLOWS products_search_ai
+		INSERT INTO product_search_new (id, name, brand, category_name, price, search_text)
+		VALUES (NEW.id, NEW.name, NEW.brand, NEW.category, NEW.price,
+			CONCAT_WS(' ', NEW.name, NEW.category, NEW.brand, NEW.model, NEW.description)) AS src
+		ON DUPLICATE KEY UPDATE name = src.name, brand = src.brand, category_name = src.category_name,
+			price = src.price, search_text = src.search_text`},
+	{"products_reindex_au", `CREATE TRIGGER products_reindex_au AFTER UPDATE ON products FOR EACH ROW FOLLOWS products_search_au
+		INSERT INTO product_search_new (id, name, brand, category_name, price, search_text)
+		VALUES (NEW.id, NEW.name, NEW.brand, NEW.category, NEW.price,
+			CONCAT_WS(' ', NEW.name, NEW.category, NEW.brand, NEW.model, NEW.description)) AS src
+		ON DUPLICATE KEY UPDATE name = src.name, brand = src.brand, category_name = src.category_name,
+			price = src.price, search_text = src.search_text`},
+	{"products_reindex_ad", `CREATE TRIGGER products_reindex_ad AFTER DELETE ON products FOR EACH ROW FOLLOWS products_search_ad
+		DELETE FROM product_search_new WHERE id = OLD.id`},
+}
+
+// copyChunk は ID の範囲の製品を新しいテーブルに書き込む。
+// INSERT ... SELECT は元の行をロックして読むため、トリガーが書いた内容より古い値で上書きすることはない
+const copyChunk = `INSERT INTO product_search_new (id, name, brand, category_name, price, popularity, search_text)
+	SELECT p.id, p.name, p.brand, p.category, p.price, COALESCE(s.popularity, 0),
+		CONCAT_WS(' ', p.name, p.category, p.brand, p.model, p.description)
+	FROM products p
+	LEFT JOIN product_search s ON s.id = p.id
+	WHERE p.id BETWEEN ? AND ?
+	ON DUPLICATE KEY UPDATE name = p.name, brand = p.brand, category_name = p.category, price = p.price,
+		popularity = COALESCE(s.popularity, 0), search_text = CONCAT_WS(' ', p.name, p.category, p.brand, p.model, p.description)`
+
+// Options は作り直しの分割と並列度
+type Options struct {
+	// 1 回の INSERT ... SELECT で扱う ID の幅
+	ChunkSize int
+	// 同時に実行する INSERT ... SELECT の数
+	Concurrency int
+}
+
+func (o Options) normalize() Options {
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = 10000
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 4
+	}
+	return o
+}
+
+// Progress は作り直しの進み具合
+type Progress struct {
+	// "running" / "succeeded" / "failed"。一度も実行していなければ空
+	State       string     `json:"state"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	TotalChunks int        `json:"total_chunks"`
+	DoneChunks  int        `json:"done_chunks"`
+	Rows        int64      `json:"rows"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// Rebuild は product_search を作り直して入れ替える。進み具合は範囲ごとに progress に通知する
+func Rebuild(ctx context.Context, db *sqlx.DB, opts Options, progress func(Progress)) (err error) {
+	opts = opts.normalize()
+	if progress == nil {
+		progress = func(Progress) {}
+	}
+	p := Progress{State: "running", StartedAt: time.Now()}
+	progress(p)
+
+	// 名前付きロックは接続に結びつくため、終わるまで 1 本の接続を持ち続ける
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var locked int
+	if err := conn.GetContext(ctx, &locked, "SELECT GET_LOCK(?, 0)", lockName); err != nil {
+		return fmt.Errorf("failed to acquire reindex lock: %w", err)
+	}
+	if locked != 1 {
+		return ErrRunning
+	}
+	defer conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", lockName)
+
+	// 途中で失敗したら一時的なトリガーとテーブルを片付ける (元のテーブルはそのまま)
+	defer func() {
+		if err != nil {
+			cleanup(db)
+		}
+	}()
+
+	cleanup(db)
+	if _, err := db.ExecContext(ctx, "CREATE TABLE "+newTable+" LIKE "+liveTable); err != nil {
+		return fmt.Errorf("failed to create %s: %w", newTable, err)
+	}
+	for _, t := range mirrorTriggers {
+		if _, err := db.ExecContext(ctx, t.ddl); err != nil {
+			return fmt.Errorf("failed to create trigger %s: %w", t.name, err)
+		}
+	}
+
+	var bounds struct {
+		Min *int `db:"min_id"`
+		Max *int `db:"max_id"`
+	}
+	if err := db.GetContext(ctx, &bounds, "SELECT MIN(id) AS min_id, MAX(id) AS max_id FROM products"); err != nil {
+		return err
+	}
+
+	if bounds.Min != nil {
+		lo, hi := *bounds.Min, *bounds.Max
+		p.TotalChunks = (hi-lo)/opts.ChunkSize + 1
+		progress(p)
+
+		var mu sync.Mutex
+		var rows atomic.Int64
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(opts.Concurrency)
+		for start := lo; start <= hi; start += opts.ChunkSize {
+			end := min(start+opts.ChunkSize-1, hi)
+			g.Go(func() error {
+				res, err := db.ExecContext(gctx, copyChunk, start, end)
+				if err != nil {
+					return fmt.Errorf("failed to copy ids %d-%d: %w", start, end, err)
+				}
+				// ON DUPLICATE KEY UPDATE で更新した行は 2 と数えられるため、行数は目安
+				n, _ := res.RowsAffected()
+				rows.Add(n)
+
+				mu.Lock()
+				p.DoneChunks++
+				p.Rows = rows.Load()
+				progress(p)
+				mu.Unlock()
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
+		}
+	}
+
+	// 入れ替え。古いテーブルを product_search_new の名前に残すことで、
+	// トリガーを消すまでの間の書き込みも失敗させない
+	if _, err := db.ExecContext(ctx, "RENAME TABLE "+liveTable+" TO product_search_old, "+
+		newTable+" TO "+liveTable+", product_search_old TO "+newTable); err != nil {
+		return fmt.Errorf("failed to swap tables: %w", err)
+	}
+	cleanup(db)
+
+	var count int
+	if err := db.GetContext(ctx, &count, "SELECT COUNT(*) FROM "+liveTable); err == nil {
+		log.Printf("[REINDEX] %s rebuilt with %d rows in %v", liveTable, count, time.Since(p.StartedAt).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// cleanup は一時的なトリガーとテーブルを削除する
+func cleanup(db *sqlx.DB) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, t := range mirrorTriggers {
+		if _, err := db.ExecContext(ctx, "DROP TRIGGER IF EXISTS "+t.name); err != nil {
+			log.Printf("[REINDEX ERROR] Failed to drop trigger %s: %v", t.name, err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS "+newTable); err != nil {
+		log.Printf("[REINDEX ERROR] Failed to drop %s: %v", newTable, err)
+	}
+}
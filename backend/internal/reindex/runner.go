@@ -0,0 +1,84 @@
+package reindex
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Runner は管理用 API から作り直しをバックグラウンドで実行し、最後の進み具合を保持する
+type Runner struct {
+	db      *sqlx.DB
+	opts    Options
+	timeout time.Duration
+	onDone  []func()
+
+	mu      sync.Mutex
+	running bool
+	last    Progress
+}
+
+// NewRunner は作り直しを timeout で打ち切る Runner を返す
+func NewRunner(db *sqlx.DB, opts Options, timeout time.Duration) *Runner {
+	return &Runner{db: db, opts: opts.normalize(), timeout: timeout}
+}
+
+// OnDone は作り直しが成功したあとに呼ぶ関数を登録する (キャッシュの破棄など)。Start より前に呼ぶ
+func (r *Runner) OnDone(fn func()) {
+	r.onDone = append(r.onDone, fn)
+}
+
+// Start は作り直しを開始する。このインスタンスで実行中なら ErrRunning
+func (r *Runner) Start() (Progress, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running {
+		return r.last, ErrRunning
+	}
+	r.running = true
+	r.last = Progress{State: "running", StartedAt: time.Now()}
+
+	go r.run()
+	log.Printf("[REINDEX] Rebuild started (chunk: %d, concurrency: %d)", r.opts.ChunkSize, r.opts.Concurrency)
+	return r.last, nil
+}
+
+// Status は実行中または最後に実行した作り直しの進み具合を返す
+func (r *Runner) Status() Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+func (r *Runner) run() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	err := Rebuild(ctx, r.db, r.opts, func(p Progress) {
+		r.mu.Lock()
+		r.last = p
+		r.mu.Unlock()
+	})
+
+	r.mu.Lock()
+	now := time.Now()
+	r.last.FinishedAt = &now
+	r.last.State = "succeeded"
+	if err != nil {
+		r.last.State = "failed"
+		r.last.Error = err.Error()
+	}
+	r.running = false
+	r.mu.Unlock()
+
+	if err != nil {
+		log.Printf("[REINDEX ERROR] Rebuild failed: %v", err)
+		return
+	}
+	for _, fn := range r.onDone {
+		fn()
+	}
+}
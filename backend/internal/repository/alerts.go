@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/database"
+	"sample-backend/internal/models"
+)
+
+// ErrAlertNotFound は解除用トークンに対応する購読が無い
+var ErrAlertNotFound = apperr.NotFound("Alert subscription not found")
+
+// AlertRepository は再入荷・値下がりの通知の購読 (alert_subscriptions) を読み書きする。
+// 条件の評価と配信は notify パッケージのワーカーが行う
+type AlertRepository interface {
+	// CreateSubscription は購読を登録し、採番された ID と登録日時を sub に設定する
+	CreateSubscription(ctx context.Context, sub *models.AlertSubscription) error
+	// GetByToken は解除用トークンの購読を配信記録付きで返す。存在しなければ ErrAlertNotFound
+	GetByToken(ctx context.Context, token string) (*models.AlertSubscription, error)
+	// Unsubscribe は購読を解除する。存在しなければ ErrAlertNotFound
+	Unsubscribe(ctx context.Context, token string) error
+}
+
+const alertColumns = "id, product_id, kind, channel, target, baseline_price, target_price, status, unsubscribe_token, created_at, fired_at"
+
+type sqlxAlertRepository struct {
+	db *sqlx.DB
+}
+
+func NewAlertRepository(db *sqlx.DB) AlertRepository {
+	return &sqlxAlertRepository{db: db}
+}
+
+func (r *sqlxAlertRepository) CreateSubscription(ctx context.Context, sub *models.AlertSubscription) error {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO alert_subscriptions
+		(product_id, kind, channel, target, baseline_price, target_price, unsubscribe_token)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		sub.ProductID, sub.Kind, sub.Channel, sub.Target, sub.BaselinePrice, sub.TargetPrice, sub.Token)
+	if err != nil {
+		return database.Classify(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	sub.ID = int(id)
+	return database.Classify(r.db.GetContext(ctx, &sub.CreatedAt, "SELECT created_at FROM alert_subscriptions WHERE id = ?", sub.ID))
+}
+
+func (r *sqlxAlertRepository) GetByToken(ctx context.Context, token string) (*models.AlertSubscription, error) {
+	var sub models.AlertSubscription
+	err := r.db.GetContext(ctx, &sub, "SELECT "+alertColumns+" FROM alert_subscriptions WHERE unsubscribe_token = ?", token)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAlertNotFound
+	}
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+
+	sub.Deliveries = []models.AlertDelivery{}
+	err = r.db.SelectContext(ctx, &sub.Deliveries, `SELECT id, event, price, status, attempts, last_error, created_at, sent_at
+		FROM alert_deliveries WHERE subscription_id = ? ORDER BY id`, sub.ID)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	return &sub, nil
+}
+
+func (r *sqlxAlertRepository) Unsubscribe(ctx context.Context, token string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE alert_subscriptions SET status = 'unsubscribed' WHERE unsubscribe_token = ?", token)
+	if err != nil {
+		return database.Classify(err)
+	}
+	// 解除済みの購読をもう一度解除しても成功とする (値が変わらない行は影響行数に数えない)
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.GetByToken(ctx, token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"sample-backend/internal/cache"
+	"sample-backend/internal/models"
+)
+
+type listKey struct {
+	filter        string
+	limit, offset int
+}
+
+func newListKey(f ListFilter, limit, offset int) listKey {
+	return listKey{filter: f.key(), limit: limit, offset: offset}
+}
+
+// cachedRepository は読み取りを TTL 付きでキャッシュし (cache-aside)、書き込みのたびに
+// すべて破棄する。返すスライスは呼び出し元の間で共有されるため書き換えないこと。
+// 当たり・外れは呼び出し元のスパンと /debug/vars の cache_total に記録する
+type cachedRepository struct {
+	next ProductRepository
+
+	counts   *cache.TTL[listKey, int]
+	lists    *cache.TTL[listKey, []models.Product]
+	products *cache.TTL[int, *models.Product]
+	// 絞り込みの候補の件数 (キーの filter には列名か価格帯の区切りを前に付ける)
+	facets       *cache.TTL[listKey, []models.FacetCount]
+	priceBuckets *cache.TTL[listKey, []models.PriceBucket]
+
+	mu    sync.Mutex
+	hooks []func()
+}
+
+// CachedRepository はキャッシュ付きの ProductRepository
+type CachedRepository interface {
+	ProductRepository
+	// OnInvalidate は書き込みでキャッシュを破棄したときに呼ぶ関数を登録する
+	// (レスポンス単位のキャッシュなど、上位の層のキャッシュも合わせて捨てるため)
+	OnInvalidate(fn func())
+	// Invalidate はキャッシュをすべて破棄する (テーブルの作り直しなど、リポジトリを経由しない変更のあとに呼ぶ)
+	Invalidate()
+	// Forget は製品 1 件の列 (在庫・レビューの集計など) をリポジトリを経由せずに変えたあとに呼ぶ。
+	// その製品の詳細と、その製品を含みうる一覧のキャッシュを捨て、OnInvalidate の関数も呼ぶ。
+	// 件数と絞り込みの候補の件数は変わらないので残す
+	Forget(id int)
+}
+
+// NewCachedRepository は next の読み取りを ttl の間キャッシュする。size は種類ごとの最大件数
+func NewCachedRepository(next ProductRepository, ttl time.Duration, size int) CachedRepository {
+	return &cachedRepository{
+		next:         next,
+		counts:       cache.NewTTL[listKey, int](ttl, size),
+		lists:        cache.NewTTL[listKey, []models.Product](ttl, size),
+		products:     cache.NewTTL[int, *models.Product](ttl, size),
+		facets:       cache.NewTTL[listKey, []models.FacetCount](ttl, size),
+		priceBuckets: cache.NewTTL[listKey, []models.PriceBucket](ttl, size),
+	}
+}
+
+func (r *cachedRepository) OnInvalidate(fn func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, fn)
+}
+
+func (r *cachedRepository) Invalidate() {
+	r.invalidate()
+}
+
+func (r *cachedRepository) Forget(id int) {
+	r.products.Delete(id)
+	// どの一覧に含まれるかはキーから分からないので、一覧はすべて捨てる
+	r.lists.Clear()
+	r.runHooks()
+}
+
+func (r *cachedRepository) invalidate() {
+	r.counts.Clear()
+	r.lists.Clear()
+	r.products.Clear()
+	r.facets.Clear()
+	r.priceBuckets.Clear()
+	r.runHooks()
+}
+
+func (r *cachedRepository) runHooks() {
+	r.mu.Lock()
+	hooks := r.hooks
+	r.mu.Unlock()
+	for _, fn := range hooks {
+		fn()
+	}
+}
+
+func (r *cachedRepository) Count(ctx context.Context, filter ListFilter) (int, error) {
+	// 件数は並び順によらないので、並び順だけが違う一覧ではキャッシュを共有する
+	filter.Sort = ""
+	key := newListKey(filter, 0, 0)
+	n, ok := r.counts.Get(key)
+	cache.Record(ctx, "repo_count", ok)
+	if ok {
+		return n, nil
+	}
+	n, err := r.next.Count(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	r.counts.Set(key, n)
+	return n, nil
+}
+
+func (r *cachedRepository) List(ctx context.Context, filter ListFilter, limit, offset int) ([]models.Product, error) {
+	key := newListKey(filter, limit, offset)
+	products, ok := r.lists.Get(key)
+	cache.Record(ctx, "repo_list", ok)
+	if ok {
+		return products, nil
+	}
+	products, err := r.next.List(ctx, filter, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	r.lists.Set(key, products)
+	return products, nil
+}
+
+// カーソル方式の一覧は起点がリクエストごとに異なりヒット率が低いため、キャッシュしない
+func (r *cachedRepository) ListAfter(ctx context.Context, filter ListFilter, afterID, limit int) ([]models.Product, error) {
+	return r.next.ListAfter(ctx, filter, afterID, limit)
+}
+
+// 件数の集計は GROUP BY で絞り込み条件に一致する行をすべて読むため、件数と同じくキャッシュする
+func (r *cachedRepository) CountBy(ctx context.Context, filter ListFilter, column string, limit int) ([]models.FacetCount, error) {
+	filter.Sort = ""
+	key := listKey{filter: column + "|" + filter.key(), limit: limit}
+	counts, ok := r.facets.Get(key)
+	cache.Record(ctx, "repo_facet", ok)
+	if ok {
+		return counts, nil
+	}
+	counts, err := r.next.CountBy(ctx, filter, column, limit)
+	if err != nil {
+		return nil, err
+	}
+	r.facets.Set(key, counts)
+	return counts, nil
+}
+
+func (r *cachedRepository) CountByPrice(ctx context.Context, filter ListFilter, bounds []float64) ([]models.PriceBucket, error) {
+	filter.Sort = ""
+	key := listKey{filter: fmt.Sprint(bounds) + "|" + filter.key()}
+	buckets, ok := r.priceBuckets.Get(key)
+	cache.Record(ctx, "repo_facet", ok)
+	if ok {
+		return buckets, nil
+	}
+	buckets, err := r.next.CountByPrice(ctx, filter, bounds)
+	if err != nil {
+		return nil, err
+	}
+	r.priceBuckets.Set(key, buckets)
+	return buckets, nil
+}
+
+func (r *cachedRepository) Get(ctx context.Context, id int) (*models.Product, error) {
+	p, ok := r.products.Get(id)
+	cache.Record(ctx, "repo_product", ok)
+	if ok {
+		return p, nil
+	}
+	p, err := r.next.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.products.Set(id, p)
+	return p, nil
+}
+
+// GetMany はキャッシュに無い製品だけを 1 回のクエリで読み、Get と同じキャッシュに入れる
+func (r *cachedRepository) GetMany(ctx context.Context, ids []int) ([]models.Product, error) {
+	products := make([]models.Product, 0, len(ids))
+	var missing []int
+	for _, id := range ids {
+		if p, ok := r.products.Get(id); ok {
+			products = append(products, *p)
+			continue
+		}
+		missing = append(missing, id)
+	}
+	cache.Record(ctx, "repo_product_batch", len(missing) == 0)
+	if len(missing) == 0 {
+		return products, nil
+	}
+	fetched, err := r.next.GetMany(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fetched {
+		p := fetched[i]
+		r.products.Set(p.ID, &p)
+	}
+	return append(products, fetched...), nil
+}
+
+// SKU での参照は外部システムとの連携用で回数が少ないため、キャッシュしない
+func (r *cachedRepository) GetBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	return r.next.GetBySKU(ctx, sku)
+}
+
+// 検索はキーワードの組み合わせが多くヒット率が低いため、キャッシュせずにそのまま渡す
+func (r *cachedRepository) SearchCount(ctx context.Context, q SearchQuery) (int, error) {
+	return r.next.SearchCount(ctx, q)
+}
+
+func (r *cachedRepository) Search(ctx context.Context, q SearchQuery, limit, offset int) ([]models.Product, error) {
+	return r.next.Search(ctx, q, limit, offset)
+}
+
+func (r *cachedRepository) FullTextCount(ctx context.Context, keyword string) (int, error) {
+	return r.next.FullTextCount(ctx, keyword)
+}
+
+func (r *cachedRepository) FullText(ctx context.Context, keyword string, limit, offset int) ([]models.Product, error) {
+	return r.next.FullText(ctx, keyword, limit, offset)
+}
+
+func (r *cachedRepository) Create(ctx context.Context, p *models.Product) error {
+	err := r.next.Create(ctx, p)
+	// 失敗しても途中まで書き込まれた可能性があるので破棄する
+	r.invalidate()
+	return err
+}
+
+func (r *cachedRepository) CreateBatch(ctx context.Context, products []models.Product) error {
+	err := r.next.CreateBatch(ctx, products)
+	r.invalidate()
+	return err
+}
+
+func (r *cachedRepository) Update(ctx context.Context, p *models.Product) (*models.Product, error) {
+	old, err := r.next.Update(ctx, p)
+	r.invalidate()
+	return old, err
+}
+
+func (r *cachedRepository) Delete(ctx context.Context, id int) error {
+	err := r.next.Delete(ctx, id)
+	r.invalidate()
+	return err
+}
@@ -0,0 +1,94 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/repository/repotest"
+)
+
+func newCachedFake() (repository.CachedRepository, *repotest.Fake) {
+	fake := repotest.NewFake(
+		models.Product{Name: "a", Category: "pc", Price: 100},
+		models.Product{Name: "b", Category: "pc", Price: 200},
+		models.Product{Name: "c", Category: "audio", Price: 300},
+	)
+	return repository.NewCachedRepository(fake, time.Minute, 100), fake
+}
+
+func TestCachedRepositoryReads(t *testing.T) {
+	ctx := context.Background()
+	cached, fake := newCachedFake()
+	fake.SetStoreStock(1, 1, 2)
+	fake.SetStoreStock(2, 3)
+
+	for i := 0; i < 3; i++ {
+		if _, err := cached.List(ctx, repository.ListFilter{}, 10, 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cached.Get(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := fake.Calls("List"); got != 1 {
+		t.Errorf("List reached the repository %d times, want 1", got)
+	}
+	if got := fake.Calls("Get"); got != 1 {
+		t.Errorf("Get reached the repository %d times, want 1", got)
+	}
+
+	// 店舗の絞り込みが違えば別のエントリになる
+	for storeID, want := range map[int]int{1: 2, 2: 1} {
+		products, err := cached.List(ctx, repository.ListFilter{StoreIDs: []int{storeID}}, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(products) != want {
+			t.Errorf("store %d: %d products, want %d", storeID, len(products), want)
+		}
+	}
+}
+
+func TestCachedRepositoryInvalidation(t *testing.T) {
+	ctx := context.Background()
+	cached, fake := newCachedFake()
+	invalidated := 0
+	cached.OnInvalidate(func() { invalidated++ })
+
+	if _, err := cached.Count(ctx, repository.ListFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cached.Get(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// 書き込みはすべて捨てる
+	if err := cached.Create(ctx, &models.Product{Name: "d", Category: "pc", Price: 400}); err != nil {
+		t.Fatal(err)
+	}
+	n, err := cached.Count(ctx, repository.ListFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 || fake.Calls("Count") != 2 || invalidated != 1 {
+		t.Errorf("after Create: count = %d, Count calls = %d, hooks = %d; want 4, 2, 1", n, fake.Calls("Count"), invalidated)
+	}
+
+	// Forget は製品の詳細を捨てるが、件数は残す
+	if _, err := cached.Get(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	cached.Forget(1)
+	if _, err := cached.Get(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cached.Count(ctx, repository.ListFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if fake.Calls("Get") != 3 || fake.Calls("Count") != 2 || invalidated != 2 {
+		t.Errorf("after Forget: Get calls = %d, Count calls = %d, hooks = %d; want 3, 2, 2", fake.Calls("Get"), fake.Calls("Count"), invalidated)
+	}
+}
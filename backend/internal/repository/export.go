@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/database"
+	"sample-backend/internal/models"
+)
+
+// ExportRepository は製品を一覧に載せずに 1 行ずつ読み出す (全件のエクスポート)
+type ExportRepository interface {
+	// EachProduct は絞り込みに合う製品を並び順のとおりに 1 件ずつ fn に渡す。fn がエラーを返したらそこで止める。
+	// p は次の行で上書きするので、fn の外で使う場合は複製すること
+	EachProduct(ctx context.Context, filter ListFilter, fn func(p *models.Product) error) error
+}
+
+// sqlxExportRepository は 1 つのクエリの結果をカーソルで読み進める。
+// 全件を読み終えるまで接続を 1 本使い続け、読み取りの時間も件数に比例するので、
+// WithTimeouts やキャッシュは通さずリクエストの context だけで打ち切る
+type sqlxExportRepository struct {
+	db *sqlx.DB
+}
+
+func NewExportRepository(db *sqlx.DB) ExportRepository {
+	return &sqlxExportRepository{db: db}
+}
+
+func (r *sqlxExportRepository) EachProduct(ctx context.Context, filter ListFilter, fn func(p *models.Product) error) error {
+	ctx, span := tracer.Start(ctx, "repository.each_product")
+	defer span.End()
+
+	conds := filter.conditions()
+	query := fmt.Sprintf("SELECT %s FROM products %s ORDER BY %s", productColumns, conds.where(), filter.orderBy())
+	rows, err := r.db.QueryContext(ctx, query, conds.args...)
+	if err != nil {
+		return database.Classify(err)
+	}
+	defer rows.Close()
+
+	var p models.Product
+	dest := []interface{}{&p.ID, &p.Name, &p.Category, &p.Brand, &p.Model, &p.Description, &p.Price, &p.SKU, &p.CreatedAt, &p.ReviewCount, &p.RatingAverage, &p.Stock}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		if err := fn(&p); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return database.Classify(err)
+	}
+	return nil
+}
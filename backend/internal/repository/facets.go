@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"sample-backend/internal/database"
+	"sample-backend/internal/models"
+)
+
+// facetHintName は件数の集計に付けるインデックスヒントの名前
+func facetHintName(filter ListFilter, kind string) string {
+	if filter.conditions().empty() {
+		return "products_facet_" + kind
+	}
+	return filterHintName(filter, "facet_"+kind)
+}
+
+// PriceBuckets は bounds (昇順) で区切った件数 0 の価格帯を返す
+func PriceBuckets(bounds []float64) []models.PriceBucket {
+	buckets := make([]models.PriceBucket, len(bounds)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].Min = bounds[i-1]
+		}
+		if i < len(bounds) {
+			upper := bounds[i]
+			buckets[i].Max = &upper
+		}
+	}
+	return buckets
+}
+
+func (r *sqlxProductRepository) CountBy(ctx context.Context, filter ListFilter, column string, limit int) ([]models.FacetCount, error) {
+	// 列名は SQL に埋め込むので、決まった列だけを受け付ける
+	if column != FacetCategory && column != FacetBrand {
+		return nil, ErrInvalidColumn
+	}
+	conds := filter.conditions()
+	hint := database.IndexHint(facetHintName(filter, column))
+	recordIndexHint(trace.SpanFromContext(ctx), hint)
+
+	// (category, price) / (brand, price) のインデックスだけで数えられる
+	query := fmt.Sprintf("SELECT %s AS value, COUNT(*) AS count FROM products %s %s GROUP BY %s ORDER BY count DESC, value LIMIT ?",
+		column, hint, conds.where(), column)
+	counts := make([]models.FacetCount, 0, limit)
+	if err := r.db.SelectContext(ctx, &counts, query, append(conds.args, limit)...); err != nil {
+		return nil, database.Classify(err)
+	}
+	return counts, nil
+}
+
+func (r *sqlxProductRepository) CountByPrice(ctx context.Context, filter ListFilter, bounds []float64) ([]models.PriceBucket, error) {
+	conds := filter.conditions()
+	hint := database.IndexHint(facetHintName(filter, "price"))
+	recordIndexHint(trace.SpanFromContext(ctx), hint)
+
+	buckets := PriceBuckets(bounds)
+	if len(bounds) == 0 {
+		n, err := r.Count(ctx, filter)
+		buckets[0].Count = n
+		return buckets, err
+	}
+
+	// INTERVAL(price, b1, b2, ...) は price < b1 なら 0、b1 <= price < b2 なら 1 … を返す
+	args := make([]interface{}, 0, len(bounds)+len(conds.args))
+	for _, b := range bounds {
+		args = append(args, b)
+	}
+	query := fmt.Sprintf("SELECT INTERVAL(price, %s) AS bucket, COUNT(*) AS count FROM products %s %s GROUP BY bucket",
+		placeholders(len(bounds)), hint, conds.where())
+	rows, err := r.db.QueryContext(ctx, query, append(args, conds.args...)...)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, err
+		}
+		if bucket >= 0 && bucket < len(buckets) {
+			buckets[bucket].Count = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, database.Classify(err)
+	}
+	return buckets, nil
+}
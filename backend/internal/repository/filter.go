@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ListFilter は製品一覧の絞り込み条件と並び順
+type ListFilter struct {
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	// いずれかの店舗に在庫がある製品だけを返す (店舗での受け取り)
+	StoreIDs []int
+	// カテゴリ・ブランドは完全一致
+	Category string
+	Brand    string
+	MinPrice *float64
+	MaxPrice *float64
+	// 並び順 (ListSorts のキー)。空なら ID 順
+	Sort string
+}
+
+// 一覧の並び順
+const (
+	SortIDAsc         = "id_asc"
+	SortPriceAsc      = "price_asc"
+	SortPriceDesc     = "price_desc"
+	SortCreatedAtAsc  = "created_at_asc"
+	SortCreatedAtDesc = "created_at_desc"
+)
+
+// listSorts は並び順と ORDER BY 句の対応。ORDER BY にはここにある固定の文字列だけを使う
+// (同じ値の製品の順序が毎回変わらないよう、最後に id を付ける)
+var listSorts = map[string]string{
+	"":                "id",
+	SortIDAsc:         "id",
+	SortPriceAsc:      "price, id",
+	SortPriceDesc:     "price DESC, id",
+	SortCreatedAtAsc:  "created_at, id",
+	SortCreatedAtDesc: "created_at DESC, id",
+}
+
+// IsListSort は一覧の並び順として指定できる値かを返す
+func IsListSort(sort string) bool {
+	_, ok := listSorts[sort]
+	return ok
+}
+
+// HasCreatedRange は登録日時の範囲指定があるかを返す
+func (f ListFilter) HasCreatedRange() bool {
+	return !f.CreatedFrom.IsZero() || !f.CreatedTo.IsZero()
+}
+
+// IsZero は絞り込みも並び順の指定も無い (既定の一覧) かを返す
+func (f ListFilter) IsZero() bool {
+	return f.conditions().empty() && listSorts[f.Sort] == listSorts[""]
+}
+
+// conditions は絞り込み条件を products に対する WHERE 句の条件として返す。
+// created_at はパーティションキーをそのまま比較するので MySQL が対象パーティションだけを読む
+func (f ListFilter) conditions() *conditions {
+	c := &conditions{}
+	if !f.CreatedFrom.IsZero() {
+		c.add("created_at >= ?", f.CreatedFrom)
+	}
+	if !f.CreatedTo.IsZero() {
+		c.add("created_at < ?", f.CreatedTo)
+	}
+	if f.Category != "" {
+		c.add("category = ?", f.Category)
+	}
+	if f.Brand != "" {
+		c.add("brand = ?", f.Brand)
+	}
+	if f.MinPrice != nil {
+		c.add("price >= ?", *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		c.add("price <= ?", *f.MaxPrice)
+	}
+	if len(f.StoreIDs) > 0 {
+		args := make([]interface{}, len(f.StoreIDs))
+		for i, id := range f.StoreIDs {
+			args[i] = id
+		}
+		c.add("id IN (SELECT product_id FROM store_stock WHERE store_id IN ("+placeholders(len(args))+") AND quantity > 0)", args...)
+	}
+	return c
+}
+
+// orderBy は並び順の ORDER BY 句 (ORDER BY を除く) を返す
+func (f ListFilter) orderBy() string {
+	if order, ok := listSorts[f.Sort]; ok {
+		return order
+	}
+	return listSorts[""]
+}
+
+// key はキャッシュのキーに使う、条件と並び順を一意に表す文字列
+func (f ListFilter) key() string {
+	var b strings.Builder
+	for _, t := range []time.Time{f.CreatedFrom, f.CreatedTo} {
+		if !t.IsZero() {
+			fmt.Fprint(&b, t.UnixNano())
+		}
+		b.WriteByte('|')
+	}
+	fmt.Fprintf(&b, "%v|%q|%q|", f.StoreIDs, f.Category, f.Brand)
+	for _, p := range []*float64{f.MinPrice, f.MaxPrice} {
+		if p != nil {
+			fmt.Fprint(&b, *p)
+		}
+		b.WriteByte('|')
+	}
+	b.WriteString(f.orderBy())
+	return b.String()
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordIndexHint はクエリに付与したインデックスヒントをスパンに残す
+func recordIndexHint(span trace.Span, hint string) {
+	if hint == "" {
+		return
+	}
+	span.SetAttributes(attribute.String("db.index_hint", hint))
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/database"
+	"sample-backend/internal/models"
+)
+
+// ErrNotYetExisted は指定した時点で製品がまだ無かった (または削除されていた)
+var ErrNotYetExisted = apperr.NotFound("Product did not exist at the requested time")
+
+// ProductHistoryRepository は製品の変更履歴 (product_revisions、トリガーで記録する) を読む
+type ProductHistoryRepository interface {
+	// AsOf は時刻 t の時点の製品の内容を返す。その時点で存在しなければ ErrNotYetExisted
+	AsOf(ctx context.Context, id int, t time.Time) (*models.ProductRevision, error)
+}
+
+type sqlxProductHistoryRepository struct {
+	db *sqlx.DB
+}
+
+func NewProductHistoryRepository(db *sqlx.DB) ProductHistoryRepository {
+	return &sqlxProductHistoryRepository{db: db}
+}
+
+func (r *sqlxProductHistoryRepository) AsOf(ctx context.Context, id int, t time.Time) (*models.ProductRevision, error) {
+	var rev models.ProductRevision
+	err := r.db.GetContext(ctx, &rev, `SELECT product_id AS id, name, category, brand, model, description, price, sku, created_at, deleted, valid_from
+		FROM product_revisions
+		WHERE product_id = ? AND valid_from <= ?
+		ORDER BY valid_from DESC, id DESC
+		LIMIT 1`, id, t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotYetExisted
+	}
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	if rev.Deleted {
+		return nil, ErrNotYetExisted
+	}
+	return &rev, nil
+}
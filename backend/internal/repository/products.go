@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"sample-backend/internal/database"
+	"sample-backend/internal/models"
+)
+
+// productColumns は selectProducts が前提とする列の並び
+const productColumns = "id, name, category, brand, model, description, price, sku, created_at, review_count, rating_average, stock"
+
+// searchColumns は検索対象として許可する列
+var searchColumns = map[string]bool{
+	"name":        true,
+	"category":    true,
+	"brand":       true,
+	"model":       true,
+	"description": true,
+}
+
+// summaryColumns は検索列と product_search 上の対応する列
+var summaryColumns = map[string]string{
+	"name":     "name",
+	"brand":    "brand",
+	"category": "category_name",
+}
+
+// likeEscaper は LIKE パターンで特別な意味を持つ文字をエスケープする (MySQL の既定のエスケープ文字は \)
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// IsSearchColumn は列が検索対象として許可されているかを返す
+func IsSearchColumn(column string) bool {
+	return searchColumns[column]
+}
+
+type sqlxProductRepository struct {
+	db *sqlx.DB
+}
+
+// NewProductRepository は sqlx で MySQL を読み書きする ProductRepository を返す
+func NewProductRepository(db *sqlx.DB) ProductRepository {
+	return &sqlxProductRepository{db: db}
+}
+
+func (r *sqlxProductRepository) Count(ctx context.Context, filter ListFilter) (int, error) {
+	// 既定の一覧は幅の狭い product_search で数える
+	hint := database.IndexHint("products_count")
+	query := fmt.Sprintf("SELECT COUNT(*) FROM product_search %s", hint)
+	var args []interface{}
+
+	// 絞り込む場合は products を読む (登録日時の範囲は created_at で対象パーティションだけを読む)
+	if conds := filter.conditions(); !conds.empty() {
+		hint = database.IndexHint(filterHintName(filter, "count"))
+		query = fmt.Sprintf("SELECT COUNT(*) FROM products %s %s", hint, conds.where())
+		args = conds.args
+	}
+	recordIndexHint(trace.SpanFromContext(ctx), hint)
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, args...); err != nil {
+		return 0, database.Classify(err)
+	}
+	return count, nil
+}
+
+func (r *sqlxProductRepository) List(ctx context.Context, filter ListFilter, limit, offset int) ([]models.Product, error) {
+	// OFFSET の読み飛ばしは幅の狭い product_search 上で行い、該当ページの行だけを products から引く
+	hint := database.IndexHint("products_list")
+	query := fmt.Sprintf(`SELECT p.id, p.name, p.category, p.brand, p.model, p.description, p.price, p.sku, p.created_at, p.review_count, p.rating_average, p.stock
+		FROM (SELECT id FROM product_search %s ORDER BY id LIMIT ? OFFSET ?) s
+		JOIN products p ON p.id = s.id
+		ORDER BY p.id`, hint)
+	var args []interface{}
+
+	// 絞り込み・並び替えは products 上で行う (並び順の列にはインデックスがある)
+	if !filter.IsZero() {
+		conds := filter.conditions()
+		hint = database.IndexHint(filterHintName(filter, "list"))
+		query = fmt.Sprintf("SELECT %s FROM products %s %s ORDER BY %s LIMIT ? OFFSET ?", productColumns, hint, conds.where(), filter.orderBy())
+		args = conds.args
+	}
+	recordIndexHint(trace.SpanFromContext(ctx), hint)
+
+	args = append(args, limit, offset)
+	return selectProducts(ctx, r.db, limit, query, args...)
+}
+
+func (r *sqlxProductRepository) ListAfter(ctx context.Context, filter ListFilter, afterID, limit int) ([]models.Product, error) {
+	// 主キーの範囲で読み始めるので、どのページでも読み飛ばしが発生しない
+	hint := database.IndexHint("products_list_after")
+	query := fmt.Sprintf(`SELECT p.id, p.name, p.category, p.brand, p.model, p.description, p.price, p.sku, p.created_at, p.review_count, p.rating_average, p.stock
+		FROM (SELECT id FROM product_search %s WHERE id > ? ORDER BY id LIMIT ?) s
+		JOIN products p ON p.id = s.id
+		ORDER BY p.id`, hint)
+	args := []interface{}{afterID, limit}
+
+	if !filter.IsZero() {
+		conds := filter.conditions()
+		conds.add("id > ?", afterID)
+		hint = database.IndexHint(filterHintName(filter, "list_after"))
+		query = fmt.Sprintf("SELECT %s FROM products %s %s ORDER BY id LIMIT ?", productColumns, hint, conds.where())
+		args = append(conds.args, limit)
+	}
+	recordIndexHint(trace.SpanFromContext(ctx), hint)
+
+	return selectProducts(ctx, r.db, limit, query, args...)
+}
+
+// filterHintName は絞り込み付きの一覧のインデックスヒント名を返す
+// (products_range_* は登録日時の範囲を含む場合、products_store_* は店舗の在庫で絞り込む場合、
+// products_filter_* はそれ以外の絞り込み・並び替えの場合)
+func filterHintName(filter ListFilter, kind string) string {
+	switch {
+	case filter.HasCreatedRange():
+		return "products_range_" + kind
+	case len(filter.StoreIDs) > 0:
+		return "products_store_" + kind
+	}
+	return "products_filter_" + kind
+}
+
+func (r *sqlxProductRepository) Get(ctx context.Context, id int) (*models.Product, error) {
+	products, err := selectProducts(ctx, r.db, 1, "SELECT "+productColumns+" FROM products WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(products) == 0 {
+		return nil, ErrNotFound
+	}
+	return &products[0], nil
+}
+
+func (r *sqlxProductRepository) GetMany(ctx context.Context, ids []int) ([]models.Product, error) {
+	if len(ids) == 0 {
+		return []models.Product{}, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return selectProducts(ctx, r.db, len(ids), "SELECT "+productColumns+" FROM products WHERE id IN ("+placeholders(len(ids))+")", args...)
+}
+
+func (r *sqlxProductRepository) GetBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	products, err := selectProducts(ctx, r.db, 1, "SELECT "+productColumns+" FROM products WHERE sku = ? LIMIT 1", sku)
+	if err != nil {
+		return nil, err
+	}
+	if len(products) == 0 {
+		return nil, ErrNotFound
+	}
+	return &products[0], nil
+}
+
+// searchQueries は検索に使う件数クエリと一覧クエリを組み立てる。
+// 非正規化テーブルに列があれば product_search 側で絞り込む
+func searchQueries(ctx context.Context, column string) (countQuery, listQuery string, err error) {
+	if !searchColumns[column] {
+		return "", "", ErrInvalidColumn
+	}
+
+	span := trace.SpanFromContext(ctx)
+	summaryColumn, ok := summaryColumns[column]
+	if span.IsRecording() {
+		span.SetAttributes(attribute.Bool("search.summary_table", ok))
+	}
+
+	if ok {
+		countHint := database.IndexHint("search_summary_count")
+		listHint := database.IndexHint("search_summary_list")
+		countQuery = fmt.Sprintf("SELECT COUNT(*) FROM product_search %s WHERE %s LIKE ?", countHint, summaryColumn)
+		listQuery = fmt.Sprintf(`SELECT p.id, p.name, p.category, p.brand, p.model, p.description, p.price, p.sku, p.created_at, p.review_count, p.rating_average, p.stock
+			FROM (SELECT id FROM product_search %s WHERE %s LIKE ? ORDER BY id LIMIT ? OFFSET ?) s
+			JOIN products p ON p.id = s.id
+			ORDER BY p.id`, listHint, summaryColumn)
+		return countQuery, listQuery, nil
+	}
+
+	countHint := database.IndexHint("search_count")
+	listHint := database.IndexHint("search_list")
+	countQuery = fmt.Sprintf("SELECT COUNT(*) FROM products %s WHERE %s LIKE ?", countHint, column)
+	listQuery = fmt.Sprintf("SELECT %s FROM products %s WHERE %s LIKE ? ORDER BY id LIMIT ? OFFSET ?", productColumns, listHint, column)
+	return countQuery, listQuery, nil
+}
+
+// searchTerm はワイルドカード文字をエスケープして文字どおりに一致させる部分一致パターンを返す
+func searchTerm(keyword string) string {
+	return "%" + escapeLike(keyword) + "%"
+}
+
+func (r *sqlxProductRepository) SearchCount(ctx context.Context, q SearchQuery) (int, error) {
+	countQuery, _, err := searchQueries(ctx, q.Column)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, countQuery, searchTerm(q.Keyword)); err != nil {
+		return 0, database.Classify(err)
+	}
+	return count, nil
+}
+
+func (r *sqlxProductRepository) Search(ctx context.Context, q SearchQuery, limit, offset int) ([]models.Product, error) {
+	_, listQuery, err := searchQueries(ctx, q.Column)
+	if err != nil {
+		return nil, err
+	}
+	return selectProducts(ctx, r.db, limit, listQuery, searchTerm(q.Keyword), limit, offset)
+}
+
+// minFullTextLength は FULLTEXT で検索できる最短のキーワードの文字数 (ngram_token_size の既定値)。
+// これより短いキーワードは ngram に分割できず一致しないため LIKE で検索する
+const minFullTextLength = 2
+
+// fullTextQueries は全文検索の件数クエリと一覧クエリを組み立てる。一覧は関連度の高い順に並べる
+func fullTextQueries(keyword string) (countQuery, listQuery string, countArgs, listArgs []interface{}) {
+	if utf8.RuneCountInString(keyword) < minFullTextLength {
+		// LIKE では関連度を計算できないため、製品名に含むものを先にする
+		term := searchTerm(keyword)
+		countQuery = "SELECT COUNT(*) FROM product_search WHERE search_text LIKE ?"
+		listQuery = `SELECT p.id, p.name, p.category, p.brand, p.model, p.description, p.price, p.sku, p.created_at, p.review_count, p.rating_average, p.stock
+			FROM (SELECT id, name LIKE ? AS in_name FROM product_search WHERE search_text LIKE ? ORDER BY in_name DESC, id LIMIT ? OFFSET ?) s
+			JOIN products p ON p.id = s.id
+			ORDER BY s.in_name DESC, s.id`
+		return countQuery, listQuery, []interface{}{term}, []interface{}{term, term}
+	}
+
+	countQuery = "SELECT COUNT(*) FROM product_search WHERE MATCH(search_text) AGAINST (? IN NATURAL LANGUAGE MODE)"
+	listQuery = `SELECT p.id, p.name, p.category, p.brand, p.model, p.description, p.price, p.sku, p.created_at, p.review_count, p.rating_average, p.stock
+		FROM (SELECT id, MATCH(search_text) AGAINST (? IN NATURAL LANGUAGE MODE) AS score
+			FROM product_search
+			WHERE MATCH(search_text) AGAINST (? IN NATURAL LANGUAGE MODE)
+			ORDER BY score DESC, id LIMIT ? OFFSET ?) s
+		JOIN products p ON p.id = s.id
+		ORDER BY s.score DESC, s.id`
+	return countQuery, listQuery, []interface{}{keyword}, []interface{}{keyword, keyword}
+}
+
+func (r *sqlxProductRepository) FullTextCount(ctx context.Context, keyword string) (int, error) {
+	countQuery, _, args, _ := fullTextQueries(keyword)
+	var count int
+	if err := r.db.GetContext(ctx, &count, countQuery, args...); err != nil {
+		return 0, database.Classify(err)
+	}
+	return count, nil
+}
+
+func (r *sqlxProductRepository) FullText(ctx context.Context, keyword string, limit, offset int) ([]models.Product, error) {
+	_, listQuery, _, args := fullTextQueries(keyword)
+	return selectProducts(ctx, r.db, limit, listQuery, append(args, limit, offset)...)
+}
+
+func (r *sqlxProductRepository) Create(ctx context.Context, p *models.Product) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return database.Classify(err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO products (name, category, brand, model, description, price, sku) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		p.Name, p.Category, p.Brand, p.Model, p.Description, p.Price, p.SKU)
+	if err != nil {
+		return database.Classify(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	p.ID = int(id)
+
+	// created_at は DB の既定値で決まるので読み直す
+	if err := tx.GetContext(ctx, &p.CreatedAt, "SELECT created_at FROM products WHERE id = ?", p.ID); err != nil {
+		return database.Classify(err)
+	}
+	return database.Classify(tx.Commit())
+}
+
+func (r *sqlxProductRepository) CreateBatch(ctx context.Context, products []models.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	// 1 行ずつ送ると往復が行数分かかるため、複数行の INSERT 1 回にまとめる
+	args := make([]interface{}, 0, len(products)*7)
+	rows := make([]string, len(products))
+	for i, p := range products {
+		rows[i] = "(?, ?, ?, ?, ?, ?, ?)"
+		args = append(args, p.Name, p.Category, p.Brand, p.Model, p.Description, p.Price, p.SKU)
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return database.Classify(err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO products (name, category, brand, model, description, price, sku) VALUES "+strings.Join(rows, ", "),
+		args...); err != nil {
+		return database.Classify(err)
+	}
+	return database.Classify(tx.Commit())
+}
+
+func (r *sqlxProductRepository) Update(ctx context.Context, p *models.Product) (*models.Product, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	defer tx.Rollback()
+
+	// 変更前の内容を返すため、行をロックして読んでから書き換える
+	var old models.Product
+	err = tx.GetContext(ctx, &old, "SELECT "+productColumns+" FROM products WHERE id = ? FOR UPDATE", p.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE products SET name = ?, category = ?, brand = ?, model = ?, description = ?, price = ?, sku = ? WHERE id = ?",
+		p.Name, p.Category, p.Brand, p.Model, p.Description, p.Price, p.SKU, p.ID)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, database.Classify(err)
+	}
+	// 書き換えない列は変更前の値のまま (行をロックしていたので、コミットの時点でも同じ値)
+	p.CreatedAt = old.CreatedAt
+	p.ReviewCount, p.RatingAverage, p.Stock = old.ReviewCount, old.RatingAverage, old.Stock
+	return &old, nil
+}
+
+func (r *sqlxProductRepository) Delete(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return database.Classify(err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM products WHERE id = ?", id)
+	if err != nil {
+		return database.Classify(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	// 製品に付随するデータも合わせて消す (閲覧履歴や Q&A などの記録は残す)
+	for _, query := range []string{
+		"DELETE FROM store_stock WHERE product_id = ?",
+		"DELETE FROM product_supplier_info WHERE product_id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return database.Classify(err)
+		}
+	}
+	return database.Classify(tx.Commit())
+}
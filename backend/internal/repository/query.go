@@ -0,0 +1,32 @@
+package repository
+
+import "strings"
+
+// conditions は WHERE 句を組み立てる。条件の SQL には固定の文字列だけを渡し、値は必ずプレースホルダーで渡す
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+// add は条件を AND で追加する
+func (c *conditions) add(clause string, args ...interface{}) {
+	c.clauses = append(c.clauses, clause)
+	c.args = append(c.args, args...)
+}
+
+func (c *conditions) empty() bool {
+	return len(c.clauses) == 0
+}
+
+// where は "WHERE ..." を返す。条件が無ければ空文字
+func (c *conditions) where() string {
+	if c.empty() {
+		return ""
+	}
+	return "WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+// placeholders は n 個の ? をカンマで区切って返す
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
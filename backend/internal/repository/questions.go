@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/database"
+	"sample-backend/internal/models"
+)
+
+// Q&A のリポジトリが返すエラー
+var (
+	ErrQuestionNotFound = apperr.NotFound("Question not found")
+	ErrAnswerNotFound   = apperr.NotFound("Answer not found")
+)
+
+// 「役に立った」の投票先
+const (
+	VoteQuestion = "question"
+	VoteAnswer   = "answer"
+)
+
+// QuestionRepository は製品の質問と回答 (product_questions / product_answers) を読み書きする
+type QuestionRepository interface {
+	// CountQuestions は状態が status の質問数を返す。productID が 0 なら全製品を数える
+	CountQuestions(ctx context.Context, productID int, status string) (int, error)
+	// ListQuestions は状態が status の質問を「役に立った」の多い順、新しい順に返す。productID が 0 なら全製品が対象
+	ListQuestions(ctx context.Context, productID int, status string, limit, offset int) ([]models.Question, error)
+	// GetQuestion は質問を返す。存在しなければ ErrQuestionNotFound
+	GetQuestion(ctx context.Context, id int) (*models.Question, error)
+	// CreateQuestion は質問を登録し、採番された ID と登録日時を q に設定する
+	CreateQuestion(ctx context.Context, q *models.Question) error
+	// SetQuestionStatus は質問の公開状態を変える。存在しなければ ErrQuestionNotFound
+	SetQuestionStatus(ctx context.Context, id int, status string) error
+	// AnswersFor は質問ごとの回答を古い順に返す
+	AnswersFor(ctx context.Context, questionIDs []int) (map[int][]models.Answer, error)
+	// CreateAnswer は回答を登録し、採番された ID と登録日時を a に設定する
+	CreateAnswer(ctx context.Context, a *models.Answer) error
+	// DeleteAnswer は回答を削除する。存在しなければ ErrAnswerNotFound
+	DeleteAnswer(ctx context.Context, id int) error
+	// Vote は公開中の質問・回答に「役に立った」を 1 票加える。同じ閲覧者の 2 票目は数えず false を返す
+	Vote(ctx context.Context, target string, id int, visitorID string) (bool, error)
+}
+
+const (
+	questionColumns = "id, product_id, author, body, status, helpful_count, created_at"
+	answerColumns   = "id, question_id, author, role, body, helpful_count, created_at"
+)
+
+// voteUpdates は投票先ごとの件数の更新。公開中の質問 (とその回答) だけを対象にする
+var voteUpdates = map[string]string{
+	VoteQuestion: "UPDATE product_questions SET helpful_count = helpful_count + 1 WHERE id = ? AND status = 'approved'",
+	VoteAnswer: `UPDATE product_answers a JOIN product_questions q ON q.id = a.question_id
+		SET a.helpful_count = a.helpful_count + 1
+		WHERE a.id = ? AND q.status = 'approved'`,
+}
+
+type sqlxQuestionRepository struct {
+	db *sqlx.DB
+}
+
+func NewQuestionRepository(db *sqlx.DB) QuestionRepository {
+	return &sqlxQuestionRepository{db: db}
+}
+
+// questionFilter は製品と状態の絞り込み条件を返す
+func questionFilter(productID int, status string) (string, []interface{}) {
+	if productID == 0 {
+		return "WHERE status = ?", []interface{}{status}
+	}
+	return "WHERE product_id = ? AND status = ?", []interface{}{productID, status}
+}
+
+func (r *sqlxQuestionRepository) CountQuestions(ctx context.Context, productID int, status string) (int, error) {
+	where, args := questionFilter(productID, status)
+	var n int
+	if err := r.db.GetContext(ctx, &n, "SELECT COUNT(*) FROM product_questions "+where, args...); err != nil {
+		return 0, database.Classify(err)
+	}
+	return n, nil
+}
+
+func (r *sqlxQuestionRepository) ListQuestions(ctx context.Context, productID int, status string, limit, offset int) ([]models.Question, error) {
+	where, args := questionFilter(productID, status)
+	questions := make([]models.Question, 0, limit)
+	err := r.db.SelectContext(ctx, &questions,
+		"SELECT "+questionColumns+" FROM product_questions "+where+" ORDER BY helpful_count DESC, id DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	return questions, nil
+}
+
+func (r *sqlxQuestionRepository) GetQuestion(ctx context.Context, id int) (*models.Question, error) {
+	var q models.Question
+	err := r.db.GetContext(ctx, &q, "SELECT "+questionColumns+" FROM product_questions WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrQuestionNotFound
+	}
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	return &q, nil
+}
+
+func (r *sqlxQuestionRepository) CreateQuestion(ctx context.Context, q *models.Question) error {
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO product_questions (product_id, author, body, status) VALUES (?, ?, ?, ?)",
+		q.ProductID, q.Author, q.Body, q.Status)
+	if err != nil {
+		return database.Classify(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	q.ID = int(id)
+	return database.Classify(r.db.GetContext(ctx, &q.CreatedAt, "SELECT created_at FROM product_questions WHERE id = ?", q.ID))
+}
+
+func (r *sqlxQuestionRepository) SetQuestionStatus(ctx context.Context, id int, status string) error {
+	res, err := r.db.ExecContext(ctx,
+		"UPDATE product_questions SET status = ?, moderated_at = CURRENT_TIMESTAMP WHERE id = ?", status, id)
+	if err != nil {
+		return database.Classify(err)
+	}
+	// 同じ状態への変更も成功として扱う (MySQL は値が変わらない行を影響行数に数えない)
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.GetQuestion(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *sqlxQuestionRepository) AnswersFor(ctx context.Context, questionIDs []int) (map[int][]models.Answer, error) {
+	byQuestion := make(map[int][]models.Answer, len(questionIDs))
+	if len(questionIDs) == 0 {
+		return byQuestion, nil
+	}
+
+	query := "SELECT " + answerColumns + " FROM product_answers WHERE question_id IN (" +
+		strings.TrimSuffix(strings.Repeat("?,", len(questionIDs)), ",") + ") ORDER BY question_id, id"
+	args := make([]interface{}, len(questionIDs))
+	for i, id := range questionIDs {
+		args[i] = id
+	}
+	var answers []models.Answer
+	if err := r.db.SelectContext(ctx, &answers, query, args...); err != nil {
+		return nil, database.Classify(err)
+	}
+	for _, a := range answers {
+		byQuestion[a.QuestionID] = append(byQuestion[a.QuestionID], a)
+	}
+	return byQuestion, nil
+}
+
+func (r *sqlxQuestionRepository) CreateAnswer(ctx context.Context, a *models.Answer) error {
+	res, err := r.db.ExecContext(ctx,
+		"INSERT INTO product_answers (question_id, author, role, body) VALUES (?, ?, ?, ?)",
+		a.QuestionID, a.Author, a.Role, a.Body)
+	if err != nil {
+		return database.Classify(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	a.ID = int(id)
+	return database.Classify(r.db.GetContext(ctx, &a.CreatedAt, "SELECT created_at FROM product_answers WHERE id = ?", a.ID))
+}
+
+func (r *sqlxQuestionRepository) DeleteAnswer(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM product_answers WHERE id = ?", id)
+	if err != nil {
+		return database.Classify(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAnswerNotFound
+	}
+	return nil
+}
+
+func (r *sqlxQuestionRepository) Vote(ctx context.Context, target string, id int, visitorID string) (bool, error) {
+	update, ok := voteUpdates[target]
+	if !ok {
+		return false, apperr.Validation("Invalid vote target")
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, database.Classify(err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "INSERT IGNORE INTO product_qa_votes (target, target_id, visitor_id) VALUES (?, ?, ?)", target, id, visitorID)
+	if err != nil {
+		return false, database.Classify(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	res, err = tx.ExecContext(ctx, update, id)
+	if err != nil {
+		return false, database.Classify(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if target == VoteAnswer {
+			return false, ErrAnswerNotFound
+		}
+		return false, ErrQuestionNotFound
+	}
+	return true, database.Classify(tx.Commit())
+}
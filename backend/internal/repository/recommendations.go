@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/database"
+	"sample-backend/internal/models"
+)
+
+// RecommendationRepository は事前計算したおすすめ (product_recommendations) を読む
+type RecommendationRepository interface {
+	// ForProduct は製品のおすすめをスコアの高い順に返す。まだ計算されていなければ空
+	ForProduct(ctx context.Context, productID, limit int) ([]models.Recommendation, error)
+}
+
+type sqlxRecommendationRepository struct {
+	db *sqlx.DB
+}
+
+func NewRecommendationRepository(db *sqlx.DB) RecommendationRepository {
+	return &sqlxRecommendationRepository{db: db}
+}
+
+func (r *sqlxRecommendationRepository) ForProduct(ctx context.Context, productID, limit int) ([]models.Recommendation, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT p.id, p.name, p.category, p.brand, p.model, p.description, p.price, p.sku, p.created_at, p.review_count, p.rating_average, p.stock, r.score
+		FROM product_recommendations r
+		JOIN products p ON p.id = r.recommended_id
+		WHERE r.product_id = ?
+		ORDER BY r.score DESC, r.recommended_id
+		LIMIT ?`, productID, limit)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	defer rows.Close()
+
+	recs := make([]models.Recommendation, 0, limit)
+	var rec models.Recommendation
+	p := &rec.Product
+	dest := []interface{}{&p.ID, &p.Name, &p.Category, &p.Brand, &p.Model, &p.Description, &p.Price, &p.SKU, &p.CreatedAt, &p.ReviewCount, &p.RatingAverage, &p.Stock, &rec.Score}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, database.Classify(err)
+	}
+	return recs, nil
+}
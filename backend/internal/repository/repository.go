@@ -0,0 +1,68 @@
+// Package repository は製品データへのアクセスをまとめる。
+// ハンドラーは ProductRepository だけに依存し、SQL とインデックスヒントの扱いはここに閉じ込める
+package repository
+
+import (
+	"context"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+)
+
+// リポジトリが返すエラー。errors.Is で apperr の分類にも一致する
+var (
+	// ErrNotFound は指定した製品が存在しない (apperr.ErrNotFound)
+	ErrNotFound = apperr.NotFound("Product not found")
+	// ErrInvalidColumn は検索対象として許可されていない列が指定された (apperr.ErrValidation)
+	ErrInvalidColumn = apperr.Validation("Invalid search column")
+)
+
+// CountBy で数えられる列
+const (
+	FacetCategory = "category"
+	FacetBrand    = "brand"
+)
+
+// SearchQuery は列を指定したキーワード検索の条件。Keyword は LIKE の部分一致として扱う
+type SearchQuery struct {
+	Column  string
+	Keyword string
+}
+
+// ProductRepository は製品の読み書きを行う
+type ProductRepository interface {
+	// Count は絞り込み条件に一致する製品数を返す
+	Count(ctx context.Context, filter ListFilter) (int, error)
+	// List は絞り込み条件に一致する製品を ID 順に返す
+	List(ctx context.Context, filter ListFilter, limit, offset int) ([]models.Product, error)
+	// ListAfter は絞り込み条件に一致し、ID が afterID より大きい製品を ID 順に最大 limit 件返す (カーソル方式)
+	ListAfter(ctx context.Context, filter ListFilter, afterID, limit int) ([]models.Product, error)
+	// CountBy は絞り込み条件に一致する製品を column (FacetCategory / FacetBrand) の値ごとに数え、多い順に最大 limit 件返す
+	CountBy(ctx context.Context, filter ListFilter, column string, limit int) ([]models.FacetCount, error)
+	// CountByPrice は絞り込み条件に一致する製品を bounds (昇順) で区切った価格帯ごとに数える。
+	// 価格帯は len(bounds)+1 個で、先頭は 0 から bounds[0] 未満、末尾は bounds の最後の値以上
+	CountByPrice(ctx context.Context, filter ListFilter, bounds []float64) ([]models.PriceBucket, error)
+	// Get は ID を指定して製品を返す。存在しなければ ErrNotFound
+	Get(ctx context.Context, id int) (*models.Product, error)
+	// GetMany は ids の製品をまとめて返す。並びは ids の順とは限らず、存在しない ID は結果に含めない
+	GetMany(ctx context.Context, ids []int) ([]models.Product, error)
+	// GetBySKU は SKU を指定して製品を返す。存在しなければ ErrNotFound
+	GetBySKU(ctx context.Context, sku string) (*models.Product, error)
+	// SearchCount は検索条件に一致する製品数を返す
+	SearchCount(ctx context.Context, q SearchQuery) (int, error)
+	// Search は検索条件に一致する製品を ID 順に返す
+	Search(ctx context.Context, q SearchQuery, limit, offset int) ([]models.Product, error)
+	// FullTextCount は全文検索に一致する製品数を返す
+	FullTextCount(ctx context.Context, keyword string) (int, error)
+	// FullText は製品名・カテゴリ・ブランド・型番・説明を対象に全文検索し、関連度の高い順に返す
+	FullText(ctx context.Context, keyword string, limit, offset int) ([]models.Product, error)
+	// Create は製品を登録し、採番された ID と登録日時を p に設定する
+	Create(ctx context.Context, p *models.Product) error
+	// CreateBatch は products を 1 つのトランザクションでまとめて登録する。1 件でも失敗すればどれも登録しない
+	CreateBatch(ctx context.Context, products []models.Product) error
+	// Update は p.ID の製品を p の内容に書き換え、変更前の内容を返す。存在しなければ ErrNotFound。
+	// 書き換えない列 (created_at・レビューの集計・在庫) は p に変更前の値を設定する
+	Update(ctx context.Context, p *models.Product) (*models.Product, error)
+	// Delete は製品を削除する。存在しなければ ErrNotFound
+	Delete(ctx context.Context, id int) error
+}
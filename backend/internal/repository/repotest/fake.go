@@ -0,0 +1,465 @@
+// Package repotest は DB を使わずにサービスやハンドラーを確かめるための ProductRepository の偽物を提供する
+package repotest
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+)
+
+// Fake はメモリ上の製品を読み書きする ProductRepository。絞り込み・並び順・検索は
+// sqlx の実装と同じ結果になるよう素朴に実装する (インデックスヒントや全文索引の関連度は模さない)。
+// 複数のゴルーチンから呼んでよい
+type Fake struct {
+	mu       sync.Mutex
+	products map[int]models.Product
+	nextID   int
+	// 店舗 ID → 在庫がある製品 ID (ListFilter.StoreIDs の絞り込みに使う)
+	storeStock map[int]map[int]bool
+	// err が nil でなければ、すべてのメソッドがこのエラーを返す (DB の障害を模す)
+	err   error
+	calls map[string]int
+	// Now は Create で設定する登録日時 (nil なら time.Now)
+	Now func() time.Time
+}
+
+var _ repository.ProductRepository = (*Fake)(nil)
+
+// NewFake は products を登録済みの Fake を返す。ID が 0 の製品には ID を振る
+func NewFake(products ...models.Product) *Fake {
+	f := &Fake{products: map[int]models.Product{}, storeStock: map[int]map[int]bool{}, calls: map[string]int{}}
+	for _, p := range products {
+		if p.ID == 0 {
+			p.ID = f.nextID + 1
+		}
+		f.products[p.ID] = p
+		f.nextID = max(f.nextID, p.ID)
+	}
+	return f
+}
+
+// SetError は以降のすべての呼び出しが err を返すようにする。nil で元に戻す
+func (f *Fake) SetError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// SetStoreStock は店舗に在庫がある製品を設定する
+func (f *Fake) SetStoreStock(storeID int, productIDs ...int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := map[int]bool{}
+	for _, id := range productIDs {
+		ids[id] = true
+	}
+	f.storeStock[storeID] = ids
+}
+
+// Calls はメソッドが呼ばれた回数を返す (キャッシュが DB を読まずに済んだかの確認などに使う)
+func (f *Fake) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// Products は登録されている製品を ID 順に返す
+func (f *Fake) Products() []models.Product {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sorted("")
+}
+
+// call は呼び出しを数え、返すべきエラーを返す。f.mu を持って呼ぶこと
+func (f *Fake) call(ctx context.Context, method string) error {
+	f.calls[method]++
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.err
+}
+
+// sorted は製品を並び順 (repository.Sort*) のとおりに並べて返す。f.mu を持って呼ぶこと
+func (f *Fake) sorted(order string) []models.Product {
+	out := make([]models.Product, 0, len(f.products))
+	for _, p := range f.products {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		switch order {
+		case repository.SortPriceAsc:
+			if a.Price != b.Price {
+				return a.Price < b.Price
+			}
+		case repository.SortPriceDesc:
+			if a.Price != b.Price {
+				return a.Price > b.Price
+			}
+		case repository.SortCreatedAtAsc:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+		case repository.SortCreatedAtDesc:
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.After(b.CreatedAt)
+			}
+		}
+		return a.ID < b.ID
+	})
+	return out
+}
+
+// matches は製品が絞り込み条件に一致するかを返す。f.mu を持って呼ぶこと
+func (f *Fake) matches(p models.Product, filter repository.ListFilter) bool {
+	switch {
+	case !filter.CreatedFrom.IsZero() && p.CreatedAt.Before(filter.CreatedFrom):
+		return false
+	case !filter.CreatedTo.IsZero() && !p.CreatedAt.Before(filter.CreatedTo):
+		return false
+	case filter.Category != "" && p.Category != filter.Category:
+		return false
+	case filter.Brand != "" && p.Brand != filter.Brand:
+		return false
+	case filter.MinPrice != nil && p.Price < *filter.MinPrice:
+		return false
+	case filter.MaxPrice != nil && p.Price > *filter.MaxPrice:
+		return false
+	}
+	if len(filter.StoreIDs) > 0 {
+		for _, storeID := range filter.StoreIDs {
+			if f.storeStock[storeID][p.ID] {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// filtered は絞り込み条件に一致する製品を並び順のとおりに返す。f.mu を持って呼ぶこと
+func (f *Fake) filtered(filter repository.ListFilter) []models.Product {
+	var out []models.Product
+	for _, p := range f.sorted(filter.Sort) {
+		if f.matches(p, filter) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// window は products の offset から最大 limit 件を空でないスライスとして返す
+func window(products []models.Product, limit, offset int) []models.Product {
+	out := []models.Product{}
+	if offset < len(products) {
+		out = append(out, products[offset:min(len(products), offset+limit)]...)
+	}
+	return out
+}
+
+func (f *Fake) Count(ctx context.Context, filter repository.ListFilter) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "Count"); err != nil {
+		return 0, err
+	}
+	return len(f.filtered(filter)), nil
+}
+
+func (f *Fake) List(ctx context.Context, filter repository.ListFilter, limit, offset int) ([]models.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "List"); err != nil {
+		return nil, err
+	}
+	return window(f.filtered(filter), limit, offset), nil
+}
+
+func (f *Fake) ListAfter(ctx context.Context, filter repository.ListFilter, afterID, limit int) ([]models.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "ListAfter"); err != nil {
+		return nil, err
+	}
+	filter.Sort = ""
+	var after []models.Product
+	for _, p := range f.filtered(filter) {
+		if p.ID > afterID {
+			after = append(after, p)
+		}
+	}
+	return window(after, limit, 0), nil
+}
+
+func (f *Fake) CountBy(ctx context.Context, filter repository.ListFilter, column string, limit int) ([]models.FacetCount, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "CountBy"); err != nil {
+		return nil, err
+	}
+	if column != repository.FacetCategory && column != repository.FacetBrand {
+		return nil, repository.ErrInvalidColumn
+	}
+	counts := map[string]int{}
+	for _, p := range f.filtered(filter) {
+		if column == repository.FacetCategory {
+			counts[p.Category]++
+		} else {
+			counts[p.Brand]++
+		}
+	}
+	out := make([]models.FacetCount, 0, len(counts))
+	for value, n := range counts {
+		out = append(out, models.FacetCount{Value: value, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out[:min(len(out), limit)], nil
+}
+
+func (f *Fake) CountByPrice(ctx context.Context, filter repository.ListFilter, bounds []float64) ([]models.PriceBucket, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "CountByPrice"); err != nil {
+		return nil, err
+	}
+	buckets := repository.PriceBuckets(bounds)
+	for _, p := range f.filtered(filter) {
+		// MySQL の INTERVAL と同じく、p.Price 以下の境界の数が価格帯の位置になる
+		i := sort.Search(len(bounds), func(i int) bool { return bounds[i] > p.Price })
+		buckets[i].Count++
+	}
+	return buckets, nil
+}
+
+func (f *Fake) Get(ctx context.Context, id int) (*models.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "Get"); err != nil {
+		return nil, err
+	}
+	p, ok := f.products[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &p, nil
+}
+
+func (f *Fake) GetMany(ctx context.Context, ids []int) ([]models.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "GetMany"); err != nil {
+		return nil, err
+	}
+	out := []models.Product{}
+	for _, id := range ids {
+		if p, ok := f.products[id]; ok {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (f *Fake) GetBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "GetBySKU"); err != nil {
+		return nil, err
+	}
+	for _, p := range f.sorted("") {
+		if sku != "" && p.SKU == sku {
+			return &p, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// column は検索対象の列の値を返す
+func column(p models.Product, name string) string {
+	switch name {
+	case "name":
+		return p.Name
+	case "category":
+		return p.Category
+	case "brand":
+		return p.Brand
+	case "model":
+		return p.Model
+	case "description":
+		return p.Description
+	}
+	return ""
+}
+
+// search は検索条件に一致する製品を ID 順に返す。f.mu を持って呼ぶこと
+func (f *Fake) search(q repository.SearchQuery) ([]models.Product, error) {
+	if !repository.IsSearchColumn(q.Column) {
+		return nil, repository.ErrInvalidColumn
+	}
+	var out []models.Product
+	for _, p := range f.sorted("") {
+		if strings.Contains(column(p, q.Column), q.Keyword) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (f *Fake) SearchCount(ctx context.Context, q repository.SearchQuery) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "SearchCount"); err != nil {
+		return 0, err
+	}
+	products, err := f.search(q)
+	return len(products), err
+}
+
+func (f *Fake) Search(ctx context.Context, q repository.SearchQuery, limit, offset int) ([]models.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "Search"); err != nil {
+		return nil, err
+	}
+	products, err := f.search(q)
+	if err != nil {
+		return nil, err
+	}
+	return window(products, limit, offset), nil
+}
+
+// fullText は全文検索の代わりにいずれかの列がキーワードを含む製品を返す。
+// 関連度の代わりに製品名に含むものを先にする (短いキーワードの LIKE 検索と同じ並び)。f.mu を持って呼ぶこと
+func (f *Fake) fullText(keyword string) []models.Product {
+	var inName, others []models.Product
+	for _, p := range f.sorted("") {
+		switch {
+		case strings.Contains(p.Name, keyword):
+			inName = append(inName, p)
+		case strings.Contains(p.Category, keyword), strings.Contains(p.Brand, keyword),
+			strings.Contains(p.Model, keyword), strings.Contains(p.Description, keyword):
+			others = append(others, p)
+		}
+	}
+	return append(inName, others...)
+}
+
+func (f *Fake) FullTextCount(ctx context.Context, keyword string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "FullTextCount"); err != nil {
+		return 0, err
+	}
+	return len(f.fullText(keyword)), nil
+}
+
+func (f *Fake) FullText(ctx context.Context, keyword string, limit, offset int) ([]models.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "FullText"); err != nil {
+		return nil, err
+	}
+	return window(f.fullText(keyword), limit, offset), nil
+}
+
+// skuTaken は SKU が他の製品で使われているかを返す (一意制約の代わり)。f.mu を持って呼ぶこと
+func (f *Fake) skuTaken(sku string, exceptID int) bool {
+	if sku == "" {
+		return false
+	}
+	for id, p := range f.products {
+		if id != exceptID && p.SKU == sku {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *Fake) now() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+	return time.Now()
+}
+
+func (f *Fake) Create(ctx context.Context, p *models.Product) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "Create"); err != nil {
+		return err
+	}
+	if f.skuTaken(p.SKU, 0) {
+		return apperr.Conflict("Already exists", nil)
+	}
+	f.nextID++
+	p.ID = f.nextID
+	p.CreatedAt = f.now()
+	f.products[p.ID] = *p
+	return nil
+}
+
+func (f *Fake) CreateBatch(ctx context.Context, products []models.Product) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "CreateBatch"); err != nil {
+		return err
+	}
+	// 1 件でも SKU が重複すればどれも登録しない
+	seen := map[string]bool{}
+	for _, p := range products {
+		if f.skuTaken(p.SKU, 0) || (p.SKU != "" && seen[p.SKU]) {
+			return apperr.Conflict("Already exists", nil)
+		}
+		seen[p.SKU] = true
+	}
+	for _, p := range products {
+		f.nextID++
+		p.ID = f.nextID
+		p.CreatedAt = f.now()
+		f.products[p.ID] = p
+	}
+	return nil
+}
+
+func (f *Fake) Update(ctx context.Context, p *models.Product) (*models.Product, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "Update"); err != nil {
+		return nil, err
+	}
+	old, ok := f.products[p.ID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	if f.skuTaken(p.SKU, p.ID) {
+		return nil, apperr.Conflict("Already exists", nil)
+	}
+	// sqlx の実装と同じく、書き換えない列は変更前の値にする
+	p.CreatedAt = old.CreatedAt
+	p.ReviewCount, p.RatingAverage, p.Stock = old.ReviewCount, old.RatingAverage, old.Stock
+	f.products[p.ID] = *p
+	return &old, nil
+}
+
+func (f *Fake) Delete(ctx context.Context, id int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "Delete"); err != nil {
+		return err
+	}
+	if _, ok := f.products[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(f.products, id)
+	return nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/database"
+	"sample-backend/internal/models"
+)
+
+// ReviewRepository は製品のレビュー (product_reviews) を読み書きする
+type ReviewRepository interface {
+	// Summary は製品に集計したレビューの件数と平均を返す。製品が存在しなければ ErrNotFound
+	Summary(ctx context.Context, productID int) (count int, average float64, err error)
+	// CountReviews は製品のレビュー数を返す
+	CountReviews(ctx context.Context, productID int) (int, error)
+	// ListReviews は製品のレビューを新しい順に返す
+	ListReviews(ctx context.Context, productID int, limit, offset int) ([]models.Review, error)
+	// CreateReview はレビューを登録し、同じトランザクションで製品の件数と平均を更新する。
+	// 採番された ID と登録日時を rv に設定する。製品が存在しなければ ErrNotFound
+	CreateReview(ctx context.Context, rv *models.Review) error
+}
+
+const reviewColumns = "id, product_id, author, rating, title, body, created_at"
+
+type sqlxReviewRepository struct {
+	db *sqlx.DB
+}
+
+func NewReviewRepository(db *sqlx.DB) ReviewRepository {
+	return &sqlxReviewRepository{db: db}
+}
+
+func (r *sqlxReviewRepository) Summary(ctx context.Context, productID int) (int, float64, error) {
+	var row struct {
+		Count   int     `db:"review_count"`
+		Average float64 `db:"rating_average"`
+	}
+	err := r.db.GetContext(ctx, &row, "SELECT review_count, rating_average FROM products WHERE id = ?", productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, 0, database.Classify(err)
+	}
+	return row.Count, row.Average, nil
+}
+
+func (r *sqlxReviewRepository) CountReviews(ctx context.Context, productID int) (int, error) {
+	var n int
+	if err := r.db.GetContext(ctx, &n, "SELECT COUNT(*) FROM product_reviews WHERE product_id = ?", productID); err != nil {
+		return 0, database.Classify(err)
+	}
+	return n, nil
+}
+
+func (r *sqlxReviewRepository) ListReviews(ctx context.Context, productID int, limit, offset int) ([]models.Review, error) {
+	reviews := make([]models.Review, 0, limit)
+	err := r.db.SelectContext(ctx, &reviews,
+		"SELECT "+reviewColumns+" FROM product_reviews WHERE product_id = ? ORDER BY id DESC LIMIT ? OFFSET ?",
+		productID, limit, offset)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	return reviews, nil
+}
+
+func (r *sqlxReviewRepository) CreateReview(ctx context.Context, rv *models.Review) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return database.Classify(err)
+	}
+	defer tx.Rollback()
+
+	// 先に製品の行を更新して行ロックを取り、同時の投稿で集計がずれないようにする。
+	// MySQL は SET を左から順に評価するので、平均には更新後の件数と合計が使われる
+	res, err := tx.ExecContext(ctx,
+		`UPDATE products
+		SET review_count = review_count + 1,
+			rating_total = rating_total + ?,
+			rating_average = ROUND(rating_total / review_count, 2)
+		WHERE id = ?`,
+		rv.Rating, rv.ProductID)
+	if err != nil {
+		return database.Classify(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	res, err = tx.ExecContext(ctx,
+		"INSERT INTO product_reviews (product_id, author, rating, title, body) VALUES (?, ?, ?, ?, ?)",
+		rv.ProductID, rv.Author, rv.Rating, rv.Title, rv.Body)
+	if err != nil {
+		return database.Classify(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	rv.ID = int(id)
+	if err := tx.GetContext(ctx, &rv.CreatedAt, "SELECT created_at FROM product_reviews WHERE id = ?", rv.ID); err != nil {
+		return database.Classify(err)
+	}
+	return database.Classify(tx.Commit())
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/database"
+	"sample-backend/internal/models"
+)
+
+// ErrSaleNotFound は指定したタイムセールが存在しない
+var ErrSaleNotFound = apperr.NotFound("Sale not found")
+
+// SaleRepository はタイムセール (flash_sales / flash_sale_items) を読み書きする
+type SaleRepository interface {
+	// Window は until までに始まり、from より後に終わるセールを対象製品付きで返す
+	Window(ctx context.Context, from, until time.Time) ([]models.Sale, error)
+	// Recent は新しく登録した順にセールを返す (管理用)
+	Recent(ctx context.Context, limit int) ([]models.Sale, error)
+	// Create はセールと対象製品を登録し、採番された ID を sale に設定する
+	Create(ctx context.Context, sale *models.Sale) error
+	// Delete はセールを削除する。存在しなければ ErrSaleNotFound
+	Delete(ctx context.Context, id int) error
+	// Claim は開催中のセールの在庫を qty 個確保する。上限を超える場合は false。
+	// 製品の在庫の引き当てと合わせて確保する場合は StockRepository.Reserve に saleID を渡す (同じトランザクションで確保する)
+	Claim(ctx context.Context, saleID, productID, qty int) (bool, error)
+}
+
+type sqlxSaleRepository struct {
+	db *sqlx.DB
+}
+
+func NewSaleRepository(db *sqlx.DB) SaleRepository {
+	return &sqlxSaleRepository{db: db}
+}
+
+func (r *sqlxSaleRepository) Window(ctx context.Context, from, until time.Time) ([]models.Sale, error) {
+	var sales []models.Sale
+	err := r.db.SelectContext(ctx, &sales, `SELECT id, name, starts_at, ends_at, created_at
+		FROM flash_sales
+		WHERE ends_at > ? AND starts_at <= ?
+		ORDER BY starts_at, id`, from, until)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	return sales, r.attachItems(ctx, sales)
+}
+
+func (r *sqlxSaleRepository) Recent(ctx context.Context, limit int) ([]models.Sale, error) {
+	var sales []models.Sale
+	err := r.db.SelectContext(ctx, &sales, `SELECT id, name, starts_at, ends_at, created_at
+		FROM flash_sales ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	return sales, r.attachItems(ctx, sales)
+}
+
+// attachItems はセールの対象製品を 1 回のクエリでまとめて読む
+func (r *sqlxSaleRepository) attachItems(ctx context.Context, sales []models.Sale) error {
+	if len(sales) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(sales))
+	index := make(map[int]int, len(sales))
+	for i, s := range sales {
+		args[i] = s.ID
+		index[s.ID] = i
+		sales[i].Items = []models.SaleItem{}
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT i.sale_id, i.product_id, p.name, p.price, i.sale_price, i.stock_cap, i.sold
+		FROM flash_sale_items i
+		JOIN products p ON p.id = i.product_id
+		WHERE i.sale_id IN (`+strings.TrimSuffix(strings.Repeat("?,", len(sales)), ",")+`)
+		ORDER BY i.sale_id, i.product_id`, args...)
+	if err != nil {
+		return database.Classify(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var saleID int
+		var item models.SaleItem
+		if err := rows.Scan(&saleID, &item.ProductID, &item.ProductName, &item.Price, &item.SalePrice, &item.StockCap, &item.Sold); err != nil {
+			return err
+		}
+		if i, ok := index[saleID]; ok {
+			sales[i].Items = append(sales[i].Items, item)
+		}
+	}
+	return database.Classify(rows.Err())
+}
+
+func (r *sqlxSaleRepository) Create(ctx context.Context, sale *models.Sale) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return database.Classify(err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO flash_sales (name, starts_at, ends_at) VALUES (?, ?, ?)",
+		sale.Name, sale.StartsAt, sale.EndsAt)
+	if err != nil {
+		return database.Classify(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	sale.ID = int(id)
+
+	query := "INSERT INTO flash_sale_items (sale_id, product_id, sale_price, stock_cap) VALUES " +
+		strings.TrimSuffix(strings.Repeat("(?, ?, ?, ?),", len(sale.Items)), ",")
+	args := make([]interface{}, 0, len(sale.Items)*4)
+	for _, item := range sale.Items {
+		args = append(args, sale.ID, item.ProductID, item.SalePrice, item.StockCap)
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return database.Classify(err)
+	}
+	if err := tx.GetContext(ctx, &sale.CreatedAt, "SELECT created_at FROM flash_sales WHERE id = ?", sale.ID); err != nil {
+		return database.Classify(err)
+	}
+	return database.Classify(tx.Commit())
+}
+
+func (r *sqlxSaleRepository) Delete(ctx context.Context, id int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return database.Classify(err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM flash_sales WHERE id = ?", id)
+	if err != nil {
+		return database.Classify(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSaleNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM flash_sale_items WHERE sale_id = ?", id); err != nil {
+		return database.Classify(err)
+	}
+	return database.Classify(tx.Commit())
+}
+
+func (r *sqlxSaleRepository) Claim(ctx context.Context, saleID, productID, qty int) (bool, error) {
+	return claimSale(ctx, r.db, saleID, productID, qty)
+}
+
+// claimSale は db (トランザクションでもよい) でセールの在庫を確保する
+func claimSale(ctx context.Context, db sqlx.ExecerContext, saleID, productID, qty int) (bool, error) {
+	// 上限の判定と加算を 1 文で行い、同時に確保しても stock_cap を超えないようにする
+	res, err := db.ExecContext(ctx, `UPDATE flash_sale_items i
+		JOIN flash_sales s ON s.id = i.sale_id
+		SET i.sold = i.sold + ?
+		WHERE i.sale_id = ? AND i.product_id = ? AND i.sold + ? <= i.stock_cap
+		AND s.starts_at <= CURRENT_TIMESTAMP AND s.ends_at > CURRENT_TIMESTAMP`,
+		qty, saleID, productID, qty)
+	if err != nil {
+		return false, database.Classify(err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/dbtest"
+)
+
+// newTestSale は開催中のセールを 1 件作り、ID を返す (dbtest.Tx の中なのでテストの終了時に消える)
+func newTestSale(t *testing.T, tx *sqlx.Tx, productID, stockCap int) int {
+	t.Helper()
+	res, err := tx.Exec(`INSERT INTO flash_sales (name, starts_at, ends_at)
+		VALUES ('test', CURRENT_TIMESTAMP - INTERVAL 1 HOUR, CURRENT_TIMESTAMP + INTERVAL 1 HOUR)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := res.LastInsertId()
+	if _, err := tx.Exec("INSERT INTO flash_sale_items (sale_id, product_id, sale_price, stock_cap) VALUES (?, ?, 100, ?)",
+		id, productID, stockCap); err != nil {
+		t.Fatal(err)
+	}
+	return int(id)
+}
+
+func testProductID(t *testing.T, tx *sqlx.Tx) int {
+	t.Helper()
+	var id int
+	if err := tx.Get(&id, "SELECT MIN(id) FROM products"); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestClaimSaleStockCap(t *testing.T) {
+	t.Parallel()
+	tx := dbtest.Tx(t)
+	ctx := context.Background()
+	productID := testProductID(t, tx)
+	saleID := newTestSale(t, tx, productID, 3)
+
+	for _, tt := range []struct {
+		qty  int
+		want bool
+	}{{2, true}, {2, false}, {1, true}, {1, false}} {
+		ok, err := claimSale(ctx, tx, saleID, productID, tt.qty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tt.want {
+			t.Errorf("claimSale(qty=%d) = %t, want %t", tt.qty, ok, tt.want)
+		}
+	}
+	var sold int
+	if err := tx.Get(&sold, "SELECT sold FROM flash_sale_items WHERE sale_id = ?", saleID); err != nil {
+		t.Fatal(err)
+	}
+	if sold != 3 {
+		t.Errorf("sold = %d, want 3", sold)
+	}
+}
+
+func TestClaimSaleEnded(t *testing.T) {
+	t.Parallel()
+	tx := dbtest.Tx(t)
+	productID := testProductID(t, tx)
+	saleID := newTestSale(t, tx, productID, 10)
+	if _, err := tx.Exec("UPDATE flash_sales SET ends_at = CURRENT_TIMESTAMP - INTERVAL 1 MINUTE WHERE id = ?", saleID); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := claimSale(context.Background(), tx, saleID, productID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("claimSale succeeded for a sale that has ended")
+	}
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/database"
+	"sample-backend/internal/models"
+)
+
+// selectProducts は製品の一覧クエリを実行して結果を返す。
+// クエリは productColumns の順 (id, name, category, brand, model, description, price, sku, created_at,
+// review_count, rating_average, stock) で 12 列を返すこと。列を増やす場合は productColumns とこのスキャン先を合わせて変える。
+// sqlx.Select は行ごとにリフレクションでスキャン先を組み立てるため、ホットパスでは
+// 件数分の容量を確保したスライスと使い回すスキャン先で読み取る。
+// DB のエラーは database.Classify で分類して返す
+func selectProducts(ctx context.Context, db *sqlx.DB, capacity int, query string, args ...interface{}) ([]models.Product, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	defer rows.Close()
+
+	products := make([]models.Product, 0, capacity)
+	var p models.Product
+	dest := []interface{}{&p.ID, &p.Name, &p.Category, &p.Brand, &p.Model, &p.Description, &p.Price, &p.SKU, &p.CreatedAt, &p.ReviewCount, &p.RatingAverage, &p.Stock}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, database.Classify(err)
+	}
+	return products, nil
+}
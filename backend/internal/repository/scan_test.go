@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/models"
+	"sample-backend/internal/pagination"
+)
+
+// benchRows は GetProducts の 1 ページ (既定の limit) の行数
+const benchRows = pagination.DefaultLimit
+
+// stubConnector は MySQL の代わりに固定の行を返すドライバー。スキャンの割り当てだけを測るために使う
+type stubConnector struct{ rows int }
+
+func (c stubConnector) Connect(context.Context) (driver.Conn, error) { return stubConn(c), nil }
+func (c stubConnector) Driver() driver.Driver                        { return nil }
+
+type stubConn struct{ rows int }
+
+func (stubConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (stubConn) Close() error                        { return nil }
+func (stubConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (c stubConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &stubRows{n: c.rows}, nil
+}
+
+type stubRows struct{ i, n int }
+
+var stubColumns = strings.Split(strings.ReplaceAll(productColumns, " ", ""), ",")
+
+func (r *stubRows) Columns() []string { return stubColumns }
+func (r *stubRows) Close() error      { return nil }
+
+var stubCreatedAt = time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.i >= r.n {
+		return io.EOF
+	}
+	r.i++
+	// go-sql-driver/mysql と同じく文字列の列は []byte で返す
+	dest[0] = int64(r.i)
+	dest[1] = []byte("ノートパソコン 14 インチ")
+	dest[2] = []byte("パソコン")
+	dest[3] = []byte("Brand")
+	dest[4] = []byte("MODEL-1400")
+	dest[5] = []byte("軽量で持ち運びやすい 14 インチのノートパソコン。バッテリーは最大 12 時間持続する。")
+	dest[6] = []byte("128000.00")
+	dest[7] = []byte("SKU-0001")
+	dest[8] = stubCreatedAt
+	dest[9] = int64(12)
+	dest[10] = []byte("4.25")
+	dest[11] = int64(30)
+	return nil
+}
+
+func newStubDB(rows int) *sqlx.DB {
+	return sqlx.NewDb(sql.OpenDB(stubConnector{rows: rows}), "mysql")
+}
+
+// BenchmarkSelectProducts は一覧 1 ページ分の読み取りの割り当て (allocs/op) を測る。
+// sqlx.Select は比較用 (行ごとにリフレクションでスキャン先を組み立てる)
+func BenchmarkSelectProducts(b *testing.B) {
+	ctx := context.Background()
+	db := newStubDB(benchRows)
+	defer db.Close()
+	query := "SELECT " + productColumns + " FROM products ORDER BY id LIMIT ? OFFSET ?"
+
+	b.Run("selectProducts", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			products, err := selectProducts(ctx, db, benchRows, query, benchRows, 0)
+			if err != nil || len(products) != benchRows {
+				b.Fatalf("selectProducts returned %d products: %v", len(products), err)
+			}
+		}
+	})
+	b.Run("sqlx.Select", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var products []models.Product
+			if err := db.SelectContext(ctx, &products, query, benchRows, 0); err != nil || len(products) != benchRows {
+				b.Fatalf("sqlx.Select returned %d products: %v", len(products), err)
+			}
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/database"
+)
+
+// StockChange は在庫を変えた結果。Price は通知 (再入荷) に載せる現在の価格
+type StockChange struct {
+	ProductID int
+	Before    int
+	After     int
+	Price     float64
+	// セールの在庫も確保した場合のセールの ID と、確保した後のセールの残り在庫数
+	SaleID        int
+	SaleRemaining int
+}
+
+// InsufficientStock は在庫が足りないときに ErrConflict とともに返す内容
+type InsufficientStock struct {
+	Available int `json:"available"`
+	Requested int `json:"requested"`
+}
+
+// InsufficientSaleStock はセールの在庫 (stock_cap) が足りないときに ErrConflict とともに返す内容
+type InsufficientSaleStock struct {
+	SaleID    int `json:"sale_id"`
+	Available int `json:"available"`
+	Requested int `json:"requested"`
+}
+
+// StockRepository は製品の在庫 (products.stock) を増減する
+type StockRepository interface {
+	// AdjustStock は在庫を delta だけ増減する。製品が存在しなければ ErrNotFound、
+	// 在庫が負になる場合は InsufficientStock を添えた apperr.ErrConflict
+	AdjustStock(ctx context.Context, productID, delta int) (*StockChange, error)
+	// Reserve は在庫から quantity を引き当てる。足りなければ InsufficientStock を添えた apperr.ErrConflict。
+	// saleID が 0 でなければ同じトランザクションでセールの在庫も確保し、セールの上限を超える場合は
+	// InsufficientSaleStock を添えた apperr.ErrConflict を返す (製品の在庫も引かない)
+	Reserve(ctx context.Context, productID, quantity, saleID int) (*StockChange, error)
+}
+
+type sqlxStockRepository struct {
+	db *sqlx.DB
+}
+
+func NewStockRepository(db *sqlx.DB) StockRepository {
+	return &sqlxStockRepository{db: db}
+}
+
+func insufficientStock(available, requested int) error {
+	return apperr.ConflictWith("Insufficient stock", InsufficientStock{Available: available, Requested: requested}, nil)
+}
+
+// current はトランザクションの中で在庫と価格を読む。forUpdate なら行ロックを取る
+func current(ctx context.Context, tx *sqlx.Tx, productID int, forUpdate bool) (*StockChange, error) {
+	query := "SELECT stock, price FROM products WHERE id = ?"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	c := &StockChange{ProductID: productID}
+	err := tx.QueryRowxContext(ctx, query, productID).Scan(&c.After, &c.Price)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	return c, nil
+}
+
+func (r *sqlxStockRepository) AdjustStock(ctx context.Context, productID, delta int) (*StockChange, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	defer tx.Rollback()
+
+	c, err := current(ctx, tx, productID, true)
+	if err != nil {
+		return nil, err
+	}
+	c.Before = c.After
+	c.After = c.Before + delta
+	if c.After < 0 {
+		return nil, insufficientStock(c.Before, -delta)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE products SET stock = ? WHERE id = ?", c.After, productID); err != nil {
+		return nil, database.Classify(err)
+	}
+	return c, database.Classify(tx.Commit())
+}
+
+func (r *sqlxStockRepository) Reserve(ctx context.Context, productID, quantity, saleID int) (*StockChange, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	defer tx.Rollback()
+
+	// 条件付きの UPDATE で減らし、同時の引き当てで在庫が負にならないようにする
+	res, err := tx.ExecContext(ctx, "UPDATE products SET stock = stock - ? WHERE id = ? AND stock >= ?", quantity, productID, quantity)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	n, _ := res.RowsAffected()
+	// 更新した場合は行ロックを持っているので、ロックを取らずに読んでも変更後の在庫になる
+	c, err := current(ctx, tx, productID, false)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, insufficientStock(c.After, quantity)
+	}
+	c.Before = c.After + quantity
+
+	if saleID > 0 {
+		claimed, err := claimSale(ctx, tx, saleID, productID, quantity)
+		if err != nil {
+			return nil, err
+		}
+		var remaining int
+		if err := tx.GetContext(ctx, &remaining, "SELECT GREATEST(stock_cap - sold, 0) FROM flash_sale_items WHERE sale_id = ? AND product_id = ?", saleID, productID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, database.Classify(err)
+		}
+		if !claimed {
+			// 製品の在庫を引いた分もロールバックで戻る (セールが終わっていた場合も確保できない)
+			return nil, apperr.ConflictWith("Insufficient sale stock", InsufficientSaleStock{SaleID: saleID, Available: remaining, Requested: quantity}, nil)
+		}
+		c.SaleID, c.SaleRemaining = saleID, remaining
+	}
+	return c, database.Classify(tx.Commit())
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/database"
+	"sample-backend/internal/models"
+)
+
+// ErrStoreNotFound は指定した店舗が存在しない
+var ErrStoreNotFound = apperr.NotFound("Store not found")
+
+// kmPerDegree は緯度 1 度あたりの距離 (km)
+const kmPerDegree = 111.045
+
+// StoreRepository は店舗 (stores) と店舗ごとの在庫 (store_stock) を読む
+type StoreRepository interface {
+	// Get は ID を指定して店舗を返す。存在しなければ ErrStoreNotFound
+	Get(ctx context.Context, id int) (*models.Store, error)
+	// Nearby は (lat, lng) から radiusKM 以内の店舗を近い順に最大 limit 件返す
+	Nearby(ctx context.Context, lat, lng, radiusKM float64, limit int) ([]models.Store, error)
+	// Stock は storeIDs の店舗にある productIDs の在庫 (1 個以上のもの) を返す
+	Stock(ctx context.Context, productIDs, storeIDs []int) ([]models.StoreStock, error)
+}
+
+type sqlxStoreRepository struct {
+	db *sqlx.DB
+}
+
+func NewStoreRepository(db *sqlx.DB) StoreRepository {
+	return &sqlxStoreRepository{db: db}
+}
+
+func (r *sqlxStoreRepository) Get(ctx context.Context, id int) (*models.Store, error) {
+	var s models.Store
+	err := r.db.GetContext(ctx, &s, "SELECT id, name, address, latitude, longitude FROM stores WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrStoreNotFound
+	}
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	return &s, nil
+}
+
+func (r *sqlxStoreRepository) Nearby(ctx context.Context, lat, lng, radiusKM float64, limit int) ([]models.Store, error) {
+	// 緯度・経度の範囲 (インデックスが効く) で候補を絞ってから球面上の距離で判定する
+	dLat := radiusKM / kmPerDegree
+	dLng := radiusKM / (kmPerDegree * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+
+	stores := []models.Store{}
+	err := r.db.SelectContext(ctx, &stores, `SELECT id, name, address, latitude, longitude,
+			ST_Distance_Sphere(POINT(longitude, latitude), POINT(?, ?)) / 1000 AS distance_km
+		FROM stores
+		WHERE latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?
+		HAVING distance_km <= ?
+		ORDER BY distance_km, id
+		LIMIT ?`, lng, lat, lat-dLat, lat+dLat, lng-dLng, lng+dLng, radiusKM, limit)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	return stores, nil
+}
+
+func (r *sqlxStoreRepository) Stock(ctx context.Context, productIDs, storeIDs []int) ([]models.StoreStock, error) {
+	if len(productIDs) == 0 || len(storeIDs) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, 0, len(productIDs)+len(storeIDs))
+	for _, id := range productIDs {
+		args = append(args, id)
+	}
+	for _, id := range storeIDs {
+		args = append(args, id)
+	}
+
+	var stock []models.StoreStock
+	err := r.db.SelectContext(ctx, &stock, `SELECT store_id, product_id, quantity
+		FROM store_stock
+		WHERE product_id IN (`+placeholders(len(productIDs))+`) AND store_id IN (`+placeholders(len(storeIDs))+`) AND quantity > 0`,
+		args...)
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	return stock, nil
+}
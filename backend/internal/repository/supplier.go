@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/database"
+	"sample-backend/internal/fieldcrypt"
+	"sample-backend/internal/models"
+)
+
+// ErrSupplierInfoNotFound は製品の仕入れ情報が登録されていない (apperr.ErrNotFound)
+var ErrSupplierInfoNotFound = apperr.NotFound("Supplier info not found")
+
+// SupplierRepository は製品の仕入れ情報 (product_supplier_info) を読み書きする。
+// 機密カラムはここで製品 ID に結び付けて暗号化・復号し、呼び出し元には平文として渡す
+type SupplierRepository interface {
+	// GetSupplierInfo は製品の仕入れ情報を返す。登録されていなければ ErrSupplierInfoNotFound
+	GetSupplierInfo(ctx context.Context, productID int) (*models.SupplierInfo, error)
+	// SaveSupplierInfo は仕入れ情報を登録する。既にあれば上書きする
+	SaveSupplierInfo(ctx context.Context, info *models.SupplierInfo) error
+}
+
+type sqlxSupplierRepository struct {
+	db *sqlx.DB
+}
+
+func NewSupplierRepository(db *sqlx.DB) SupplierRepository {
+	return &sqlxSupplierRepository{db: db}
+}
+
+// supplierRow は暗号化したままの product_supplier_info の行
+type supplierRow struct {
+	ProductID      int       `db:"product_id"`
+	SupplierCost   []byte    `db:"supplier_cost"`
+	PartnerContact []byte    `db:"partner_contact"`
+	UpdatedAt      time.Time `db:"updated_at"`
+}
+
+// supplierAAD は機密カラムの暗号文を product_supplier_info のその製品の行と列に結び付ける
+func supplierAAD(column string, productID int) []byte {
+	return fieldcrypt.AAD("product_supplier_info", column, productID)
+}
+
+func (r *sqlxSupplierRepository) GetSupplierInfo(ctx context.Context, productID int) (*models.SupplierInfo, error) {
+	var row supplierRow
+	err := r.db.GetContext(ctx, &row, "SELECT product_id, supplier_cost, partner_contact, updated_at FROM product_supplier_info WHERE product_id = ?", productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSupplierInfoNotFound
+	}
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+
+	info := models.SupplierInfo{ProductID: row.ProductID, UpdatedAt: row.UpdatedAt}
+	if err := info.SupplierCost.Open(row.SupplierCost, supplierAAD("supplier_cost", row.ProductID)); err != nil {
+		return nil, err
+	}
+	if err := info.PartnerContact.Open(row.PartnerContact, supplierAAD("partner_contact", row.ProductID)); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (r *sqlxSupplierRepository) SaveSupplierInfo(ctx context.Context, info *models.SupplierInfo) error {
+	cost, err := info.SupplierCost.Seal(supplierAAD("supplier_cost", info.ProductID))
+	if err != nil {
+		return err
+	}
+	contact, err := info.PartnerContact.Seal(supplierAAD("partner_contact", info.ProductID))
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO product_supplier_info (product_id, supplier_cost, partner_contact)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE supplier_cost = VALUES(supplier_cost), partner_contact = VALUES(partner_contact)`,
+		info.ProductID, cost, contact)
+	return database.Classify(err)
+}
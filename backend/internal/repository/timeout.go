@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/reqlog"
+)
+
+// timeoutRepository は製品リポジトリの操作ごとに子スパンを作り、読み取りと書き込みで別のタイムアウトを掛ける。
+// タイムアウトした問い合わせはドライバーが打ち切り、database.Classify によって ErrUnavailable (503) になる
+type timeoutRepository struct {
+	next        ProductRepository
+	read, write time.Duration
+}
+
+// WithTimeouts は操作ごとにタイムアウトを掛ける ProductRepository を返す。0 のタイムアウトは掛けない
+// (呼び出し元のコンテキストの期限だけに従う)。タイムアウトを含めて計測するよう、メトリクスより内側に置く
+func WithTimeouts(next ProductRepository, read, write time.Duration) ProductRepository {
+	return &timeoutRepository{next: next, read: read, write: write}
+}
+
+// start は op の子スパンとタイムアウトを設定したコンテキストを返す。
+// 返した関数は defer で呼び、エラーは名前付きの戻り値へのポインタで渡す
+func (r *timeoutRepository) start(ctx context.Context, op string, timeout time.Duration) (context.Context, func(*error)) {
+	ctx, span := tracer.Start(ctx, "repository."+op)
+	span.SetAttributes(attribute.String("db.operation", op))
+	cancel := func() {}
+	if timeout > 0 {
+		span.SetAttributes(attribute.Int64("db.timeout_ms", timeout.Milliseconds()))
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func(errp *error) {
+		timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+		cancel()
+		if err := *errp; err != nil && !errors.Is(err, apperr.ErrNotFound) {
+			span.SetAttributes(attribute.String("error", err.Error()))
+			if timedOut {
+				span.SetAttributes(attribute.Bool("db.timed_out", true))
+				reqlog.From(ctx).Printf("[DB ERROR] %s timed out after %v", op, timeout)
+			}
+		}
+		span.End()
+	}
+}
+
+func (r *timeoutRepository) Count(ctx context.Context, filter ListFilter) (n int, err error) {
+	ctx, end := r.start(ctx, "count", r.read)
+	defer end(&err)
+	return r.next.Count(ctx, filter)
+}
+
+func (r *timeoutRepository) List(ctx context.Context, filter ListFilter, limit, offset int) (products []models.Product, err error) {
+	ctx, end := r.start(ctx, "list", r.read)
+	defer end(&err)
+	return r.next.List(ctx, filter, limit, offset)
+}
+
+func (r *timeoutRepository) ListAfter(ctx context.Context, filter ListFilter, afterID, limit int) (products []models.Product, err error) {
+	ctx, end := r.start(ctx, "list_after", r.read)
+	defer end(&err)
+	return r.next.ListAfter(ctx, filter, afterID, limit)
+}
+
+func (r *timeoutRepository) CountBy(ctx context.Context, filter ListFilter, column string, limit int) (counts []models.FacetCount, err error) {
+	ctx, end := r.start(ctx, "count_by", r.read)
+	defer end(&err)
+	return r.next.CountBy(ctx, filter, column, limit)
+}
+
+func (r *timeoutRepository) CountByPrice(ctx context.Context, filter ListFilter, bounds []float64) (buckets []models.PriceBucket, err error) {
+	ctx, end := r.start(ctx, "count_by_price", r.read)
+	defer end(&err)
+	return r.next.CountByPrice(ctx, filter, bounds)
+}
+
+func (r *timeoutRepository) Get(ctx context.Context, id int) (p *models.Product, err error) {
+	ctx, end := r.start(ctx, "get", r.read)
+	defer end(&err)
+	return r.next.Get(ctx, id)
+}
+
+func (r *timeoutRepository) GetMany(ctx context.Context, ids []int) (products []models.Product, err error) {
+	ctx, end := r.start(ctx, "get_many", r.read)
+	defer end(&err)
+	return r.next.GetMany(ctx, ids)
+}
+
+func (r *timeoutRepository) GetBySKU(ctx context.Context, sku string) (p *models.Product, err error) {
+	ctx, end := r.start(ctx, "get_by_sku", r.read)
+	defer end(&err)
+	return r.next.GetBySKU(ctx, sku)
+}
+
+func (r *timeoutRepository) SearchCount(ctx context.Context, q SearchQuery) (n int, err error) {
+	ctx, end := r.start(ctx, "search_count", r.read)
+	defer end(&err)
+	return r.next.SearchCount(ctx, q)
+}
+
+func (r *timeoutRepository) Search(ctx context.Context, q SearchQuery, limit, offset int) (products []models.Product, err error) {
+	ctx, end := r.start(ctx, "search", r.read)
+	defer end(&err)
+	return r.next.Search(ctx, q, limit, offset)
+}
+
+func (r *timeoutRepository) FullTextCount(ctx context.Context, keyword string) (n int, err error) {
+	ctx, end := r.start(ctx, "fulltext_count", r.read)
+	defer end(&err)
+	return r.next.FullTextCount(ctx, keyword)
+}
+
+func (r *timeoutRepository) FullText(ctx context.Context, keyword string, limit, offset int) (products []models.Product, err error) {
+	ctx, end := r.start(ctx, "fulltext", r.read)
+	defer end(&err)
+	return r.next.FullText(ctx, keyword, limit, offset)
+}
+
+func (r *timeoutRepository) Create(ctx context.Context, p *models.Product) (err error) {
+	ctx, end := r.start(ctx, "create", r.write)
+	defer end(&err)
+	return r.next.Create(ctx, p)
+}
+
+func (r *timeoutRepository) CreateBatch(ctx context.Context, products []models.Product) (err error) {
+	ctx, end := r.start(ctx, "create_batch", r.write)
+	defer end(&err)
+	return r.next.CreateBatch(ctx, products)
+}
+
+func (r *timeoutRepository) Update(ctx context.Context, p *models.Product) (old *models.Product, err error) {
+	ctx, end := r.start(ctx, "update", r.write)
+	defer end(&err)
+	return r.next.Update(ctx, p)
+}
+
+func (r *timeoutRepository) Delete(ctx context.Context, id int) (err error) {
+	ctx, end := r.start(ctx, "delete", r.write)
+	defer end(&err)
+	return r.next.Delete(ctx, id)
+}
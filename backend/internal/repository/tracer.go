@@ -0,0 +1,6 @@
+package repository
+
+import "go.opentelemetry.io/otel"
+
+// tracer はリポジトリ層のトレーサー (ハンドラー・サービスと同じサービス名で記録する)
+var tracer = otel.Tracer("product-search-backend")
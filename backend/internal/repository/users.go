@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/database"
+	"sample-backend/internal/models"
+)
+
+// ErrUserNotFound は指定したユーザーが存在しない
+var ErrUserNotFound = apperr.NotFound("User not found")
+
+// UserRepository は JWT でログインするユーザー (users) を読み書きする
+type UserRepository interface {
+	// GetByUsername はユーザー名を指定してユーザーを返す。存在しなければ ErrUserNotFound
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	// Create はユーザーを登録し、採番された ID と登録日時を u に設定する。ユーザー名が重複すれば ErrConflict
+	Create(ctx context.Context, u *models.User) error
+}
+
+type sqlxUserRepository struct {
+	db *sqlx.DB
+}
+
+func NewUserRepository(db *sqlx.DB) UserRepository {
+	return &sqlxUserRepository{db: db}
+}
+
+func (r *sqlxUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	var u models.User
+	err := r.db.GetContext(ctx, &u, "SELECT id, username, password_hash, role, created_at FROM users WHERE username = ?", username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, database.Classify(err)
+	}
+	return &u, nil
+}
+
+func (r *sqlxUserRepository) Create(ctx context.Context, u *models.User) error {
+	res, err := r.db.ExecContext(ctx, "INSERT INTO users (username, password_hash, role) VALUES (?, ?, ?)",
+		u.Username, u.PasswordHash, u.Role)
+	if err != nil {
+		return database.Classify(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	u.ID = int(id)
+	return database.Classify(r.db.GetContext(ctx, &u.CreatedAt, "SELECT created_at FROM users WHERE id = ?", u.ID))
+}
@@ -0,0 +1,85 @@
+// Package reqlog はリクエスト単位のロガーを扱う。
+// ミドルウェアがリクエストの識別情報をコンテキストに載せ、サービスやリポジトリは From(ctx) で
+// 取り出したロガーで出力するだけで、各行にリクエスト ID・ルート・クライアント・トレース ID が付く
+package reqlog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"sample-backend/internal/logging"
+)
+
+// Fields はリクエストの識別情報。ルートとクライアントはルーティングや認証の後で決まるため、
+// ミドルウェアが同じ値を後から書き込む。書き込みはハンドラーが並列処理を始める前に済ませること
+type Fields struct {
+	RequestID string
+	Route     string
+	User      string
+}
+
+type fieldsKey struct{}
+
+// NewContext はリクエストの識別情報を載せたコンテキストを返す
+func NewContext(ctx context.Context, f *Fields) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+// FieldsFrom はコンテキストに載っている識別情報を返す。載っていなければ nil
+func FieldsFrom(ctx context.Context) *Fields {
+	f, _ := ctx.Value(fieldsKey{}).(*Fields)
+	return f
+}
+
+// RequestID はコンテキストに載っているリクエスト ID を返す
+func RequestID(ctx context.Context) string {
+	if f := FieldsFrom(ctx); f != nil {
+		return f.RequestID
+	}
+	return ""
+}
+
+// Logger は識別情報を付けて出力する (plain 形式では末尾の key=value、text / JSON 形式では属性)
+type Logger struct {
+	ctx context.Context
+}
+
+// From はコンテキストに結び付いたロガーを返す。リクエストの外で呼んでもよく、その場合は何も付けない
+func From(ctx context.Context) Logger {
+	return Logger{ctx: ctx}
+}
+
+// Printf は log.Printf と同じ書式で出力し、末尾に request_id などを付ける
+func (l Logger) Printf(format string, args ...interface{}) {
+	l.output(fmt.Sprintf(format, args...))
+}
+
+// Println は log.Println と同じく引数を空白で区切って出力する
+func (l Logger) Println(args ...interface{}) {
+	l.output(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+}
+
+func (l Logger) output(msg string) {
+	var attrs []slog.Attr
+	if f := FieldsFrom(l.ctx); f != nil {
+		attrs = appendAttr(attrs, "request_id", f.RequestID)
+		attrs = appendAttr(attrs, "route", f.Route)
+		attrs = appendAttr(attrs, "user", f.User)
+	}
+	if sc := trace.SpanContextFromContext(l.ctx); sc.HasTraceID() {
+		attrs = appendAttr(attrs, "trace_id", sc.TraceID().String())
+	}
+	// 呼び出し元のファイル名を出す設定でも Printf の呼び出し位置になるよう 3 段上を指す
+	logging.Output(3, msg, attrs...)
+}
+
+func appendAttr(attrs []slog.Attr, key, value string) []slog.Attr {
+	if value == "" {
+		return attrs
+	}
+	return append(attrs, slog.String(key, value))
+}
@@ -0,0 +1,146 @@
+// Package rerank は検索結果の並べ替えを外部のスコアリングサービスに任せる。
+// スコアリングサービスは機械学習のランキングモデルなどを想定し、遅い・落ちている場合は
+// 元の並び順のまま返すので、検索の可用性には影響しない
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"sample-backend/internal/models"
+	"sample-backend/internal/reqlog"
+)
+
+// 結果ごとの件数 (管理用リスナーの /debug/vars で参照できる)
+var outcomes = expvar.NewMap("rerank_total")
+
+// Query は並べ替えの対象になった検索条件
+type Query struct {
+	Column  string `json:"column"`
+	Keyword string `json:"keyword"`
+}
+
+// Ranker は検索結果を並べ替える。失敗した場合も含め、必ず candidates と同じ製品を返す
+type Ranker interface {
+	Rerank(ctx context.Context, q Query, candidates []models.Product) []models.Product
+}
+
+type scoreRequest struct {
+	Query      Query            `json:"query"`
+	Candidates []models.Product `json:"candidates"`
+}
+
+type scoreResponse struct {
+	Scores []struct {
+		ID    int     `json:"id"`
+		Score float64 `json:"score"`
+	} `json:"scores"`
+}
+
+// HTTPRanker は候補を JSON で POST し、返ってきたスコアの高い順に並べ替える。
+//
+//	リクエスト: {"query": {"column": "name", "keyword": "..."}, "candidates": [製品, ...]}
+//	レスポンス: {"scores": [{"id": 1, "score": 0.93}, ...]}
+//
+// スコアが返らなかった製品は、スコアのある製品の後ろに元の順で並べる
+type HTTPRanker struct {
+	url     string
+	client  *http.Client
+	timeout time.Duration
+}
+
+// NewHTTPRanker はタイムアウト付きで url のスコアリングサービスを呼ぶ Ranker を返す
+func NewHTTPRanker(url string, timeout time.Duration) *HTTPRanker {
+	log.Printf("[RERANK] Re-ranking search results with %s (timeout: %v)", url, timeout)
+	return &HTTPRanker{
+		url:     url,
+		timeout: timeout,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: 32,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+func (r *HTTPRanker) Rerank(ctx context.Context, q Query, candidates []models.Product) []models.Product {
+	if len(candidates) < 2 {
+		return candidates
+	}
+
+	scores, err := r.score(ctx, q, candidates)
+	if err != nil {
+		outcome := "error"
+		if errors.Is(err, context.DeadlineExceeded) {
+			outcome = "timeout"
+		}
+		outcomes.Add(outcome, 1)
+		reqlog.From(ctx).Printf("[RERANK ERROR] Falling back to base ordering: %v", err)
+		return candidates
+	}
+	outcomes.Add("ok", 1)
+	return reorder(candidates, scores)
+}
+
+func (r *HTTPRanker) score(ctx context.Context, q Query, candidates []models.Product) (map[int]float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	body, err := json.Marshal(scoreRequest{Query: q, Candidates: candidates})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if id := reqlog.RequestID(ctx); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("scoring service returned %d", resp.StatusCode)
+	}
+
+	var sr scoreResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&sr); err != nil {
+		return nil, fmt.Errorf("invalid scoring response: %w", err)
+	}
+	scores := make(map[int]float64, len(sr.Scores))
+	for _, s := range sr.Scores {
+		scores[s.ID] = s.Score
+	}
+	return scores, nil
+}
+
+// reorder はスコアの高い順に並べ替える。同点とスコアのない製品は元の順を保つ
+func reorder(candidates []models.Product, scores map[int]float64) []models.Product {
+	out := append([]models.Product(nil), candidates...)
+	sort.SliceStable(out, func(i, j int) bool {
+		si, iok := scores[out[i].ID]
+		sj, jok := scores[out[j].ID]
+		if iok != jok {
+			return iok
+		}
+		return iok && si > sj
+	})
+	return out
+}
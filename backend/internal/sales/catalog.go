@@ -0,0 +1,239 @@
+// Package sales はタイムセールの状態をメモリに保持し、製品のレスポンスにセール価格を付ける。
+//
+// セールの開始直後はアクセスが集中するため、リクエストごとには DB を読まない。
+// 開催中に加えて開始間近 (Horizon 以内) のセールも先に読み込んでおき、開催中かどうかは
+// リクエストの時刻で判定するので、セール価格は開始時刻ちょうどに一斉に切り替わる
+package sales
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"sample-backend/internal/clock"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+)
+
+// snapshot は読み込んだ時点のセールの一覧。作成後は変更しない
+type snapshot struct {
+	sales []models.Sale
+	// 製品 ID → その製品を含むセールの位置
+	byProduct map[int][]int
+	// 内容が最後に変わった時刻
+	changedAt time.Time
+	hash      uint64
+}
+
+// Catalog はタイムセールの状態を定期的に読み込み直して保持する
+type Catalog struct {
+	repo     repository.SaleRepository
+	interval time.Duration
+	horizon  time.Duration
+	clock    clock.Clock
+
+	snap atomic.Pointer[snapshot]
+
+	// 開催中のセールの JSON (スナップショットと開催状況が変わるまで使い回す)
+	mu       sync.Mutex
+	bodyKey  bodyKey
+	bodyJSON []byte
+}
+
+type bodyKey struct {
+	snap  *snapshot
+	epoch time.Time
+}
+
+// NewCatalog は interval ごとに、開催中と horizon 以内に始まるセールを読み込む Catalog を返す
+func NewCatalog(repo repository.SaleRepository, interval, horizon time.Duration) *Catalog {
+	c := &Catalog{repo: repo, interval: interval, horizon: horizon, clock: clock.Real}
+	c.snap.Store(&snapshot{byProduct: map[int][]int{}})
+	return c
+}
+
+// SetClock は開催中かどうかの判定に使う Clock を差し替える。Start より前に呼ぶ
+func (c *Catalog) SetClock(clk clock.Clock) {
+	c.clock = clock.OrReal(clk)
+}
+
+// Start は初回の読み込みを行い、以降定期的に読み込み直すゴルーチンを起動する
+func (c *Catalog) Start() {
+	if err := c.Refresh(context.Background()); err != nil {
+		log.Printf("[SALES ERROR] Failed to load sales: %v", err)
+	}
+	go func() {
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := c.Refresh(context.Background()); err != nil {
+				log.Printf("[SALES ERROR] Failed to refresh sales: %v", err)
+			}
+		}
+	}()
+	log.Printf("[SALES] Sale catalog started - refresh: %v, horizon: %v", c.interval, c.horizon)
+}
+
+// Refresh はセールを読み込み直す。管理用 API でセールを変更したあとにも呼ぶ
+func (c *Catalog) Refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	now := c.clock.Now()
+	sales, err := c.repo.Window(ctx, now, now.Add(c.horizon))
+	if err != nil {
+		return err
+	}
+
+	next := &snapshot{sales: sales, byProduct: map[int][]int{}, hash: hashSales(sales)}
+	for i, s := range sales {
+		for _, item := range s.Items {
+			next.byProduct[item.ProductID] = append(next.byProduct[item.ProductID], i)
+		}
+	}
+	prev := c.snap.Load()
+	next.changedAt = prev.changedAt
+	if next.hash != prev.hash {
+		next.changedAt = now
+		log.Printf("[SALES] Loaded %d sales", len(sales))
+	}
+	c.snap.Store(next)
+	return nil
+}
+
+// hashSales は内容が変わったかどうかの判定に使うハッシュ。売り切れの変化も含める
+func hashSales(sales []models.Sale) uint64 {
+	h := fnv.New64a()
+	for _, s := range sales {
+		fmt.Fprintf(h, "%d|%d|%d;", s.ID, s.StartsAt.UnixNano(), s.EndsAt.UnixNano())
+		for _, item := range s.Items {
+			fmt.Fprintf(h, "%d|%v|%t;", item.ProductID, item.SalePrice, item.Remaining() > 0)
+		}
+	}
+	return h.Sum64()
+}
+
+func active(s models.Sale, now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// ChangedAt は now の時点で表示すべきセールの状態が最後に変わった時刻を返す。
+// これより前に組み立てたレスポンスのキャッシュは使えない
+func (c *Catalog) ChangedAt(now time.Time) time.Time {
+	snap := c.snap.Load()
+	latest := snap.changedAt
+	for _, s := range snap.sales {
+		for _, t := range []time.Time{s.StartsAt, s.EndsAt} {
+			if !t.After(now) && t.After(latest) {
+				latest = t
+			}
+		}
+	}
+	return latest
+}
+
+// Lookup は now の時点で製品に適用するセールを返す。複数のセールに含まれる場合は最も安いもの。
+// 売り切れたセールは適用しない
+func (c *Catalog) Lookup(productID int, now time.Time) *models.ProductSale {
+	return c.snap.Load().lookup(productID, now)
+}
+
+func (snap *snapshot) lookup(productID int, now time.Time) *models.ProductSale {
+	var best *models.ProductSale
+	for _, i := range snap.byProduct[productID] {
+		s := snap.sales[i]
+		if !active(s, now) {
+			continue
+		}
+		for _, item := range s.Items {
+			if item.ProductID != productID || item.Remaining() == 0 {
+				continue
+			}
+			if best == nil || item.SalePrice < best.SalePrice {
+				best = &models.ProductSale{SaleID: s.ID, SalePrice: item.SalePrice, StartsAt: s.StartsAt, EndsAt: s.EndsAt}
+			}
+		}
+	}
+	return best
+}
+
+// Apply は開催中のセールを製品に付けた一覧を返す。
+// products はキャッシュと共有していることがあるため書き換えず、セール中の製品がある場合だけ複製する。
+// 一覧の途中で読み込み直しが起きても価格が混ざらないよう、1 つのスナップショットだけを見る
+func (c *Catalog) Apply(products []models.Product, now time.Time) []models.Product {
+	if c == nil {
+		return products
+	}
+	snap := c.snap.Load()
+	if len(snap.byProduct) == 0 {
+		return products
+	}
+	var out []models.Product
+	for i := range products {
+		sale := snap.lookup(products[i].ID, now)
+		if sale == nil {
+			continue
+		}
+		if out == nil {
+			out = append([]models.Product(nil), products...)
+		}
+		out[i].Sale = sale
+	}
+	if out == nil {
+		return products
+	}
+	return out
+}
+
+// ApplyOne は開催中のセールを付けた製品の複製を返す。セール中でなければ p をそのまま返す
+func (c *Catalog) ApplyOne(p *models.Product, now time.Time) *models.Product {
+	if c == nil || p == nil {
+		return p
+	}
+	sale := c.Lookup(p.ID, now)
+	if sale == nil {
+		return p
+	}
+	cp := *p
+	cp.Sale = sale
+	return &cp
+}
+
+// Active は開催中と開始間近のセールを返す
+func (c *Catalog) Active(now time.Time) *models.ActiveSalesResponse {
+	snap := c.snap.Load()
+	resp := &models.ActiveSalesResponse{Active: []models.Sale{}, Upcoming: []models.Sale{}}
+	for _, s := range snap.sales {
+		switch {
+		case active(s, now):
+			resp.Active = append(resp.Active, s)
+		case now.Before(s.StartsAt):
+			resp.Upcoming = append(resp.Upcoming, s)
+		}
+	}
+	return resp
+}
+
+// ActiveJSON は Active をエンコードした JSON を返す。セールの状態が変わるまでは同じバイト列を使い回す
+func (c *Catalog) ActiveJSON(now time.Time) ([]byte, error) {
+	key := bodyKey{snap: c.snap.Load(), epoch: c.ChangedAt(now)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.bodyJSON != nil && c.bodyKey == key {
+		return c.bodyJSON, nil
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(c.Active(now)); err != nil {
+		return nil, err
+	}
+	c.bodyKey, c.bodyJSON = key, buf.Bytes()
+	return c.bodyJSON, nil
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"os"
+
+	"sample-backend/internal/metrics"
+	"sample-backend/internal/middleware"
+)
+
+// adminRouter は pprof やデバッグ用のエンドポイントを持つ管理用ルーター。
+// クライアント証明書を要求する別ポートでのみ公開する
+func (s *Server) adminRouter() http.Handler {
+	r := http.NewServeMux()
+
+	// 管理用リスナーには直接接続するため、プロキシのヘッダーは信頼しない
+	allow := func(group string, mws ...middleware.Middleware) func(pattern string, h http.Handler) {
+		guard := middleware.Chain(append([]middleware.Middleware{middleware.IPAllowlist(group, s.config.AdminAllowlists[group], nil)}, mws...)...)
+		return func(pattern string, h http.Handler) {
+			r.Handle(pattern, middleware.Route(pattern, guard(h)))
+		}
+	}
+
+	pprofRoute := allow("pprof")
+	pprofRoute("/debug/pprof/", http.HandlerFunc(pprof.Index))
+	pprofRoute("/debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
+	pprofRoute("/debug/pprof/profile", http.HandlerFunc(pprof.Profile))
+	pprofRoute("/debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
+	pprofRoute("/debug/pprof/trace", http.HandlerFunc(pprof.Trace))
+
+	// メトリクスはこの管理用リスナー (mTLS) だけで公開する。公開用リスナーでは転送ヘッダーや
+	// docker のネットワークの接続元で IP の制限をすり抜けられるため置かない。
+	// Prometheus から取得するには ADMIN_PORT / ADMIN_TLS_CERT / ADMIN_TLS_KEY / ADMIN_CLIENT_CA を設定する
+	metricsRoute := allow("metrics")
+	metricsRoute("GET /debug/vars", expvar.Handler())
+	metricsRoute("GET /metrics", metrics.Handler())
+
+	// 仕入れ情報 (暗号化カラムを含むため管理用リスナーでのみ公開する)
+	adminRoute := allow("admin", middleware.JSONHeaders)
+	supplierHandler := s.handlers.Supplier
+	adminRoute("GET /admin/products/{id}/supplier", http.HandlerFunc(supplierHandler.GetSupplierInfo))
+	adminRoute("PUT /admin/products/{id}/supplier", http.HandlerFunc(supplierHandler.PutSupplierInfo))
+
+	// 製品の一括登録 (大量の書き込みになるため、公開用リスナーには置かない)
+	adminRoute("POST /admin/products/import", http.HandlerFunc(s.handlers.Product.ImportProducts))
+
+	// Q&A の承認と管理者の回答
+	questionHandler := s.handlers.Question
+	adminRoute("GET /admin/questions", http.HandlerFunc(questionHandler.ListForModeration))
+	adminRoute("PUT /admin/questions/{id}/status", http.HandlerFunc(questionHandler.ModerateQuestion))
+	adminRoute("POST /admin/questions/{id}/answers", http.HandlerFunc(questionHandler.AdminAnswer))
+	adminRoute("DELETE /admin/answers/{id}", http.HandlerFunc(questionHandler.DeleteAnswer))
+
+	// 検索用テーブルの作り直し
+	reindexHandler := s.handlers.Reindex
+	adminRoute("POST /admin/reindex", http.HandlerFunc(reindexHandler.StartReindex))
+	adminRoute("GET /admin/reindex", http.HandlerFunc(reindexHandler.GetReindexStatus))
+
+	// タイムセールの登録と削除
+	saleHandler := s.handlers.Sale
+	adminRoute("GET /admin/sales", http.HandlerFunc(saleHandler.ListSales))
+	adminRoute("POST /admin/sales", http.HandlerFunc(saleHandler.CreateSale))
+	adminRoute("DELETE /admin/sales/{id}", http.HandlerFunc(saleHandler.DeleteSale))
+
+	// JWT でログインするユーザーの登録
+	if s.handlers.Auth != nil {
+		adminRoute("POST /admin/users", http.HandlerFunc(s.handlers.Auth.CreateUser))
+	}
+
+	return r
+}
+
+// adminServer は mTLS を必須とした管理用リスナーを作る (起動は呼び出し側が ListenAndServeTLS で行う)。
+// 証明書の設定が揃っていない場合は nil を返す (平文で公開することはない)
+func (s *Server) adminServer() (*http.Server, error) {
+	cfg := s.config
+	if cfg.AdminPort == "" || cfg.AdminTLSCert == "" || cfg.AdminTLSKey == "" || cfg.AdminClientCA == "" {
+		log.Println("[ADMIN] Admin listener disabled (ADMIN_PORT / ADMIN_TLS_CERT / ADMIN_TLS_KEY / ADMIN_CLIENT_CA not set)")
+		return nil, nil
+	}
+
+	caPEM, err := os.ReadFile(cfg.AdminClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admin client CA: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in admin client CA %s", cfg.AdminClientCA)
+	}
+
+	// ブラウザはクライアント証明書を自動で提示するため、管理画面からの変更操作には CSRF トークンを要求する
+	srv := &http.Server{
+		Addr:    ":" + cfg.AdminPort,
+		Handler: middleware.CSRF(s.adminRouter()),
+		TLSConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  clientCAs,
+		},
+	}
+
+	log.Printf("[ADMIN] Admin listener (mTLS) starting on port %s...", cfg.AdminPort)
+	log.Printf("[ADMIN]   GET  /debug/pprof/ - Profiling")
+	log.Printf("[ADMIN]   GET  /debug/vars   - Runtime variables")
+	log.Printf("[ADMIN]   GET  /metrics      - Prometheus metrics")
+	log.Printf("[ADMIN]   GET/PUT /admin/products/{id}/supplier - Supplier info")
+	log.Printf("[ADMIN]   POST /admin/products/import - Bulk import products from CSV or NDJSON")
+	log.Printf("[ADMIN]   GET /admin/questions, PUT /admin/questions/{id}/status - Q&A moderation")
+	log.Printf("[ADMIN]   POST /admin/questions/{id}/answers, DELETE /admin/answers/{id} - Admin answers")
+	log.Printf("[ADMIN]   GET/POST /admin/reindex - Rebuild the search table")
+	if s.handlers.Auth != nil {
+		log.Printf("[ADMIN]   POST /admin/users - Create a login user")
+	}
+	return srv, nil
+}
@@ -1,57 +1,302 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"sync"
 
-	"github.com/gorilla/mux"
-	"github.com/jmoiron/sqlx"
-	"github.com/rs/cors"
-
+	"sample-backend/internal/auth"
 	"sample-backend/internal/config"
 	"sample-backend/internal/handlers"
+	"sample-backend/internal/hooks"
+	"sample-backend/internal/middleware"
 )
 
+// Handlers はサーバーが公開するハンドラー (app パッケージで組み立てて渡す)
+type Handlers struct {
+	Product  *handlers.ProductHandler
+	Search   *handlers.SearchHandler
+	Supplier *handlers.SupplierHandler
+	QR       *handlers.QRHandler
+	// 製品詳細に添えるおすすめ
+	Recommendation *handlers.RecommendationHandler
+	// 製品の Q&A (回答の投稿と承認は管理用リスナーでも公開する)
+	Question *handlers.QuestionHandler
+	// 製品の変化の SSE
+	Events *handlers.EventsHandler
+	// 製品の在庫 (増減は API キーか admin のユーザーに限る)
+	Stock *handlers.StockHandler
+	// 製品のエクスポート (API キーか admin のユーザーに限る)
+	Export *handlers.ExportHandler
+	// 製品のレビュー
+	Review *handlers.ReviewHandler
+	// 再入荷・値下がりの通知の購読
+	Alert *handlers.AlertHandler
+	// 検索用テーブルの作り直し (管理用リスナー)
+	Reindex *handlers.ReindexHandler
+	// タイムセール (登録と削除は管理用リスナー)
+	Sale *handlers.SaleHandler
+	// JWT のログイン (ユーザーの登録は管理用リスナー)。署名鍵が未設定なら nil
+	Auth *handlers.AuthHandler
+	// ロードバランサー向けの healthz / readyz
+	Probe *handlers.ProbeHandler
+}
+
 type Server struct {
-	config *config.Config
-	db     *sqlx.DB
+	config   *config.Config
+	handlers Handlers
+	// JWT の署名鍵 (未設定なら nil)
+	keys *auth.KeySet
+	// リクエストの各段階の通知先
+	hooks *hooks.Registry
+	// 転送ヘッダーを信頼するプロキシ (nil なら接続元の IP アドレスを使う)
+	proxies *middleware.ProxyTrust
+
+	// Start で作る。Shutdown で処理中のリクエストを待ってから止める
+	mu        sync.Mutex
+	public    *http.Server
+	admin     *http.Server
+	accessLog *middleware.AccessLogger
 }
 
-func New(cfg *config.Config, db *sqlx.DB) *Server {
+func New(cfg *config.Config, h Handlers, keys *auth.KeySet, hk *hooks.Registry) *Server {
 	return &Server{
-		config: cfg,
-		db:     db,
+		config:   cfg,
+		handlers: h,
+		keys:     keys,
+		hooks:    hk,
+		proxies:  middleware.NewProxyTrust(cfg.TrustProxyHeaders, cfg.TrustedProxyCIDRs),
 	}
 }
 
+// handle はルートのパターンを付けてハンドラーを登録する (スパン名やメトリクスのラベルに使う)
+func handle(mux *http.ServeMux, pattern string, h http.HandlerFunc) {
+	mux.Handle(pattern, middleware.Route(pattern, h))
+}
+
+// handleConditional は ETag で再検証できる GET のルートを登録する。Cache-Control はルートごとに設定できる
+func (s *Server) handleConditional(mux *http.ServeMux, pattern string, h http.HandlerFunc) {
+	mux.Handle(pattern, middleware.Route(pattern, middleware.Conditional(s.config.CacheControl[pattern])(h)))
+}
+
+// rateLimiter は設定からレート制限を作る。読めない設定は警告して読み飛ばす
+func (s *Server) rateLimiter(mux *http.ServeMux) *middleware.RateLimiter {
+	cfg := middleware.RateLimitConfig{Routes: map[string]middleware.RatePolicy{}, Mux: mux, TrustProxy: s.proxies}
+	if s.config.RateLimit != "" {
+		policy, err := middleware.ParseRatePolicy(s.config.RateLimit)
+		if err != nil {
+			log.Printf("[RATELIMIT WARN] Ignoring RATE_LIMIT: %v", err)
+		}
+		cfg.Default = policy
+	}
+	for route, v := range s.config.RateLimitRoutes {
+		policy, err := middleware.ParseRatePolicy(v)
+		if err != nil {
+			log.Printf("[RATELIMIT WARN] Ignoring RATE_LIMIT_ROUTES entry for %q: %v", route, err)
+			continue
+		}
+		cfg.Routes[route] = policy
+	}
+	return middleware.NewRateLimiter(cfg)
+}
+
+// Start はリスナーを起動し、Shutdown が呼ばれるまで戻らない。Shutdown で止めた場合は nil を返す
 func (s *Server) Start() error {
-	// ハンドラー初期化
-	productHandler := handlers.NewProductHandler(s.db)
-	searchHandler := handlers.NewSearchHandler(s.db)
+	productHandler := s.handlers.Product
+	searchHandler := s.handlers.Search
 
 	// ルーター設定
 	log.Println("[MAIN] Setting up routes...")
-	r := mux.NewRouter()
-	r.HandleFunc("/api/health", handlers.HealthHandler).Methods("GET")
-	r.HandleFunc("/api/products", productHandler.GetProducts).Methods("GET")
-	r.HandleFunc("/api/search", searchHandler.SearchProducts).Methods("POST")
+	r := http.NewServeMux()
+	handle(r, "GET /api/health", handlers.HealthHandler)
+	handle(r, "GET /healthz", s.handlers.Probe.Liveness)
+	handle(r, "GET /readyz", s.handlers.Probe.Readiness)
+	s.handleConditional(r, "GET /api/products", productHandler.GetProducts)
+	s.handleConditional(r, "GET /api/products/search", searchHandler.FullTextSearch)
+	s.handleConditional(r, "GET /api/products/facets", productHandler.GetFacets)
+	s.handleConditional(r, "GET /api/products/batch", productHandler.GetProductsBatch)
+	handle(r, "GET /api/products/events", s.handlers.Events.StreamProductEvents)
+	handle(r, "POST /api/products/batch", productHandler.GetProductsBatch)
+	s.handleConditional(r, "GET /api/products/{id}", productHandler.GetProduct)
+	handle(r, "POST /api/products", productHandler.CreateProduct)
+	handle(r, "GET /api/products/export", s.handlers.Export.ExportProducts)
+	handle(r, "PUT /api/products/{id}", productHandler.UpdateProduct)
+	handle(r, "DELETE /api/products/{id}", productHandler.DeleteProduct)
+	// /api/products/sku/{sku} は /api/products/{id}/qr などと衝突するため別の階層に置く
+	s.handleConditional(r, "GET /api/skus/{sku}", productHandler.GetProductBySKU)
+	handle(r, "GET /api/products/{id}/qr", s.handlers.QR.GetProductQR)
+	handle(r, "GET /api/products/{id}/recommendations", s.handlers.Recommendation.GetRecommendations)
+	handle(r, "GET /api/products/{id}/questions", s.handlers.Question.ListQuestions)
+	handle(r, "POST /api/products/{id}/questions", s.handlers.Question.AskQuestion)
+	handle(r, "POST /api/questions/{id}/answers", s.handlers.Question.SellerAnswer)
+	handle(r, "POST /api/questions/{id}/helpful", s.handlers.Question.MarkQuestionHelpful)
+	handle(r, "POST /api/answers/{id}/helpful", s.handlers.Question.MarkAnswerHelpful)
+	s.handleConditional(r, "GET /api/products/{id}/reviews", s.handlers.Review.ListReviews)
+	handle(r, "POST /api/products/{id}/reviews", s.handlers.Review.PostReview)
+	handle(r, "POST /api/products/{id}/stock/adjust", s.handlers.Stock.AdjustStock)
+	handle(r, "POST /api/products/{id}/reserve", s.handlers.Stock.Reserve)
+	handle(r, "POST /api/products/{id}/alerts", s.handlers.Alert.Subscribe)
+	handle(r, "GET /api/alerts/{token}", s.handlers.Alert.GetAlert)
+	handle(r, "DELETE /api/alerts/{token}", s.handlers.Alert.Unsubscribe)
+	handle(r, "GET /api/sales/active", s.handlers.Sale.GetActiveSales)
+	handle(r, "POST /api/search", searchHandler.SearchProducts)
+	if s.handlers.Auth != nil {
+		handle(r, "POST /api/auth/login", s.handlers.Auth.Login)
+	}
 
-	// CORS設定
-	log.Println("[MAIN] Configuring CORS...")
-	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders:   []string{"*"},
-		AllowCredentials: true,
+	// ミドルウェアは外側から順に並べる。設定で無効なものは nil にしておく
+	var chaos, apiKeyAuth, jwtAuth, rateLimit, anomaly middleware.Middleware
+
+	// 障害注入 (本番以外で明示的に有効にした場合のみ)
+	if s.config.ChaosActive() {
+		chaos = middleware.Chaos(middleware.ChaosConfig{
+			Latency:     s.config.ChaosLatency,
+			LatencyRate: s.config.ChaosLatencyRate,
+			DropRate:    s.config.ChaosDropRate,
+			DBErrorRate: s.config.ChaosDBErrorRate,
+		})
+	}
+
+	// API キー認証 (キーが設定されている場合のみ)
+	if len(s.config.APIKeys) > 0 {
+		lockout := auth.NewLockout(auth.LockoutConfig{
+			Threshold:    s.config.AuthLockoutThreshold,
+			BaseDuration: s.config.AuthLockoutBase,
+			MaxDuration:  s.config.AuthLockoutMax,
+		})
+		apiKeyAuth = middleware.NewAPIKeyAuth(s.config.APIKeys, lockout, s.proxies).Middleware
+	}
+
+	// JWT 認証 (署名鍵が設定されている場合のみ)
+	if s.keys != nil {
+		jwtAuth = middleware.JWTAuth(s.keys)
+	}
+
+	// クライアントごとのレート制限 (設定がある場合のみ)
+	if s.config.RateLimit != "" || len(s.config.RateLimitRoutes) > 0 {
+		rateLimit = s.rateLimiter(r).Middleware
+	}
+
+	// 異常リクエストの検知
+	if s.config.AnomalyMode != "off" {
+		anomaly = middleware.NewAnomalyDetector(middleware.AnomalyConfig{
+			Mode:          s.config.AnomalyMode,
+			ThrottleLimit: s.config.AnomalyThrottleLimit,
+			TrustProxy:    s.proxies,
+		}).Middleware
+	}
+
+	// アクセスログ (リクエストのゴルーチンでは書き込まない)
+	accessLog := middleware.NewAccessLogger(middleware.AccessLogConfig{
+		SampleRate:    s.config.AccessLogSampleRate,
+		SlowThreshold: s.config.AccessLogSlowThreshold,
+		BufferSize:    s.config.AccessLogBufferSize,
 	})
 
-	handler := c.Handler(r)
+	log.Println("[MAIN] Configuring CORS...")
+	handler := middleware.Chain(
+		middleware.TrackRoute,
+		middleware.Metrics,
+		middleware.RequestID,
+		middleware.Hooks(s.hooks),
+		accessLog.Middleware,
+		middleware.Recover,
+		middleware.Tracing,
+		middleware.SecurityHeaders(middleware.SecurityHeadersConfig{
+			ContentSecurityPolicy: s.config.ContentSecurityPolicy,
+			FrameOptions:          s.config.FrameOptions,
+			ReferrerPolicy:        s.config.ReferrerPolicy,
+			CSPOverrides:          s.config.CSPOverrides,
+		}),
+		middleware.CORS(middleware.CORSConfig{
+			AllowedOrigins:   s.config.CORSAllowedOrigins,
+			AllowedMethods:   s.config.CORSAllowedMethods,
+			AllowedHeaders:   s.config.CORSAllowedHeaders,
+			ExposedHeaders:   s.config.CORSExposedHeaders,
+			MaxAge:           s.config.CORSMaxAge,
+			AllowCredentials: s.config.CORSAllowCredentials,
+		}),
+		anomaly,
+		apiKeyAuth,
+		jwtAuth,
+		rateLimit,
+		chaos,
+		middleware.JSONHeaders,
+	)(r)
+
+	public := &http.Server{Addr: ":" + s.config.Port, Handler: handler}
+	admin, err := s.adminServer()
+	if err != nil {
+		log.Printf("[ADMIN ERROR] Admin listener failed: %v", err)
+	}
+	s.mu.Lock()
+	s.public, s.admin, s.accessLog = public, admin, accessLog
+	s.mu.Unlock()
+
+	// 管理用リスナーは別ポートで起動する
+	if admin != nil {
+		go func() {
+			if err := admin.ListenAndServeTLS(s.config.AdminTLSCert, s.config.AdminTLSKey); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("[ADMIN ERROR] Admin listener failed: %v", err)
+			}
+		}()
+	}
 
 	log.Printf("[MAIN] Server starting on port %s...", s.config.Port)
 	log.Printf("[MAIN] Available endpoints:")
 	log.Printf("[MAIN]   GET  /api/health  - Health check")
+	log.Printf("[MAIN]   GET  /healthz, /readyz - Liveness and readiness (pings the database)")
 	log.Printf("[MAIN]   GET  /api/products - Get products with pagination")
+	log.Printf("[MAIN]   GET  /api/products/facets - Product counts per category, brand and price range")
+	log.Printf("[MAIN]   GET/POST /api/products/batch - Get up to 200 products by id")
+	log.Printf("[MAIN]   GET  /api/products/events - Server-Sent Events for product changes")
+	log.Printf("[MAIN]   GET  /api/products/export - Stream the catalog as CSV or NDJSON (API key or admin)")
+	log.Printf("[MAIN]   GET  /api/products/{id} - Get a product")
+	log.Printf("[MAIN]   GET  /api/products/{id}/qr - QR code linking to the product page")
+	log.Printf("[MAIN]   GET  /api/products/{id}/recommendations - Products viewed together")
+	log.Printf("[MAIN]   GET/POST /api/products/{id}/questions - Product Q&A")
+	log.Printf("[MAIN]   POST /api/questions/{id}/answers - Answer a question (API key required)")
+	log.Printf("[MAIN]   POST /api/{questions,answers}/{id}/helpful - Mark as helpful")
+	log.Printf("[MAIN]   GET/POST /api/products/{id}/reviews - Product reviews and ratings")
+	log.Printf("[MAIN]   POST /api/products/{id}/stock/adjust - Restock or correct stock (API key or admin)")
+	log.Printf("[MAIN]   POST /api/products/{id}/reserve - Reserve stock (API key or login, 409 if insufficient)")
+	log.Printf("[MAIN]   POST /api/products/{id}/alerts - Subscribe to restock / price-drop alerts")
+	log.Printf("[MAIN]   GET/DELETE /api/alerts/{token} - Alert status and unsubscribe")
 	log.Printf("[MAIN]   POST /api/search  - Search products")
+	if s.handlers.Auth != nil {
+		log.Printf("[MAIN]   POST /api/auth/login - Issue a JWT (admin tokens can modify products)")
+	}
+
+	// Shutdown の後に ListenAndServe を呼んだ場合も ErrServerClosed で戻る
+	if err := public.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
 
-	return http.ListenAndServe(":"+s.config.Port, handler)
+// Shutdown は新しい接続の受け付けを止め、処理中のリクエストが終わるのを ctx の期限まで待つ。
+// その後、溜まっているアクセスログを書き出す
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	public, admin, accessLog := s.public, s.admin, s.accessLog
+	s.mu.Unlock()
+
+	var errs []error
+	if admin != nil {
+		if err := admin.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("admin listener: %w", err))
+		}
+	}
+	if public != nil {
+		if err := public.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("public listener: %w", err))
+		}
+	}
+	if accessLog != nil {
+		accessLog.Close()
+	}
+	return errors.Join(errs...)
 }
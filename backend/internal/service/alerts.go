@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+)
+
+// maxAlertTargetLength は通知先 (メールアドレス・URL) の最大文字数
+const maxAlertTargetLength = 2048
+
+// AlertService は再入荷・値下がりの通知の購読を受け付ける。
+// Webhook は任意の URL にサーバーから接続させることになるため、API キーで認証したクライアントだけに許可する
+type AlertService struct {
+	products         repository.ProductRepository
+	alerts           repository.AlertRepository
+	allowHTTPWebhook bool
+}
+
+func NewAlertService(products repository.ProductRepository, alerts repository.AlertRepository, allowHTTPWebhook bool) *AlertService {
+	return &AlertService{products: products, alerts: alerts, allowHTTPWebhook: allowHTTPWebhook}
+}
+
+// Subscribe は製品の通知を購読する。authenticated は API キーで認証したクライアントからの申し込みか
+func (s *AlertService) Subscribe(ctx context.Context, productID int, req models.AlertRequest, authenticated bool) (*models.AlertSubscription, error) {
+	if productID < 1 {
+		return nil, apperr.Validation("Invalid product id")
+	}
+	if req.Kind != models.AlertRestock && req.Kind != models.AlertPriceDrop {
+		return nil, apperr.Validation("kind must be restock or price_drop")
+	}
+	target, err := s.validateTarget(req.Channel, strings.TrimSpace(req.Target), authenticated)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := s.products.Get(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if req.TargetPrice != nil {
+		if req.Kind != models.AlertPriceDrop {
+			return nil, apperr.Validation("target_price is only valid for price_drop alerts")
+		}
+		if *req.TargetPrice <= 0 || *req.TargetPrice >= p.Price {
+			return nil, apperr.Validation("target_price must be between 0 and the current price")
+		}
+	}
+
+	token, err := newAlertToken()
+	if err != nil {
+		return nil, err
+	}
+	sub := &models.AlertSubscription{
+		ProductID:     productID,
+		Kind:          req.Kind,
+		Channel:       req.Channel,
+		Target:        target,
+		BaselinePrice: p.Price,
+		TargetPrice:   req.TargetPrice,
+		Status:        "active",
+		Token:         token,
+		Deliveries:    []models.AlertDelivery{},
+	}
+	if err := s.alerts.CreateSubscription(ctx, sub); err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to create alert for product %d: %v", productID, err)
+		return nil, err
+	}
+	reqlog.From(ctx).Printf("[NOTIFY] Subscription %d created: %s via %s for product %d", sub.ID, sub.Kind, sub.Channel, productID)
+	return sub, nil
+}
+
+func (s *AlertService) validateTarget(channel, target string, authenticated bool) (string, error) {
+	if target == "" || len(target) > maxAlertTargetLength {
+		return "", apperr.Validation("target is required")
+	}
+	switch channel {
+	case models.AlertEmail:
+		// 表示名付きの形式は受け付けず、アドレスだけにする
+		addr, err := mail.ParseAddress(target)
+		if err != nil || addr.Address != target {
+			return "", apperr.Validation("target must be an email address")
+		}
+		return target, nil
+	case models.AlertWebhook:
+		if !authenticated {
+			return "", apperr.Unauthorized("An API key is required for webhook alerts")
+		}
+		u, err := url.Parse(target)
+		if err != nil || u.Host == "" || (u.Scheme != "https" && !(s.allowHTTPWebhook && u.Scheme == "http")) {
+			return "", apperr.Validation("target must be an https URL")
+		}
+		return u.String(), nil
+	}
+	return "", apperr.Validation("channel must be email or webhook")
+}
+
+// Get は購読の状態と配信記録を返す
+func (s *AlertService) Get(ctx context.Context, token string) (*models.AlertSubscription, error) {
+	if !validAlertToken(token) {
+		return nil, repository.ErrAlertNotFound
+	}
+	return s.alerts.GetByToken(ctx, token)
+}
+
+// Unsubscribe は購読を解除する。配信待ちの通知も送らない
+func (s *AlertService) Unsubscribe(ctx context.Context, token string) error {
+	if !validAlertToken(token) {
+		return repository.ErrAlertNotFound
+	}
+	if err := s.alerts.Unsubscribe(ctx, token); err != nil {
+		return err
+	}
+	reqlog.From(ctx).Println("[NOTIFY] Subscription cancelled")
+	return nil
+}
+
+// newAlertToken は購読の確認と解除に使う推測できないトークン (32 文字の 16 進) を返す
+func newAlertToken() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+func validAlertToken(token string) bool {
+	if len(token) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
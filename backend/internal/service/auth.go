@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/auth"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+)
+
+const (
+	maxUsernameLength = 64
+	// パスワードの長さ (文字数)。上限はハッシュの計算に時間をかけさせないため
+	minPasswordLength = 12
+	maxPasswordLength = 256
+)
+
+// errInvalidCredentials はユーザー名かパスワードが違う。どちらが違うかは返さない
+var errInvalidCredentials = apperr.Unauthorized("Invalid username or password")
+
+// AuthService はユーザーの登録とログイン (JWT の発行) を扱う。
+// ログインの失敗が続いたユーザー名と IP アドレスは Lockout によって一定時間締め出す
+type AuthService struct {
+	users   repository.UserRepository
+	keys    *auth.KeySet
+	ttl     time.Duration
+	lockout *auth.Lockout
+}
+
+func NewAuthService(users repository.UserRepository, keys *auth.KeySet, ttl time.Duration, lockout *auth.Lockout) *AuthService {
+	return &AuthService{users: users, keys: keys, ttl: ttl, lockout: lockout}
+}
+
+// Login はユーザー名とパスワードを確かめてトークンを発行する。ip はロックアウトの判定に使う
+func (s *AuthService) Login(ctx context.Context, req models.LoginRequest, ip string) (*models.LoginResponse, error) {
+	if req.Username == "" || req.Password == "" {
+		return nil, apperr.Validation("username and password are required")
+	}
+	if utf8.RuneCountInString(req.Password) > maxPasswordLength {
+		return nil, errInvalidCredentials
+	}
+
+	keys := []string{"ip:" + ip, "user:" + strings.ToLower(req.Username)}
+	for _, k := range keys {
+		if remaining, locked := s.lockout.Locked(k); locked {
+			reqlog.From(ctx).Printf("[AUTH] Blocked login from locked %s (%v remaining)", k, remaining.Round(time.Second))
+			return nil, apperr.TooManyRequests("Too many failed login attempts", remaining)
+		}
+	}
+
+	u, err := s.users.GetByUsername(ctx, req.Username)
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		auth.CheckDummyPassword(req.Password)
+	case err != nil:
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to get user: %v", err)
+		return nil, err
+	}
+	if u == nil || !auth.CheckPassword(u.PasswordHash, req.Password) {
+		for _, k := range keys {
+			if d := s.lockout.Fail(k); d > 0 {
+				reqlog.From(ctx).Printf("[AUTH] Locked %s for %v after repeated login failures", k, d)
+			}
+		}
+		reqlog.From(ctx).Printf("[AUTH] Failed login for %q from %s", req.Username, ip)
+		return nil, errInvalidCredentials
+	}
+	for _, k := range keys {
+		s.lockout.Succeed(k)
+	}
+
+	token, _, err := s.keys.Issue(auth.User{ID: u.ID, Username: u.Username, Role: u.Role}, s.ttl)
+	if err != nil {
+		reqlog.From(ctx).Printf("[AUTH ERROR] Failed to issue token: %v", err)
+		return nil, err
+	}
+	reqlog.From(ctx).Printf("[AUTH] User %q logged in (role=%s)", u.Username, u.Role)
+	return &models.LoginResponse{
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresIn: int(s.ttl.Seconds()),
+		User:      *u,
+	}, nil
+}
+
+// CreateUser はユーザーを登録する (管理用)。パスワードはハッシュにして保存する
+func (s *AuthService) CreateUser(ctx context.Context, req models.UserRequest) (*models.User, error) {
+	if !validUsername(req.Username) {
+		return nil, apperr.Validation(fmt.Sprintf("username must be 1 to %d letters, digits, '.', '_' or '-'", maxUsernameLength))
+	}
+	if n := utf8.RuneCountInString(req.Password); n < minPasswordLength || n > maxPasswordLength {
+		return nil, apperr.Validation(fmt.Sprintf("password must be %d to %d characters", minPasswordLength, maxPasswordLength))
+	}
+	role := req.Role
+	if role == "" {
+		role = auth.RoleUser
+	}
+	if role != auth.RoleAdmin && role != auth.RoleUser {
+		return nil, apperr.Validation("role must be admin or user")
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+	u := &models.User{Username: req.Username, PasswordHash: hash, Role: role}
+	if err := s.users.Create(ctx, u); err != nil {
+		if errors.Is(err, apperr.ErrConflict) {
+			return nil, apperr.Conflict("Username is already in use", err)
+		}
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to create user: %v", err)
+		return nil, err
+	}
+	reqlog.From(ctx).Printf("[AUTH] Created user %q (role=%s)", u.Username, u.Role)
+	return u, nil
+}
+
+func validUsername(name string) bool {
+	if name == "" || len(name) > maxUsernameLength {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
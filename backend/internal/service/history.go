@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+)
+
+// HistoryService は製品の過去の時点の内容を返す (注文時の価格の表示や問い合わせ対応に使う)
+type HistoryService struct {
+	history repository.ProductHistoryRepository
+}
+
+func NewHistoryService(history repository.ProductHistoryRepository) *HistoryService {
+	return &HistoryService{history: history}
+}
+
+// GetProductAsOf は時刻 asOf の時点の製品の内容を返す
+func (s *HistoryService) GetProductAsOf(ctx context.Context, id int, asOf time.Time) (*models.ProductRevision, error) {
+	if id < 1 {
+		return nil, apperr.Validation("Invalid product id")
+	}
+
+	ctx, span := tracer.Start(ctx, "database_product_history_query")
+	defer span.End()
+	span.SetAttributes(attribute.Int("product.id", id), attribute.String("as_of", asOf.Format(time.RFC3339)))
+
+	rev, err := s.history.AsOf(ctx, id, asOf)
+	if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to get product %d as of %s: %v", id, asOf.Format(time.RFC3339), err)
+		span.SetAttributes(attribute.String("error", err.Error()))
+	}
+	return rev, err
+}
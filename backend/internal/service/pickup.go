@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"math"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+)
+
+const (
+	// DefaultPickupRadiusKM は near だけを指定したときの検索半径
+	DefaultPickupRadiusKM = 10.0
+	// maxPickupRadiusKM は検索半径の上限
+	maxPickupRadiusKM = 100.0
+	// maxPickupStores は位置で探す店舗数の上限 (近い順)
+	maxPickupStores = 20
+)
+
+// PickupQuery は店舗での受け取りによる絞り込み。StoreID か (Latitude, Longitude, RadiusKM) のどちらかを指定する
+type PickupQuery struct {
+	StoreID   int
+	Near      bool
+	Latitude  float64
+	Longitude float64
+	RadiusKM  float64
+}
+
+// SetStores は店舗での受け取りによる絞り込みに使う StoreRepository を設定する
+func (s *ProductService) SetStores(stores repository.StoreRepository) {
+	s.stores = stores
+}
+
+// resolveStores は絞り込みの対象になる店舗を返す。位置で探した場合は近い順
+func (s *ProductService) resolveStores(ctx context.Context, q PickupQuery) ([]models.Store, error) {
+	if s.stores == nil {
+		return nil, apperr.Validation("Store filters are not available")
+	}
+
+	if !q.Near {
+		if q.StoreID < 1 {
+			return nil, apperr.Validation("Invalid store id")
+		}
+		store, err := s.stores.Get(ctx, q.StoreID)
+		if err != nil {
+			return nil, err
+		}
+		return []models.Store{*store}, nil
+	}
+
+	if math.IsNaN(q.Latitude) || q.Latitude < -90 || q.Latitude > 90 || math.IsNaN(q.Longitude) || q.Longitude < -180 || q.Longitude > 180 {
+		return nil, apperr.Validation("near must be a valid latitude,longitude")
+	}
+	if math.IsNaN(q.RadiusKM) || q.RadiusKM <= 0 || q.RadiusKM > maxPickupRadiusKM {
+		return nil, apperr.Validation("radius must be greater than 0 and at most 100 (km)")
+	}
+
+	ctx, span := tracer.Start(ctx, "database_nearby_stores_query")
+	defer span.End()
+	stores, err := s.stores.Nearby(ctx, q.Latitude, q.Longitude, q.RadiusKM, maxPickupStores)
+	if err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to find nearby stores: %v", err)
+		return nil, err
+	}
+	return stores, nil
+}
+
+// attachPickup は製品ごとに在庫のある店舗を付けた一覧を返す。
+// products はキャッシュと共有していることがあるため複製してから書き込む
+func (s *ProductService) attachPickup(ctx context.Context, products []models.Product, stores []models.Store) ([]models.Product, error) {
+	if len(products) == 0 {
+		return products, nil
+	}
+
+	productIDs := make([]int, len(products))
+	for i, p := range products {
+		productIDs[i] = p.ID
+	}
+	storeIDs := make([]int, len(stores))
+	for i, st := range stores {
+		storeIDs[i] = st.ID
+	}
+
+	ctx, span := tracer.Start(ctx, "database_store_stock_query")
+	defer span.End()
+	stock, err := s.stores.Stock(ctx, productIDs, storeIDs)
+	if err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to get store stock: %v", err)
+		return nil, err
+	}
+
+	quantities := make(map[[2]int]int, len(stock))
+	for _, st := range stock {
+		quantities[[2]int{st.ProductID, st.StoreID}] = st.Quantity
+	}
+
+	out := append([]models.Product(nil), products...)
+	for i := range out {
+		out[i].Pickup = []models.PickupAvailability{}
+		// stores は近い順に並んでいるので、その順に並べる
+		for _, st := range stores {
+			if qty, ok := quantities[[2]int{out[i].ID, st.ID}]; ok {
+				out[i].Pickup = append(out[i].Pickup, models.PickupAvailability{
+					StoreID:    st.ID,
+					StoreName:  st.Name,
+					DistanceKM: st.DistanceKM,
+					Quantity:   qty,
+				})
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/reqlog"
+)
+
+// MaxBatchIDs は 1 回のまとめての取得で指定できる ID の数
+const MaxBatchIDs = 200
+
+// ErrTooManyBatchIDs は指定した ID が MaxBatchIDs を超えた (apperr.ErrValidation)
+var ErrTooManyBatchIDs = apperr.Validation("ids must contain at most 200 product ids")
+
+// GetProducts は ids の製品を 1 回のクエリでまとめて返す (カートなど、製品を 1 件ずつ読むと N+1 になる画面向け)。
+// 重複した ID は最初の 1 つだけを数え、結果はリクエストの順に並べる
+func (s *ProductService) GetProducts(ctx context.Context, ids []int) (*models.BatchProductsResponse, error) {
+	unique := make([]int, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if id < 1 {
+			return nil, apperr.Validation("Invalid product id")
+		}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, apperr.Validation("ids is required")
+	}
+	if len(unique) > MaxBatchIDs {
+		return nil, ErrTooManyBatchIDs
+	}
+
+	ctx, span := tracer.Start(ctx, "database_product_batch_query")
+	defer span.End()
+	span.SetAttributes(attribute.Int("requested_count", len(unique)))
+
+	products, err := s.repo.GetMany(ctx, unique)
+	if err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to get %d products: %v", len(unique), err)
+		span.SetAttributes(attribute.String("error", err.Error()))
+		return nil, err
+	}
+
+	byID := make(map[int]int, len(products))
+	for i := range products {
+		byID[products[i].ID] = i
+	}
+	response := &models.BatchProductsResponse{
+		Products: make(models.ProductsByID, 0, len(products)),
+		NotFound: []int{},
+	}
+	for _, id := range unique {
+		i, ok := byID[id]
+		if !ok {
+			response.NotFound = append(response.NotFound, id)
+			continue
+		}
+		response.Products = append(response.Products, products[i])
+	}
+	response.Products = s.sales.Apply(response.Products, time.Now())
+	span.SetAttributes(attribute.Int("returned_count", len(response.Products)), attribute.Int("not_found_count", len(response.NotFound)))
+	return response, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/errgroup"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+)
+
+// maxFacetValues はカテゴリ・ブランドごとに返す値の最大数 (件数の多い順)
+const maxFacetValues = 50
+
+// facetPriceBounds は価格帯の区切り (円)
+var facetPriceBounds = []float64{5000, 10000, 30000, 50000, 100000, 200000}
+
+// FacetsRequest は絞り込みの候補の集計条件。一覧と同じ絞り込みを指定できる (並び順は無視する)
+type FacetsRequest struct {
+	Filter repository.ListFilter
+	Pickup *PickupQuery
+}
+
+// Facets はカテゴリ・ブランド・価格帯ごとの製品数を返す。
+// 各項目は自身の絞り込みを外して数えるので、選んでいる値以外に切り替えた場合の件数もわかる
+func (s *ProductService) Facets(ctx context.Context, req FacetsRequest) (*models.FacetsResponse, error) {
+	ctx, span := tracer.Start(ctx, "product_facets")
+	defer span.End()
+
+	filter := req.Filter
+	filter.Sort = ""
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedFrom.Before(filter.CreatedTo) {
+		return nil, apperr.Validation("created_from must be before created_to")
+	}
+	if err := normalizeListFilter(&filter); err != nil {
+		return nil, err
+	}
+
+	resp := &models.FacetsResponse{Categories: []models.FacetCount{}, Brands: []models.FacetCount{}}
+	if req.Pickup != nil {
+		stores, err := s.resolveStores(ctx, *req.Pickup)
+		if err != nil {
+			return nil, err
+		}
+		filter.StoreIDs = make([]int, len(stores))
+		for i, st := range stores {
+			filter.StoreIDs[i] = st.ID
+		}
+	}
+	if !filter.IsZero() {
+		resp.Filters = appliedFilters(filter)
+	}
+	span.SetAttributes(attribute.Bool("facets.filtered", !filter.IsZero()))
+
+	// 近くに店舗が無ければ DB を読まずにすべて 0 件で返す
+	if req.Pickup != nil && len(filter.StoreIDs) == 0 {
+		resp.PriceRanges = repository.PriceBuckets(facetPriceBounds)
+		return resp, nil
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		f := filter
+		f.Category = ""
+		var err error
+		resp.Categories, err = s.countBy(gctx, f, repository.FacetCategory)
+		return err
+	})
+	g.Go(func() error {
+		f := filter
+		f.Brand = ""
+		var err error
+		resp.Brands, err = s.countBy(gctx, f, repository.FacetBrand)
+		return err
+	})
+	g.Go(func() error {
+		f := filter
+		f.MinPrice, f.MaxPrice = nil, nil
+		cctx, qspan := tracer.Start(gctx, "database_facet_query")
+		defer qspan.End()
+		qspan.SetAttributes(attribute.String("facet", "price"), attribute.Int("facet.buckets", len(facetPriceBounds)+1))
+
+		buckets, err := s.repo.CountByPrice(cctx, f, facetPriceBounds)
+		if err != nil {
+			reqlog.From(ctx).Printf("[DB ERROR] Failed to count products by price: %v", err)
+			qspan.SetAttributes(attribute.String("error", err.Error()))
+			return err
+		}
+		resp.PriceRanges = buckets
+		return nil
+	})
+	if err := g.Wait(); err != nil {
+		span.SetAttributes(attribute.String("error", err.Error()))
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("facets.categories", len(resp.Categories)), attribute.Int("facets.brands", len(resp.Brands)))
+	return resp, nil
+}
+
+// countBy は column の値ごとの製品数を子スパンの中で数える
+func (s *ProductService) countBy(ctx context.Context, filter repository.ListFilter, column string) ([]models.FacetCount, error) {
+	cctx, span := tracer.Start(ctx, "database_facet_query")
+	defer span.End()
+	span.SetAttributes(attribute.String("facet", column))
+
+	counts, err := s.repo.CountBy(cctx, filter, column, maxFacetValues)
+	if err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to count products by %s: %v", column, err)
+		span.SetAttributes(attribute.String("error", err.Error()))
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("facet.values", len(counts)))
+	return counts, nil
+}
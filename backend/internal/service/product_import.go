@@ -0,0 +1,294 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/events"
+	"sample-backend/internal/models"
+	"sample-backend/internal/reqlog"
+)
+
+// 一括登録の形式
+const (
+	ImportCSV    = "csv"
+	ImportNDJSON = "ndjson"
+)
+
+const (
+	// importBatchSize は 1 つのトランザクションで登録する行数
+	importBatchSize = 1000
+	// maxImportErrors はレスポンスに含める行ごとのエラーの最大数
+	maxImportErrors = 1000
+	// maxImportLineBytes は NDJSON の 1 行の最大バイト数
+	maxImportLineBytes = 1 << 20
+)
+
+// ErrImportTooLarge はアップロードされたファイルが上限を超えた (apperr.ErrValidation)。
+// ハンドラーは読み取りの上限に達したときにこのエラーを返す io.Reader を渡す
+var ErrImportTooLarge = apperr.Validation("The uploaded file is too large")
+
+// importColumns は CSV のヘッダーに指定できる列名。required の列は必ず含める
+var importColumns = map[string]bool{
+	"name":        true,
+	"category":    true,
+	"brand":       true,
+	"model":       true,
+	"price":       true,
+	"description": false,
+	"sku":         false,
+}
+
+// ImportProducts は CSV (ヘッダー行つき) または NDJSON の製品を読みながら importBatchSize 行ずつ登録する。
+// ファイル全体をメモリに載せないよう、読んだ行はバッチを登録したら捨てる。
+// 検証に通らない行と SKU が重複する行は登録せずに結果に残し、DB の障害やファイルの形式の誤りでは中断する
+// (中断した場合も、それまでに登録したバッチは取り消さない)
+func (s *ProductService) ImportProducts(ctx context.Context, format string, r io.Reader) (*models.ImportSummary, error) {
+	ctx, span := tracer.Start(ctx, "import_products")
+	defer span.End()
+	span.SetAttributes(attribute.String("import.format", format))
+
+	im := &importer{s: s, summary: &models.ImportSummary{Errors: []models.ImportRowError{}}, skus: map[string]int{}}
+	var err error
+	switch format {
+	case ImportCSV:
+		err = im.readCSV(ctx, r)
+	case ImportNDJSON:
+		err = im.readNDJSON(ctx, r)
+	default:
+		return nil, apperr.Validation("format must be csv or ndjson")
+	}
+	if err == nil {
+		err = im.flush(ctx)
+	}
+	span.SetAttributes(attribute.Int("import.inserted", im.summary.Inserted), attribute.Int("import.skipped", im.summary.Skipped))
+
+	if im.summary.Inserted > 0 {
+		s.InvalidateCache()
+		s.events.Publish(events.Event{Type: events.ProductsImported, Count: im.summary.Inserted})
+	}
+	if err != nil {
+		reqlog.From(ctx).Printf("[IMPORT ERROR] Import stopped after %d products: %v", im.summary.Inserted, err)
+		span.SetAttributes(attribute.String("error", err.Error()))
+		return nil, err
+	}
+	reqlog.From(ctx).Printf("[IMPORT] Imported %d products (%d skipped)", im.summary.Inserted, im.summary.Skipped)
+	// SKU の重複はバッチの登録時に見つかるので、行番号の順に並べ直す
+	sort.SliceStable(im.summary.Errors, func(i, j int) bool { return im.summary.Errors[i].Line < im.summary.Errors[j].Line })
+	return im.summary, nil
+}
+
+// importer は 1 回の一括登録の途中の状態
+type importer struct {
+	s       *ProductService
+	summary *models.ImportSummary
+
+	batch []models.Product
+	lines []int
+	// このファイルで既に使った SKU → その行番号
+	skus map[string]int
+}
+
+// readCSV はヘッダー行で列を決めてから 1 行ずつ読む
+func (im *importer) readCSV(ctx context.Context, r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return apperr.Validation("CSV must start with a header row")
+	}
+	if err != nil {
+		return importReadError(err)
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		// Excel で保存した CSV は先頭に BOM が付く
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := importColumns[name]; !ok {
+			return apperr.Validation(fmt.Sprintf("unknown CSV column %q", name))
+		}
+		if _, dup := index[name]; dup {
+			return apperr.Validation(fmt.Sprintf("CSV column %q appears more than once", name))
+		}
+		index[name] = i
+	}
+	for name, required := range importColumns {
+		if _, ok := index[name]; required && !ok {
+			return apperr.Validation(fmt.Sprintf("CSV column %q is required", name))
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := index[name]; ok {
+			return record[i]
+		}
+		return ""
+	}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// 列数の違いや引用符の誤りはその行だけを飛ばす
+			im.rowError(parseErr.StartLine, apperr.Validation(parseErr.Err.Error()))
+			continue
+		}
+		if err != nil {
+			return importReadError(err)
+		}
+		line, _ := cr.FieldPos(0)
+
+		req := models.ProductRequest{
+			Name:        field(record, "name"),
+			Category:    field(record, "category"),
+			Brand:       field(record, "brand"),
+			Model:       field(record, "model"),
+			Description: field(record, "description"),
+			SKU:         field(record, "sku"),
+		}
+		if v := strings.TrimSpace(field(record, "price")); v != "" {
+			price, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				im.rowError(line, apperr.Validation("price must be a number"))
+				continue
+			}
+			req.Price = &price
+		}
+		if err := im.add(ctx, line, req); err != nil {
+			return err
+		}
+	}
+}
+
+// readNDJSON は 1 行に 1 つの製品 (JSON オブジェクト) を読む。空行は飛ばす
+func (im *importer) readNDJSON(ctx context.Context, r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+	line := 0
+	for sc.Scan() {
+		line++
+		b := sc.Bytes()
+		if len(bytes.TrimSpace(b)) == 0 {
+			continue
+		}
+		var req models.ProductRequest
+		if err := json.Unmarshal(b, &req); err != nil {
+			im.rowError(line, apperr.Validation("invalid JSON"))
+			continue
+		}
+		if err := im.add(ctx, line, req); err != nil {
+			return err
+		}
+	}
+	if errors.Is(sc.Err(), bufio.ErrTooLong) {
+		return apperr.Validation(fmt.Sprintf("line %d is longer than %d bytes", line+1, maxImportLineBytes))
+	}
+	return importReadError(sc.Err())
+}
+
+// importReadError はファイルの読み取りのエラーをクライアントに返すエラーにする
+func importReadError(err error) error {
+	var parseErr *csv.ParseError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &parseErr):
+		return apperr.Validation(fmt.Sprintf("invalid CSV at line %d: %v", parseErr.StartLine, parseErr.Err))
+	case errors.Is(err, ErrImportTooLarge):
+		return err
+	}
+	return apperr.Validation("Failed to read the uploaded file")
+}
+
+// add は 1 行を検証してバッチに加え、バッチが埋まれば登録する
+func (im *importer) add(ctx context.Context, line int, req models.ProductRequest) error {
+	p, err := validateProduct(req)
+	if err != nil {
+		im.rowError(line, err)
+		return nil
+	}
+	if p.SKU != "" {
+		if first, ok := im.skus[p.SKU]; ok {
+			im.rowError(line, apperr.Validation(fmt.Sprintf("sku is already used on line %d", first)))
+			return nil
+		}
+		im.skus[p.SKU] = line
+	}
+
+	im.batch = append(im.batch, *p)
+	im.lines = append(im.lines, line)
+	if len(im.batch) >= importBatchSize {
+		return im.flush(ctx)
+	}
+	return nil
+}
+
+// flush はバッチを登録する。既存の製品と SKU が重複する行があればバッチ全体が失敗するので、
+// そのバッチだけ 1 行ずつ登録し直して重複した行を飛ばす
+func (im *importer) flush(ctx context.Context) error {
+	if len(im.batch) == 0 {
+		return nil
+	}
+	defer func() {
+		im.batch, im.lines = im.batch[:0], im.lines[:0]
+	}()
+
+	ctx, span := tracer.Start(ctx, "database_product_batch_insert")
+	defer span.End()
+	span.SetAttributes(attribute.Int("batch_size", len(im.batch)))
+
+	err := im.s.repo.CreateBatch(ctx, im.batch)
+	if err == nil {
+		im.summary.Inserted += len(im.batch)
+		return nil
+	}
+	if !errors.Is(err, apperr.ErrConflict) {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to insert product batch: %v", err)
+		return err
+	}
+
+	span.SetAttributes(attribute.Bool("batch_retried", true))
+	for i := range im.batch {
+		p := im.batch[i]
+		err := im.s.repo.Create(ctx, &p)
+		switch {
+		case err == nil:
+			im.summary.Inserted++
+		case errors.Is(err, apperr.ErrConflict):
+			im.rowError(im.lines[i], apperr.Validation("SKU is already in use"))
+		default:
+			reqlog.From(ctx).Printf("[DB ERROR] Failed to insert product: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// rowError は登録しなかった行を記録する。エラーの一覧は maxImportErrors 件までにする
+func (im *importer) rowError(line int, err error) {
+	im.summary.Skipped++
+	if len(im.summary.Errors) >= maxImportErrors {
+		im.summary.ErrorsTruncated = true
+		return
+	}
+	msg, ok := apperr.Message(err)
+	if !ok {
+		msg = err.Error()
+	}
+	im.summary.Errors = append(im.summary.Errors, models.ImportRowError{Line: line, Error: msg})
+}
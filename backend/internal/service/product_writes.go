@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/events"
+	"sample-backend/internal/models"
+	"sample-backend/internal/notify"
+	"sample-backend/internal/reqlog"
+)
+
+// 製品の項目の最大文字数 (products の列の長さ。説明は TEXT に収まる範囲で制限する)
+const (
+	maxProductNameLength        = 255
+	maxProductFieldLength       = 100
+	maxProductDescriptionLength = 5000
+	// maxProductPrice は DECIMAL(10, 2) に収まる最大の価格
+	maxProductPrice = 99999999.99
+)
+
+// SetNotifier は価格を変えたときに通知する Worker を設定する (値下がりの通知用)
+func (s *ProductService) SetNotifier(w *notify.Worker) {
+	s.notifier = w
+}
+
+// SetEvents は製品の登録・更新・削除を配る Hub を設定する (SSE の購読用)
+func (s *ProductService) SetEvents(h *events.Hub) {
+	s.events = h
+}
+
+// validateProduct は登録・更新の内容を検証し、前後の空白を除いた製品を返す
+func validateProduct(req models.ProductRequest) (*models.Product, error) {
+	p := &models.Product{
+		Name:        strings.TrimSpace(req.Name),
+		Category:    strings.TrimSpace(req.Category),
+		Brand:       strings.TrimSpace(req.Brand),
+		Model:       strings.TrimSpace(req.Model),
+		Description: strings.TrimSpace(req.Description),
+	}
+
+	for _, f := range []struct {
+		name, value string
+		max         int
+	}{
+		{"name", p.Name, maxProductNameLength},
+		{"category", p.Category, maxProductFieldLength},
+		{"brand", p.Brand, maxProductFieldLength},
+		{"model", p.Model, maxProductFieldLength},
+	} {
+		if f.value == "" {
+			return nil, apperr.Validation(f.name + " is required")
+		}
+		if utf8.RuneCountInString(f.value) > f.max {
+			return nil, apperr.Validation(fmt.Sprintf("%s must be at most %d characters", f.name, f.max))
+		}
+	}
+	if utf8.RuneCountInString(p.Description) > maxProductDescriptionLength {
+		return nil, apperr.Validation(fmt.Sprintf("description must be at most %d characters", maxProductDescriptionLength))
+	}
+
+	if req.Price == nil {
+		return nil, apperr.Validation("price is required")
+	}
+	if math.IsNaN(*req.Price) || *req.Price < 0 || *req.Price > maxProductPrice {
+		return nil, apperr.Validation("price must be between 0 and 99999999.99")
+	}
+	// DECIMAL(10, 2) に丸めた値を保存・比較に使う
+	p.Price = math.Round(*req.Price*100) / 100
+
+	if strings.TrimSpace(req.SKU) != "" {
+		sku, err := NormalizeSKU(req.SKU)
+		if err != nil {
+			return nil, err
+		}
+		p.SKU = sku
+	}
+	return p, nil
+}
+
+// CreateProduct は製品を登録する。SKU がほかの製品と重複する場合は、その製品を添えた apperr.ErrConflict を返す
+func (s *ProductService) CreateProduct(ctx context.Context, req models.ProductRequest) (*models.Product, error) {
+	p, err := validateProduct(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := tracer.Start(ctx, "database_product_insert")
+	defer span.End()
+
+	if err := s.repo.Create(ctx, p); err != nil {
+		return nil, s.writeError(ctx, "create product", p.SKU, err)
+	}
+	reqlog.From(ctx).Printf("[API] Product %d created", p.ID)
+	s.InvalidateCache()
+	s.events.Publish(events.Event{Type: events.ProductCreated, ProductID: p.ID, Product: p})
+	return p, nil
+}
+
+// UpdateProduct は製品の内容を置き換える。価格が変わった場合は値下がりの通知に知らせる
+func (s *ProductService) UpdateProduct(ctx context.Context, id int, req models.ProductRequest) (*models.Product, error) {
+	if id < 1 {
+		return nil, apperr.Validation("Invalid product id")
+	}
+	p, err := validateProduct(req)
+	if err != nil {
+		return nil, err
+	}
+	p.ID = id
+
+	ctx, span := tracer.Start(ctx, "database_product_update")
+	defer span.End()
+
+	old, err := s.repo.Update(ctx, p)
+	if err != nil {
+		return nil, s.writeError(ctx, "update product", p.SKU, err)
+	}
+	reqlog.From(ctx).Printf("[API] Product %d updated", id)
+	s.InvalidateCache()
+	e := events.Event{Type: events.ProductUpdated, ProductID: id, Product: p}
+	if old.Price != p.Price {
+		s.notifier.Publish(notify.Event{Kind: notify.EventPriceChanged, ProductID: id, Price: p.Price})
+		e.PreviousPrice = &old.Price
+	}
+	s.events.Publish(e)
+	return p, nil
+}
+
+// DeleteProduct は製品を削除する
+func (s *ProductService) DeleteProduct(ctx context.Context, id int) error {
+	if id < 1 {
+		return apperr.Validation("Invalid product id")
+	}
+
+	ctx, span := tracer.Start(ctx, "database_product_delete")
+	defer span.End()
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return s.writeError(ctx, "delete product", "", err)
+	}
+	reqlog.From(ctx).Printf("[API] Product %d deleted", id)
+	s.InvalidateCache()
+	s.events.Publish(events.Event{Type: events.ProductDeleted, ProductID: id})
+	return nil
+}
+
+// writeError は書き込みのエラーをログに出し、SKU の重複であれば重複した製品を添えたエラーにする
+func (s *ProductService) writeError(ctx context.Context, op, sku string, err error) error {
+	switch {
+	case errors.Is(err, apperr.ErrConflict) && sku != "":
+		return s.skuConflict(ctx, sku, err)
+	case errors.Is(err, apperr.ErrNotFound), errors.Is(err, apperr.ErrConflict):
+		return err
+	}
+	reqlog.From(ctx).Printf("[DB ERROR] Failed to %s: %v", op, err)
+	return err
+}
+
+// skuConflict は SKU の重複を、その SKU を使っている製品を添えたエラーにする
+func (s *ProductService) skuConflict(ctx context.Context, sku string, cause error) error {
+	existing, err := s.repo.GetBySKU(ctx, sku)
+	if err != nil {
+		// 衝突した製品が直後に削除・変更された場合は製品を添えずに返す
+		return apperr.Conflict("SKU is already in use", cause)
+	}
+	return apperr.ConflictWith("SKU is already in use", existing, cause)
+}
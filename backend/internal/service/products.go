@@ -0,0 +1,544 @@
+// Package service は製品一覧・検索の業務ロジック (ページ計算、入力の検証、キャッシュの利用) をまとめる。
+// HTTP ハンドラーはリクエストとレスポンスの変換だけを行い、ここを呼び出す
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/cache"
+	"sample-backend/internal/config"
+	"sample-backend/internal/events"
+	"sample-backend/internal/models"
+	"sample-backend/internal/notify"
+	"sample-backend/internal/pagination"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/rerank"
+	"sample-backend/internal/sales"
+)
+
+// maxKeywordLength は検索キーワードの最大文字数
+const maxKeywordLength = 100
+
+// maxFilterLength は一覧の絞り込みに指定できるカテゴリ・ブランドの最大文字数 (列の長さ)
+const maxFilterLength = 100
+
+// maxSKULength は SKU の最大文字数 (products.sku の列の長さ)
+const maxSKULength = 64
+
+// skuPattern は SKU に使える文字 (英数字で始まり、英数字と . _ - が続く)
+var skuPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9._-]*$`)
+
+// ListRequest は製品一覧の条件。Paging が範囲外なら既定値に丸める
+type ListRequest struct {
+	Paging pagination.Request
+	Filter repository.ListFilter
+	// 指定すると受け取れる店舗に在庫がある製品だけを、店舗ごとの在庫数を付けて返す
+	Pickup *PickupQuery
+	// 前のページの next_cursor。指定するとページ番号ではなくカーソルの続きから ID 順に返す
+	Cursor string
+	// カーソル方式でも総件数 (count / totalPages) を数える。ページ番号による一覧では常に数える
+	IncludeTotal bool
+}
+
+// ListResult は製品一覧の結果。ページキャッシュに当たった場合は Cached に
+// エンコード済みのレスポンスが入り、それ以外は Response が入る
+type ListResult struct {
+	Page     int
+	Limit    int
+	Response *models.PaginatedResponse
+	Cached   *cache.Page
+}
+
+type ProductService struct {
+	repo   repository.ProductRepository
+	pages  *cache.PageCache
+	ranker rerank.Ranker
+	sales  *sales.Catalog
+	stores repository.StoreRepository
+	// 価格の変更を通知する先 (nil なら通知しない)
+	notifier *notify.Worker
+	// 製品の登録・更新・削除を配る先 (nil なら配らない)
+	events *events.Hub
+}
+
+func NewProductService(repo repository.ProductRepository, cfg *config.Config) *ProductService {
+	s := &ProductService{repo: repo}
+
+	// 先頭ページへのアクセスが大半を占めるため、既定の一覧は事前生成した JSON を返す
+	if cfg.PageCachePages > 0 {
+		s.pages = cache.NewPageCache(cfg.PageCachePages, pagination.DefaultLimit, cfg.PageCacheTTL, s.buildPage)
+		s.pages.SetCompression(cfg.CacheCompressThreshold)
+		s.pages.Start()
+	}
+
+	return s
+}
+
+// SetRanker は検索結果のページを並べ替える Ranker を設定する。nil ならリポジトリの並び順のまま返す
+func (s *ProductService) SetRanker(r rerank.Ranker) {
+	s.ranker = r
+}
+
+// SetSales は一覧・検索・詳細の製品に開催中のタイムセールを付ける Catalog を設定する
+func (s *ProductService) SetSales(c *sales.Catalog) {
+	s.sales = c
+}
+
+// InvalidateCache は事前生成した一覧ページを破棄して作り直させる。製品の書き込み後に呼び出す
+func (s *ProductService) InvalidateCache() {
+	if s.pages != nil {
+		s.pages.Invalidate()
+	}
+}
+
+// productsResponse は一覧の結果を製品一覧のレスポンスの形にする
+func productsResponse(p *pagination.Page[models.Product]) *models.PaginatedResponse {
+	return &models.PaginatedResponse{
+		Products:   p.Items,
+		Page:       p.Page,
+		Limit:      p.Limit,
+		TotalPages: p.TotalPages,
+		Count:      p.Count,
+	}
+}
+
+// ListProducts は製品一覧を返す。絞り込みのない既定の一覧はページキャッシュから返す。
+// Cursor を指定した場合は OFFSET を使わず、カーソルの ID より後の製品を返す
+func (s *ProductService) ListProducts(ctx context.Context, req ListRequest) (*ListResult, error) {
+	paging := req.Paging.Normalize(pagination.DefaultLimit, pagination.MaxLimit)
+	filter := req.Filter
+
+	if !filter.CreatedFrom.IsZero() && !filter.CreatedTo.IsZero() && !filter.CreatedFrom.Before(filter.CreatedTo) {
+		return nil, apperr.Validation("created_from must be before created_to")
+	}
+	if err := normalizeListFilter(&filter); err != nil {
+		return nil, err
+	}
+
+	// カーソルは ID なので、ID 順以外の並びでは続きの位置を表せない
+	cursorMode := req.Cursor != ""
+	var afterID int
+	if cursorMode {
+		if filter.Sort != "" {
+			return nil, apperr.Validation("cursor can only be used with sort=id_asc")
+		}
+		var err error
+		if afterID, err = pagination.DecodeCursor(req.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &ListResult{Page: paging.Page, Limit: paging.Limit}
+
+	var stores []models.Store
+	if req.Pickup != nil {
+		var err error
+		if stores, err = s.resolveStores(ctx, *req.Pickup); err != nil {
+			return nil, err
+		}
+		// 近くに店舗が無ければ DB を読まずに空の一覧を返す
+		if len(stores) == 0 {
+			result.Response = &models.PaginatedResponse{Products: []models.Product{}, Page: paging.Page, Limit: paging.Limit, Filters: appliedFilters(filter)}
+			return result, nil
+		}
+		filter.StoreIDs = make([]int, len(stores))
+		for i, st := range stores {
+			filter.StoreIDs[i] = st.ID
+		}
+	}
+
+	if cursorMode {
+		response, err := s.fetchAfter(ctx, afterID, paging.Limit, req.IncludeTotal, filter)
+		if err != nil {
+			return nil, err
+		}
+		if stores != nil {
+			if response.Products, err = s.attachPickup(ctx, response.Products, stores); err != nil {
+				return nil, err
+			}
+		}
+		if !filter.IsZero() {
+			response.Filters = appliedFilters(filter)
+		}
+		result.Page = 0
+		result.Response = response
+		return result, nil
+	}
+
+	if s.pages != nil && filter.IsZero() {
+		cached, ok := s.pages.Get(paging.Page, paging.Limit)
+		// セールの開始・終了・変更より前に生成したページは使わず、作り直させる
+		if ok && s.sales != nil && cached.BuiltAt.Before(s.sales.ChangedAt(time.Now())) {
+			s.pages.Invalidate()
+			ok = false
+		}
+		cache.Record(ctx, "page", ok)
+		if ok {
+			result.Cached = cached
+			return result, nil
+		}
+	}
+
+	response, err := s.fetchPage(ctx, paging, filter)
+	if err != nil {
+		return nil, err
+	}
+	if stores != nil {
+		if response.Products, err = s.attachPickup(ctx, response.Products, stores); err != nil {
+			return nil, err
+		}
+	}
+	if !filter.IsZero() {
+		response.Filters = appliedFilters(filter)
+	}
+	result.Response = response
+	return result, nil
+}
+
+// normalizeListFilter はカテゴリ・ブランド・価格帯・並び順の指定を検証する
+func normalizeListFilter(f *repository.ListFilter) error {
+	f.Category, f.Brand = strings.TrimSpace(f.Category), strings.TrimSpace(f.Brand)
+	if utf8.RuneCountInString(f.Category) > maxFilterLength || utf8.RuneCountInString(f.Brand) > maxFilterLength {
+		return apperr.Validation("category and brand must be at most 100 characters")
+	}
+	for _, p := range []*float64{f.MinPrice, f.MaxPrice} {
+		if p != nil && (math.IsNaN(*p) || math.IsInf(*p, 0) || *p < 0) {
+			return apperr.Validation("min_price and max_price must be 0 or greater")
+		}
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		return apperr.Validation("min_price must not be greater than max_price")
+	}
+	if !repository.IsListSort(f.Sort) {
+		return apperr.Validation("sort must be one of id_asc, price_asc, price_desc, created_at_asc, created_at_desc")
+	}
+	// 既定の並び順を明示した場合も既定の一覧 (ページキャッシュの対象) として扱う
+	if f.Sort == repository.SortIDAsc {
+		f.Sort = ""
+	}
+	return nil
+}
+
+// appliedFilters はレスポンスに含める、適用した絞り込みと並び順
+func appliedFilters(f repository.ListFilter) *models.AppliedFilters {
+	applied := &models.AppliedFilters{
+		Category: f.Category,
+		Brand:    f.Brand,
+		MinPrice: f.MinPrice,
+		MaxPrice: f.MaxPrice,
+		StoreIDs: f.StoreIDs,
+		Sort:     f.Sort,
+	}
+	if applied.Sort == "" {
+		applied.Sort = repository.SortIDAsc
+	}
+	if !f.CreatedFrom.IsZero() {
+		applied.CreatedFrom = &f.CreatedFrom
+	}
+	if !f.CreatedTo.IsZero() {
+		applied.CreatedTo = &f.CreatedTo
+	}
+	return applied
+}
+
+// fetchPage は指定ページの製品と総件数を取得してレスポンスを組み立てる
+func (s *ProductService) fetchPage(ctx context.Context, paging pagination.Request, filter repository.ListFilter) (*models.PaginatedResponse, error) {
+	count := func(ctx context.Context) (int, error) {
+		// 総件数取得用の子スパン（親のコンテキストを使用）
+		cctx, countSpan := tracer.Start(ctx, "database_count_query")
+		defer countSpan.End()
+		countSpan.SetAttributes(attribute.String("query_type", "COUNT"))
+
+		totalCount, err := s.repo.Count(cctx, filter)
+		if err != nil {
+			reqlog.From(ctx).Printf("[DB ERROR] Failed to get total count: %v", err)
+			countSpan.SetAttributes(attribute.String("error", err.Error()))
+			return 0, err
+		}
+		countSpan.SetAttributes(attribute.Int("total_count", totalCount))
+		return totalCount, nil
+	}
+
+	list := func(ctx context.Context, limit, offset int) ([]models.Product, error) {
+		pctx, productsSpan := tracer.Start(ctx, "database_products_query")
+		defer productsSpan.End()
+		if productsSpan.IsRecording() {
+			productsSpan.SetAttributes(
+				attribute.String("query_type", "SELECT"),
+				attribute.Int("limit", limit),
+				attribute.Int("offset", offset),
+			)
+		}
+
+		products, err := s.repo.List(pctx, filter, limit, offset)
+		if err != nil {
+			reqlog.From(ctx).Printf("[DB ERROR] Failed to get products: %v", err)
+			productsSpan.SetAttributes(attribute.String("error", err.Error()))
+			return nil, err
+		}
+		productsSpan.SetAttributes(attribute.Int("returned_count", len(products)))
+		return products, nil
+	}
+
+	page, err := pagination.Fetch(ctx, paging, count, list)
+	if err != nil {
+		return nil, err
+	}
+	page.Items = s.sales.Apply(page.Items, time.Now())
+	response := productsResponse(page)
+	// ID 順の一覧では、次のページからカーソル方式に切り替えられるようカーソルも返す
+	if filter.Sort == "" && page.Page < page.TotalPages && len(page.Items) > 0 {
+		response.NextCursor = pagination.EncodeCursor(page.Items[len(page.Items)-1].ID)
+	}
+	return response, nil
+}
+
+// fetchAfter は afterID より後の製品を ID 順に 1 ページ分取得してレスポンスを組み立てる。
+// 総件数は includeTotal のときだけ数える
+func (s *ProductService) fetchAfter(ctx context.Context, afterID, limit int, includeTotal bool, filter repository.ListFilter) (*models.PaginatedResponse, error) {
+	var count pagination.CountFunc
+	if includeTotal {
+		count = func(ctx context.Context) (int, error) {
+			cctx, countSpan := tracer.Start(ctx, "database_count_query")
+			defer countSpan.End()
+			countSpan.SetAttributes(attribute.String("query_type", "COUNT"))
+
+			totalCount, err := s.repo.Count(cctx, filter)
+			if err != nil {
+				reqlog.From(ctx).Printf("[DB ERROR] Failed to get total count: %v", err)
+				countSpan.SetAttributes(attribute.String("error", err.Error()))
+				return 0, err
+			}
+			countSpan.SetAttributes(attribute.Int("total_count", totalCount))
+			return totalCount, nil
+		}
+	}
+
+	list := func(ctx context.Context, afterID, limit int) ([]models.Product, error) {
+		pctx, productsSpan := tracer.Start(ctx, "database_products_after_query")
+		defer productsSpan.End()
+		if productsSpan.IsRecording() {
+			productsSpan.SetAttributes(
+				attribute.String("query_type", "SELECT"),
+				attribute.Int("limit", limit),
+				attribute.Int("after_id", afterID),
+			)
+		}
+
+		products, err := s.repo.ListAfter(pctx, filter, afterID, limit)
+		if err != nil {
+			reqlog.From(ctx).Printf("[DB ERROR] Failed to get products after %d: %v", afterID, err)
+			productsSpan.SetAttributes(attribute.String("error", err.Error()))
+			return nil, err
+		}
+		productsSpan.SetAttributes(attribute.Int("returned_count", len(products)))
+		return products, nil
+	}
+
+	page, err := pagination.FetchAfter(ctx, afterID, limit, count, list, func(p models.Product) int { return p.ID })
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.PaginatedResponse{
+		Products:   s.sales.Apply(page.Items, time.Now()),
+		Limit:      page.Limit,
+		NextCursor: page.NextCursor,
+	}
+	if page.Count != nil {
+		response.Count = *page.Count
+		response.TotalPages = pagination.TotalPages(*page.Count, page.Limit)
+	}
+	return response, nil
+}
+
+// buildPage はページキャッシュ用にレスポンスを JSON まで組み立てる
+func (s *ProductService) buildPage(ctx context.Context, page, limit int) (*cache.Page, error) {
+	ctx, span := tracer.Start(ctx, "build_cached_page")
+	defer span.End()
+	span.SetAttributes(attribute.Int("page", page), attribute.Int("limit", limit))
+
+	response, err := s.fetchPage(ctx, pagination.Request{Page: page, Limit: limit}, repository.ListFilter{})
+	if err != nil {
+		span.SetAttributes(attribute.String("error", err.Error()))
+		return nil, err
+	}
+
+	// json.Encoder と同じく末尾に改行を付けて通常のレスポンスと揃える
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(response); err != nil {
+		return nil, err
+	}
+
+	return &cache.Page{
+		Body:       buf.Bytes(),
+		TotalCount: response.Count,
+		TotalPages: response.TotalPages,
+		Returned:   len(response.Products),
+	}, nil
+}
+
+// GetProduct は ID を指定して製品を返す。存在しなければ apperr.ErrNotFound に分類されるエラーを返す
+func (s *ProductService) GetProduct(ctx context.Context, id int) (*models.Product, error) {
+	if id < 1 {
+		return nil, apperr.Validation("Invalid product id")
+	}
+
+	ctx, span := tracer.Start(ctx, "database_product_query")
+	defer span.End()
+	span.SetAttributes(attribute.Int("product.id", id))
+
+	p, err := s.repo.Get(ctx, id)
+	if err != nil && !errors.Is(err, apperr.ErrNotFound) {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to get product %d: %v", id, err)
+		span.SetAttributes(attribute.String("error", err.Error()))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.sales.ApplyOne(p, time.Now()), nil
+}
+
+// NormalizeSKU は SKU の前後の空白を除いて大文字にそろえる。使えない文字を含む場合は apperr.ErrValidation
+// (照合順序が大文字と小文字を区別しないため、保存する値もそろえておく)
+func NormalizeSKU(sku string) (string, error) {
+	sku = strings.ToUpper(strings.TrimSpace(sku))
+	if sku == "" || len(sku) > maxSKULength || !skuPattern.MatchString(sku) {
+		return "", apperr.Validation("sku must be 1 to 64 characters of letters, digits, '.', '_' or '-'")
+	}
+	return sku, nil
+}
+
+// GetProductBySKU は SKU を指定して製品を返す
+func (s *ProductService) GetProductBySKU(ctx context.Context, sku string) (*models.Product, error) {
+	sku, err := NormalizeSKU(sku)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := tracer.Start(ctx, "database_product_sku_query")
+	defer span.End()
+	span.SetAttributes(attribute.String("product.sku", sku))
+
+	p, err := s.repo.GetBySKU(ctx, sku)
+	if err != nil {
+		if !errors.Is(err, apperr.ErrNotFound) {
+			reqlog.From(ctx).Printf("[DB ERROR] Failed to get product by sku %q: %v", sku, err)
+			span.SetAttributes(attribute.String("error", err.Error()))
+		}
+		return nil, err
+	}
+	return s.sales.ApplyOne(p, time.Now()), nil
+}
+
+// SearchProducts は列を指定したキーワード検索の結果を返す
+func (s *ProductService) SearchProducts(ctx context.Context, req models.SearchRequest) (*models.PaginatedResponse, error) {
+	if !repository.IsSearchColumn(req.Column) {
+		reqlog.From(ctx).Printf("[ERROR] Invalid search column: %s", req.Column)
+		return nil, apperr.Validation("Invalid search column")
+	}
+
+	paging := pagination.Request{Page: req.Page, Limit: req.Limit}.Normalize(pagination.DefaultLimit, pagination.MaxLimit)
+	reqlog.From(ctx).Printf("[API] Validated params - page: %d, limit: %d, offset: %d", paging.Page, paging.Limit, paging.Offset())
+
+	// ワイルドカード文字はリポジトリ側でエスケープして文字どおりに一致させる
+	keyword := strings.TrimSpace(req.Keyword)
+	if utf8.RuneCountInString(keyword) > maxKeywordLength {
+		reqlog.From(ctx).Printf("[ERROR] Search keyword too long: %d chars", utf8.RuneCountInString(keyword))
+		return nil, apperr.Validation("Search keyword too long")
+	}
+	query := repository.SearchQuery{Column: req.Column, Keyword: keyword}
+
+	// 総件数と検索結果を並列に取得する
+	count := func(ctx context.Context) (int, error) {
+		countCtx, countSpan := tracer.Start(ctx, "database_search_count_query")
+		defer countSpan.End()
+		totalCount, err := s.repo.SearchCount(countCtx, query)
+		if err != nil {
+			reqlog.From(ctx).Printf("[DB ERROR] Failed to get search count: %v", err)
+			return 0, err
+		}
+		reqlog.From(ctx).Printf("[DB] Search result count: %d", totalCount)
+		return totalCount, nil
+	}
+
+	list := func(ctx context.Context, limit, offset int) ([]models.Product, error) {
+		reqlog.From(ctx).Printf("[DB] Executing search query with limit: %d, offset: %d", limit, offset)
+		listCtx, listSpan := tracer.Start(ctx, "database_search_query")
+		defer listSpan.End()
+		products, err := s.repo.Search(listCtx, query, limit, offset)
+		if err != nil {
+			reqlog.From(ctx).Printf("[DB ERROR] Failed to execute search query: %v", err)
+			return nil, err
+		}
+		reqlog.From(ctx).Printf("[DB] Retrieved %d search results", len(products))
+		return products, nil
+	}
+
+	page, err := pagination.Fetch(ctx, paging, count, list)
+	if err != nil {
+		return nil, err
+	}
+	// 並べ替えはページ内の候補だけが対象 (ページをまたいだ順位は変えない)
+	if s.ranker != nil {
+		rerankCtx, rerankSpan := tracer.Start(ctx, "rerank_search_results")
+		page.Items = s.ranker.Rerank(rerankCtx, rerank.Query{Column: query.Column, Keyword: query.Keyword}, page.Items)
+		rerankSpan.End()
+	}
+	page.Items = s.sales.Apply(page.Items, time.Now())
+	return productsResponse(page), nil
+}
+
+// FullTextSearch はキーワードで製品名・カテゴリ・ブランド・型番・説明を全文検索し、関連度の高い順に返す
+func (s *ProductService) FullTextSearch(ctx context.Context, keyword string, paging pagination.Request) (*models.PaginatedResponse, error) {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return nil, apperr.Validation("q is required")
+	}
+	if utf8.RuneCountInString(keyword) > maxKeywordLength {
+		reqlog.From(ctx).Printf("[ERROR] Search keyword too long: %d chars", utf8.RuneCountInString(keyword))
+		return nil, apperr.Validation("Search keyword too long")
+	}
+	paging = paging.Normalize(pagination.DefaultLimit, pagination.MaxLimit)
+
+	count := func(ctx context.Context) (int, error) {
+		countCtx, countSpan := tracer.Start(ctx, "database_fulltext_count_query")
+		defer countSpan.End()
+		totalCount, err := s.repo.FullTextCount(countCtx, keyword)
+		if err != nil {
+			reqlog.From(ctx).Printf("[DB ERROR] Failed to get full-text search count: %v", err)
+			return 0, err
+		}
+		return totalCount, nil
+	}
+
+	list := func(ctx context.Context, limit, offset int) ([]models.Product, error) {
+		listCtx, listSpan := tracer.Start(ctx, "database_fulltext_query")
+		defer listSpan.End()
+		products, err := s.repo.FullText(listCtx, keyword, limit, offset)
+		if err != nil {
+			reqlog.From(ctx).Printf("[DB ERROR] Failed to execute full-text search: %v", err)
+			return nil, err
+		}
+		return products, nil
+	}
+
+	page, err := pagination.Fetch(ctx, paging, count, list)
+	if err != nil {
+		return nil, err
+	}
+	page.Items = s.sales.Apply(page.Items, time.Now())
+	return productsResponse(page), nil
+}
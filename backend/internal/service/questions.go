@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/cache"
+	"sample-backend/internal/models"
+	"sample-backend/internal/pagination"
+	"sample-backend/internal/recommend"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+)
+
+const (
+	maxQuestionAuthorLength = 50
+	maxQuestionBodyLength   = 2000
+	// 製品詳細に添える質問数のキャッシュ (詳細は閲覧のたびに呼ばれるため毎回は数えない)
+	questionCountTTL  = time.Minute
+	questionCountSize = 10000
+)
+
+// QuestionService は製品の Q&A を扱う。質問は誰でも投稿でき、管理者が承認したものだけを公開する。
+// 回答は出品者 (API キーで認証したクライアント) と管理者だけが投稿できる
+type QuestionService struct {
+	products  repository.ProductRepository
+	questions repository.QuestionRepository
+	counts    *cache.TTL[int, int]
+}
+
+func NewQuestionService(products repository.ProductRepository, questions repository.QuestionRepository) *QuestionService {
+	return &QuestionService{
+		products:  products,
+		questions: questions,
+		counts:    cache.NewTTL[int, int](questionCountTTL, questionCountSize),
+	}
+}
+
+// validatePost は投稿の前後の空白を除き、長さを検証する
+func validatePost(req models.PostRequest) (models.PostRequest, error) {
+	req.Author = strings.TrimSpace(req.Author)
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Author == "" || utf8.RuneCountInString(req.Author) > maxQuestionAuthorLength {
+		return req, apperr.Validation("author must be 1 to 50 characters")
+	}
+	if req.Body == "" || utf8.RuneCountInString(req.Body) > maxQuestionBodyLength {
+		return req, apperr.Validation("body must be 1 to 2000 characters")
+	}
+	return req, nil
+}
+
+// CountForProduct は製品の公開中の質問数を返す
+func (s *QuestionService) CountForProduct(ctx context.Context, productID int) (int, error) {
+	if n, ok := s.counts.Get(productID); ok {
+		return n, nil
+	}
+	n, err := s.questions.CountQuestions(ctx, productID, models.QuestionApproved)
+	if err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to count questions for product %d: %v", productID, err)
+		return 0, err
+	}
+	s.counts.Set(productID, n)
+	return n, nil
+}
+
+// ListQuestions は製品の公開中の質問を回答付きで返す
+func (s *QuestionService) ListQuestions(ctx context.Context, productID int, paging pagination.Request) (*models.QuestionsResponse, error) {
+	if productID < 1 {
+		return nil, apperr.Validation("Invalid product id")
+	}
+	if _, err := s.products.Get(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.list(ctx, productID, models.QuestionApproved, paging)
+}
+
+// ListForModeration は状態が status の質問を全製品から返す (管理用)
+func (s *QuestionService) ListForModeration(ctx context.Context, status string, paging pagination.Request) (*models.QuestionsResponse, error) {
+	if status == "" {
+		status = models.QuestionPending
+	}
+	if !validQuestionStatus(status) {
+		return nil, apperr.Validation("status must be one of pending, approved, rejected")
+	}
+	return s.list(ctx, 0, status, paging)
+}
+
+func (s *QuestionService) list(ctx context.Context, productID int, status string, paging pagination.Request) (*models.QuestionsResponse, error) {
+	paging = paging.Normalize(pagination.DefaultLimit, pagination.MaxLimit)
+
+	ctx, span := tracer.Start(ctx, "database_questions_query")
+	defer span.End()
+	span.SetAttributes(attribute.Int("product.id", productID), attribute.String("status", status))
+
+	count := func(ctx context.Context) (int, error) {
+		return s.questions.CountQuestions(ctx, productID, status)
+	}
+	list := func(ctx context.Context, limit, offset int) ([]models.Question, error) {
+		return s.questions.ListQuestions(ctx, productID, status, limit, offset)
+	}
+	page, err := pagination.Fetch(ctx, paging, count, list)
+	if err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to list questions: %v", err)
+		span.SetAttributes(attribute.String("error", err.Error()))
+		return nil, err
+	}
+
+	// ページ内の質問の回答は 1 回のクエリでまとめて取得する
+	ids := make([]int, len(page.Items))
+	for i, q := range page.Items {
+		ids[i] = q.ID
+	}
+	answers, err := s.questions.AnswersFor(ctx, ids)
+	if err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to get answers: %v", err)
+		return nil, err
+	}
+	for i := range page.Items {
+		page.Items[i].Answers = answers[page.Items[i].ID]
+		if page.Items[i].Answers == nil {
+			page.Items[i].Answers = []models.Answer{}
+		}
+	}
+	span.SetAttributes(attribute.Int("returned_count", len(page.Items)))
+
+	return &models.QuestionsResponse{
+		Questions:  page.Items,
+		Page:       page.Page,
+		Limit:      page.Limit,
+		TotalPages: page.TotalPages,
+		Count:      page.Count,
+	}, nil
+}
+
+// AskQuestion は質問を承認待ちとして登録する
+func (s *QuestionService) AskQuestion(ctx context.Context, productID int, req models.PostRequest) (*models.Question, error) {
+	if productID < 1 {
+		return nil, apperr.Validation("Invalid product id")
+	}
+	req, err := validatePost(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.products.Get(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	q := &models.Question{
+		ProductID: productID,
+		Author:    req.Author,
+		Body:      req.Body,
+		Status:    models.QuestionPending,
+		Answers:   []models.Answer{},
+	}
+	if err := s.questions.CreateQuestion(ctx, q); err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to create question for product %d: %v", productID, err)
+		return nil, err
+	}
+	reqlog.From(ctx).Printf("[QA] Question %d posted for product %d", q.ID, productID)
+	return q, nil
+}
+
+// Moderate は質問の公開状態を変える (管理用)
+func (s *QuestionService) Moderate(ctx context.Context, questionID int, status string) error {
+	if !validQuestionStatus(status) {
+		return apperr.Validation("status must be one of pending, approved, rejected")
+	}
+	q, err := s.questions.GetQuestion(ctx, questionID)
+	if err != nil {
+		return err
+	}
+	if err := s.questions.SetQuestionStatus(ctx, questionID, status); err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to moderate question %d: %v", questionID, err)
+		return err
+	}
+	s.counts.Delete(q.ProductID)
+	reqlog.From(ctx).Printf("[QA] Question %d: %s -> %s", questionID, q.Status, status)
+	return nil
+}
+
+// Answer は質問に回答する。role は models.AnswerBySeller か models.AnswerByAdmin
+func (s *QuestionService) Answer(ctx context.Context, questionID int, role string, req models.PostRequest) (*models.Answer, error) {
+	req, err := validatePost(req)
+	if err != nil {
+		return nil, err
+	}
+	// 出品者は公開中の質問にだけ回答できる (管理者は承認前に回答を付けてから公開してもよい)
+	q, err := s.questions.GetQuestion(ctx, questionID)
+	if err != nil {
+		return nil, err
+	}
+	if role == models.AnswerBySeller && q.Status != models.QuestionApproved {
+		return nil, repository.ErrQuestionNotFound
+	}
+
+	a := &models.Answer{QuestionID: questionID, Author: req.Author, Role: role, Body: req.Body}
+	if err := s.questions.CreateAnswer(ctx, a); err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to create answer for question %d: %v", questionID, err)
+		return nil, err
+	}
+	reqlog.From(ctx).Printf("[QA] Answer %d posted for question %d by %s %s", a.ID, questionID, role, req.Author)
+	return a, nil
+}
+
+// DeleteAnswer は回答を削除する (管理用)
+func (s *QuestionService) DeleteAnswer(ctx context.Context, answerID int) error {
+	if err := s.questions.DeleteAnswer(ctx, answerID); err != nil {
+		return err
+	}
+	reqlog.From(ctx).Printf("[QA] Answer %d deleted", answerID)
+	return nil
+}
+
+// MarkHelpful は公開中の質問・回答に「役に立った」を記録する。同じ閲覧者からは 1 回だけ数える
+func (s *QuestionService) MarkHelpful(ctx context.Context, target string, id int, visitorID string) error {
+	if !recommend.ValidVisitorID(visitorID) {
+		return apperr.Validation("X-Visitor-ID header is required")
+	}
+	counted, err := s.questions.Vote(ctx, target, id, visitorID)
+	if err != nil {
+		return err
+	}
+	if !counted {
+		reqlog.From(ctx).Printf("[QA] Duplicate helpful vote for %s %d ignored", target, id)
+	}
+	return nil
+}
+
+func validQuestionStatus(status string) bool {
+	switch status {
+	case models.QuestionPending, models.QuestionApproved, models.QuestionRejected:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+)
+
+const (
+	// DefaultRecommendationLimit は返すおすすめの既定の件数
+	DefaultRecommendationLimit = 10
+	// MaxRecommendationLimit は返すおすすめの最大件数
+	MaxRecommendationLimit = 50
+)
+
+// RecommendationService は製品詳細に添える「この製品を見た人はこんな製品も見ています」を返す
+type RecommendationService struct {
+	products repository.ProductRepository
+	recs     repository.RecommendationRepository
+}
+
+func NewRecommendationService(products repository.ProductRepository, recs repository.RecommendationRepository) *RecommendationService {
+	return &RecommendationService{products: products, recs: recs}
+}
+
+// GetRecommendations は製品のおすすめを返す。limit が範囲外なら既定値に丸める
+func (s *RecommendationService) GetRecommendations(ctx context.Context, id, limit int) (*models.RecommendationsResponse, error) {
+	if id < 1 {
+		return nil, apperr.Validation("Invalid product id")
+	}
+	if limit < 1 || limit > MaxRecommendationLimit {
+		limit = DefaultRecommendationLimit
+	}
+
+	// 存在しない製品は 404 にする (おすすめが空なのと区別する)
+	if _, err := s.products.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	ctx, span := tracer.Start(ctx, "database_recommendations_query")
+	defer span.End()
+	span.SetAttributes(attribute.Int("product.id", id), attribute.Int("limit", limit))
+
+	recs, err := s.recs.ForProduct(ctx, id, limit)
+	if err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to get recommendations for product %d: %v", id, err)
+		span.SetAttributes(attribute.String("error", err.Error()))
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("returned_count", len(recs)))
+
+	return &models.RecommendationsResponse{ProductID: id, Recommendations: recs}, nil
+}
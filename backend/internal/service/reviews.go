@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/pagination"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+)
+
+const (
+	minReviewRating       = 1
+	maxReviewRating       = 5
+	maxReviewTitleLength  = 100
+	maxReviewAuthorLength = 50
+	maxReviewBodyLength   = 2000
+)
+
+// ReviewService は製品のレビューを扱う。レビューは誰でも投稿でき、すぐに公開する。
+// 件数と平均は投稿時に製品へ集計するので、製品の一覧はレビューを数えずにそのまま返せる
+type ReviewService struct {
+	reviews repository.ReviewRepository
+	// レビューを登録したときに呼ぶ (集計が変わった製品の詳細・一覧・事前生成したページのキャッシュを捨てる)
+	onChange []func(productID int)
+}
+
+func NewReviewService(reviews repository.ReviewRepository) *ReviewService {
+	return &ReviewService{reviews: reviews}
+}
+
+// OnChange はレビューを登録したときに呼ぶ関数を登録する
+func (s *ReviewService) OnChange(fn func(productID int)) {
+	s.onChange = append(s.onChange, fn)
+}
+
+// validateReview は投稿の前後の空白を除き、評価と長さを検証する
+func validateReview(req models.ReviewRequest) (models.ReviewRequest, error) {
+	req.Author = strings.TrimSpace(req.Author)
+	req.Title = strings.TrimSpace(req.Title)
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Rating < minReviewRating || req.Rating > maxReviewRating {
+		return req, apperr.Validation("rating must be an integer from 1 to 5")
+	}
+	if req.Author == "" || utf8.RuneCountInString(req.Author) > maxReviewAuthorLength {
+		return req, apperr.Validation("author must be 1 to 50 characters")
+	}
+	if utf8.RuneCountInString(req.Title) > maxReviewTitleLength {
+		return req, apperr.Validation("title must be at most 100 characters")
+	}
+	if req.Body == "" || utf8.RuneCountInString(req.Body) > maxReviewBodyLength {
+		return req, apperr.Validation("body must be 1 to 2000 characters")
+	}
+	return req, nil
+}
+
+// ListReviews は製品のレビューを新しい順に、集計した件数と平均を添えて返す
+func (s *ReviewService) ListReviews(ctx context.Context, productID int, paging pagination.Request) (*models.ReviewsResponse, error) {
+	if productID < 1 {
+		return nil, apperr.Validation("Invalid product id")
+	}
+	paging = paging.Normalize(pagination.DefaultLimit, pagination.MaxLimit)
+
+	ctx, span := tracer.Start(ctx, "database_reviews_query")
+	defer span.End()
+	span.SetAttributes(attribute.Int("product.id", productID))
+
+	reviewCount, average, err := s.reviews.Summary(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	count := func(ctx context.Context) (int, error) {
+		return s.reviews.CountReviews(ctx, productID)
+	}
+	list := func(ctx context.Context, limit, offset int) ([]models.Review, error) {
+		return s.reviews.ListReviews(ctx, productID, limit, offset)
+	}
+	page, err := pagination.Fetch(ctx, paging, count, list)
+	if err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to list reviews for product %d: %v", productID, err)
+		span.SetAttributes(attribute.String("error", err.Error()))
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("returned_count", len(page.Items)))
+
+	return &models.ReviewsResponse{
+		ProductID:     productID,
+		ReviewCount:   reviewCount,
+		RatingAverage: average,
+		Reviews:       page.Items,
+		Page:          page.Page,
+		Limit:         page.Limit,
+		TotalPages:    page.TotalPages,
+		Count:         page.Count,
+	}, nil
+}
+
+// PostReview はレビューを登録し、製品の件数と平均を更新する
+func (s *ReviewService) PostReview(ctx context.Context, productID int, req models.ReviewRequest) (*models.Review, error) {
+	if productID < 1 {
+		return nil, apperr.Validation("Invalid product id")
+	}
+	req, err := validateReview(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := &models.Review{
+		ProductID: productID,
+		Author:    req.Author,
+		Rating:    req.Rating,
+		Title:     req.Title,
+		Body:      req.Body,
+	}
+	if err := s.reviews.CreateReview(ctx, rv); err != nil {
+		if !errors.Is(err, apperr.ErrNotFound) {
+			reqlog.From(ctx).Printf("[DB ERROR] Failed to create review for product %d: %v", productID, err)
+		}
+		return nil, err
+	}
+	reqlog.From(ctx).Printf("[REVIEW] Review %d (rating %d) posted for product %d", rv.ID, rv.Rating, productID)
+	for _, fn := range s.onChange {
+		fn(productID)
+	}
+	return rv, nil
+}
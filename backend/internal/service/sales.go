@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/models"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/sales"
+)
+
+const (
+	// maxSaleItems は 1 つのタイムセールに含められる製品数
+	maxSaleItems = 500
+	// maxSaleNameLength はタイムセール名の最大文字数
+	maxSaleNameLength = 100
+	// recentSalesLimit は管理用の一覧に返すセールの数
+	recentSalesLimit = 50
+)
+
+// SaleService はタイムセールの登録・削除と、開催中のセールの一覧を扱う
+type SaleService struct {
+	products repository.ProductRepository
+	sales    repository.SaleRepository
+	catalog  *sales.Catalog
+}
+
+func NewSaleService(products repository.ProductRepository, repo repository.SaleRepository, catalog *sales.Catalog) *SaleService {
+	return &SaleService{products: products, sales: repo, catalog: catalog}
+}
+
+// ActiveJSON は開催中と開始間近のセールをエンコードした JSON を返す (DB は読まない)
+func (s *SaleService) ActiveJSON() ([]byte, error) {
+	return s.catalog.ActiveJSON(time.Now())
+}
+
+// Recent は新しく登録した順にセールを返す (管理用)
+func (s *SaleService) Recent(ctx context.Context) ([]models.Sale, error) {
+	list, err := s.sales.Recent(ctx, recentSalesLimit)
+	if err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to list sales: %v", err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// Create はタイムセールを登録する。セール価格は登録時点の製品の価格より安くなければならない
+func (s *SaleService) Create(ctx context.Context, req models.SaleRequest) (*models.Sale, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxSaleNameLength {
+		return nil, apperr.Validation(fmt.Sprintf("name must be 1 to %d characters", maxSaleNameLength))
+	}
+	if req.StartsAt.IsZero() || req.EndsAt.IsZero() || !req.StartsAt.Before(req.EndsAt) {
+		return nil, apperr.Validation("starts_at must be before ends_at")
+	}
+	if len(req.Items) == 0 || len(req.Items) > maxSaleItems {
+		return nil, apperr.Validation(fmt.Sprintf("items must contain 1 to %d products", maxSaleItems))
+	}
+
+	sale := &models.Sale{Name: name, StartsAt: req.StartsAt, EndsAt: req.EndsAt, Items: make([]models.SaleItem, 0, len(req.Items))}
+	seen := make(map[int]bool, len(req.Items))
+	for _, item := range req.Items {
+		if item.ProductID < 1 {
+			return nil, apperr.Validation("Invalid product id")
+		}
+		if seen[item.ProductID] {
+			return nil, apperr.Validation(fmt.Sprintf("product %d is listed more than once", item.ProductID))
+		}
+		seen[item.ProductID] = true
+		if item.StockCap < 1 {
+			return nil, apperr.Validation("stock_cap must be at least 1")
+		}
+		p, err := s.products.Get(ctx, item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if item.SalePrice <= 0 || item.SalePrice >= p.Price {
+			return nil, apperr.Validation(fmt.Sprintf("sale_price for product %d must be between 0 and the current price", item.ProductID))
+		}
+		sale.Items = append(sale.Items, models.SaleItem{
+			ProductID:   p.ID,
+			ProductName: p.Name,
+			Price:       p.Price,
+			SalePrice:   item.SalePrice,
+			StockCap:    item.StockCap,
+		})
+	}
+
+	if err := s.sales.Create(ctx, sale); err != nil {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to create sale: %v", err)
+		return nil, err
+	}
+	reqlog.From(ctx).Printf("[SALES] Sale %d created: %d products, %s - %s", sale.ID, len(sale.Items),
+		sale.StartsAt.Format(time.RFC3339), sale.EndsAt.Format(time.RFC3339))
+	s.refresh(ctx)
+	return sale, nil
+}
+
+// Delete はタイムセールを削除する。開催中であれば直ちに通常価格に戻る
+func (s *SaleService) Delete(ctx context.Context, id int) error {
+	if id < 1 {
+		return apperr.Validation("Invalid sale id")
+	}
+	if err := s.sales.Delete(ctx, id); err != nil {
+		return err
+	}
+	reqlog.From(ctx).Printf("[SALES] Sale %d deleted", id)
+	s.refresh(ctx)
+	return nil
+}
+
+// refresh は変更を次の定期読み込みを待たずに反映する。失敗しても定期読み込みで反映されるのでエラーにはしない
+func (s *SaleService) refresh(ctx context.Context) {
+	if err := s.catalog.Refresh(ctx); err != nil {
+		reqlog.From(ctx).Printf("[SALES ERROR] Failed to refresh sales: %v", err)
+	}
+}
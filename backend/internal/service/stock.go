@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"sample-backend/internal/apperr"
+	"sample-backend/internal/events"
+	"sample-backend/internal/models"
+	"sample-backend/internal/notify"
+	"sample-backend/internal/repository"
+	"sample-backend/internal/reqlog"
+	"sample-backend/internal/sales"
+)
+
+const (
+	// maxStockDelta は 1 回の増減の上限 (入力の誤りで桁違いの在庫になるのを防ぐ)
+	maxStockDelta = 1000000
+	// maxReserveQuantity は 1 回に引き当てられる数
+	maxReserveQuantity = 1000
+)
+
+// StockService は製品の在庫を扱う。在庫が 0 から増えたら再入荷の通知に知らせ、
+// 在庫の変化は SSE の購読者にも配る
+type StockService struct {
+	stock    repository.StockRepository
+	notifier *notify.Worker
+	events   *events.Hub
+	// 開催中のセールの製品を引き当てるときはセールの在庫 (stock_cap) も確保する
+	sales *sales.Catalog
+	// 在庫を変えたときに呼ぶ (製品詳細・一覧・事前生成したページのキャッシュを捨てる)
+	onChange []func(productID int)
+}
+
+func NewStockService(stock repository.StockRepository, notifier *notify.Worker, hub *events.Hub) *StockService {
+	return &StockService{stock: stock, notifier: notifier, events: hub}
+}
+
+// SetSales はセールの在庫の確保に使う Catalog を設定する
+func (s *StockService) SetSales(c *sales.Catalog) {
+	s.sales = c
+}
+
+// OnChange は在庫を変えたときに呼ぶ関数を登録する
+func (s *StockService) OnChange(fn func(productID int)) {
+	s.onChange = append(s.onChange, fn)
+}
+
+// Adjust は在庫を delta だけ増減する (入荷・棚卸し)
+func (s *StockService) Adjust(ctx context.Context, productID int, req models.StockAdjustRequest) (*models.StockResponse, error) {
+	if productID < 1 {
+		return nil, apperr.Validation("Invalid product id")
+	}
+	if req.Delta == 0 || req.Delta > maxStockDelta || req.Delta < -maxStockDelta {
+		return nil, apperr.Validation("delta must be a non-zero integer between -1000000 and 1000000")
+	}
+
+	ctx, span := tracer.Start(ctx, "database_stock_adjust")
+	defer span.End()
+	span.SetAttributes(attribute.Int("product.id", productID), attribute.Int("stock.delta", req.Delta))
+
+	c, err := s.stock.AdjustStock(ctx, productID, req.Delta)
+	if err != nil {
+		return nil, s.stockError(ctx, "adjust stock", productID, err)
+	}
+	reqlog.From(ctx).Printf("[STOCK] Product %d stock adjusted %d -> %d", productID, c.Before, c.After)
+	s.changed(c)
+	return &models.StockResponse{ProductID: productID, Previous: c.Before, Stock: c.After}, nil
+}
+
+// Reserve は在庫から quantity を引き当てる。足りなければ在庫数を添えた apperr.ErrConflict (409)。
+// 製品がセール中ならセールの在庫も同じトランザクションで確保し、上限を使い切っていれば 409 を返す
+func (s *StockService) Reserve(ctx context.Context, productID int, req models.ReserveRequest) (*models.StockResponse, error) {
+	if productID < 1 {
+		return nil, apperr.Validation("Invalid product id")
+	}
+	if req.Quantity < 1 || req.Quantity > maxReserveQuantity {
+		return nil, apperr.Validation("quantity must be between 1 and 1000")
+	}
+
+	ctx, span := tracer.Start(ctx, "database_stock_reserve")
+	defer span.End()
+	span.SetAttributes(attribute.Int("product.id", productID), attribute.Int("stock.quantity", req.Quantity))
+
+	var sale *models.ProductSale
+	saleID := 0
+	if s.sales != nil {
+		if sale = s.sales.Lookup(productID, time.Now()); sale != nil {
+			saleID = sale.SaleID
+			span.SetAttributes(attribute.Int("sale.id", saleID))
+		}
+	}
+
+	c, err := s.stock.Reserve(ctx, productID, req.Quantity, saleID)
+	if err != nil {
+		if _, soldOut := apperr.Details(err).(repository.InsufficientSaleStock); soldOut {
+			// 売り切れたセールを表示し続けないよう読み込み直す
+			s.refreshSales()
+		}
+		return nil, s.stockError(ctx, "reserve stock", productID, err)
+	}
+	reqlog.From(ctx).Printf("[STOCK] Reserved %d of product %d (%d left)", req.Quantity, productID, c.After)
+	if c.SaleID > 0 && c.SaleRemaining == 0 {
+		reqlog.From(ctx).Printf("[STOCK] Sale %d sold out for product %d", c.SaleID, productID)
+		s.refreshSales()
+	}
+	s.changed(c)
+	return &models.StockResponse{ProductID: productID, Previous: c.Before, Stock: c.After, Sale: sale}, nil
+}
+
+// changed は在庫の変化を通知とキャッシュに反映する
+func (s *StockService) changed(c *repository.StockChange) {
+	if c.Before <= 0 && c.After > 0 {
+		s.notifier.Publish(notify.Event{Kind: notify.EventRestocked, ProductID: c.ProductID, Price: c.Price})
+	}
+	stock := c.After
+	s.events.Publish(events.Event{Type: events.ProductStockChanged, ProductID: c.ProductID, Stock: &stock})
+	for _, fn := range s.onChange {
+		fn(c.ProductID)
+	}
+}
+
+// refreshSales はセールを非同期で読み込み直す (売り切れたセールの価格を一覧・詳細から外す)
+func (s *StockService) refreshSales() {
+	go func() {
+		if err := s.sales.Refresh(context.Background()); err != nil {
+			log.Printf("[SALES ERROR] Failed to refresh sales: %v", err)
+		}
+	}()
+}
+
+func (s *StockService) stockError(ctx context.Context, op string, productID int, err error) error {
+	if !errors.Is(err, apperr.ErrNotFound) && !errors.Is(err, apperr.ErrConflict) {
+		reqlog.From(ctx).Printf("[DB ERROR] Failed to %s for product %d: %v", op, productID, err)
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("error", err.Error()))
+	}
+	return err
+}
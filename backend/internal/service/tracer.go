@@ -0,0 +1,6 @@
+package service
+
+import "go.opentelemetry.io/otel"
+
+// tracer はサービス層のトレーサー (ハンドラーと同じサービス名で記録する)
+var tracer = otel.Tracer("product-search-backend")
@@ -1,6 +1,7 @@
 package tracing
 
 import (
+	"context"
 	"log"
 
 	"go.opentelemetry.io/otel"
@@ -12,6 +13,9 @@ import (
 	"sample-backend/internal/config"
 )
 
+// provider は Init で作ったトレースプロバイダー (トレースが無効なら nil)
+var provider *sdktrace.TracerProvider
+
 func Init(cfg *config.Config) {
 	log.Println("[INIT] Initializing tracing...")
 	log.Printf("[INIT] TRACE_ENABLED: %t", cfg.TraceEnabled)
@@ -41,5 +45,14 @@ func Init(cfg *config.Config) {
 
 	// グローバル設定
 	otel.SetTracerProvider(tp)
+	provider = tp
 	log.Println("[INIT] Tracing enabled successfully")
 }
+
+// Shutdown はバッファに残っているスパンを送ってからエクスポーターを止める
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
@@ -0,0 +1,35 @@
+#!/bin/bash
+# MySQL を使い捨てのコンテナで起動し、TEST_DSN を設定して結合テストを実行する。
+# スキーマと初期データ (mysql/init) は internal/dbtest が最初の接続で適用する。
+#   ./scripts/integration-test.sh                     # すべてのパッケージ
+#   ./scripts/integration-test.sh ./internal/integration/... -run TestGetProducts
+set -euo pipefail
+
+cd "$(dirname "$0")/.."
+
+IMAGE="${TEST_MYSQL_IMAGE:-mysql:9.4}"
+PORT="${TEST_MYSQL_PORT:-3307}"
+NAME="sample-backend-test-db-$$"
+
+docker run -d --rm --name "$NAME" \
+    -e MYSQL_ROOT_PASSWORD=mysql -e MYSQL_DATABASE=sample_db \
+    -p "127.0.0.1:${PORT}:3306" "$IMAGE" >/dev/null
+trap 'docker stop "$NAME" >/dev/null' EXIT
+
+echo "[TEST] Waiting for MySQL on 127.0.0.1:${PORT}..."
+for i in $(seq 1 60); do
+    # 初期化中の一時サーバーはソケットだけで受け付けるため、TCP で応答するまで待つ
+    if docker exec "$NAME" mysqladmin ping -h 127.0.0.1 -uroot -pmysql --silent >/dev/null 2>&1; then
+        break
+    fi
+    if [ "$i" -eq 60 ]; then
+        echo "[TEST] MySQL did not become ready" >&2
+        exit 1
+    fi
+    sleep 2
+done
+
+if [ $# -eq 0 ]; then
+    set -- ./...
+fi
+TEST_DSN="root:mysql@tcp(127.0.0.1:${PORT})/sample_db" go test -count=1 "$@"
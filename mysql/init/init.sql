@@ -9,6 +9,23 @@ SET character_set_connection = utf8mb4;
 SET character_set_results = utf8mb4;
 
 -- Products table with 6 searchable columns
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS store_stock;
+DROP TABLE IF EXISTS stores;
+DROP TABLE IF EXISTS flash_sale_items;
+DROP TABLE IF EXISTS flash_sales;
+DROP TABLE IF EXISTS alert_deliveries;
+DROP TABLE IF EXISTS alert_subscriptions;
+DROP TABLE IF EXISTS product_reviews;
+DROP TABLE IF EXISTS product_qa_votes;
+DROP TABLE IF EXISTS product_answers;
+DROP TABLE IF EXISTS product_questions;
+DROP TABLE IF EXISTS product_recommendations;
+DROP TABLE IF EXISTS product_views;
+DROP TABLE IF EXISTS product_supplier_info;
+DROP TABLE IF EXISTS product_search;
+DROP TABLE IF EXISTS product_revisions;
+DROP TABLE IF EXISTS product_skus;
 DROP TABLE IF EXISTS products;
 CREATE TABLE IF NOT EXISTS products (
     id INT AUTO_INCREMENT PRIMARY KEY,
@@ -18,7 +35,23 @@ CREATE TABLE IF NOT EXISTS products (
     model VARCHAR(100) NOT NULL,
     description TEXT NOT NULL,
     price DECIMAL(10, 2) NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    -- 在庫管理単位 (ERP 連携のキー)。空文字は未設定。一意性は product_skus で保証する
+    sku VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    -- レビューの集計 (product_reviews への投稿と同じトランザクションで更新する)。
+    -- 平均は合計から計算し、一覧ではそのまま返す
+    review_count INT NOT NULL DEFAULT 0,
+    rating_total INT NOT NULL DEFAULT 0,
+    rating_average DECIMAL(3, 2) NOT NULL DEFAULT 0,
+    -- オンライン販売の在庫数。引き当ては stock >= 数量 の条件付きの UPDATE で減らし、負にはしない
+    -- (店舗ごとの在庫は store_stock で別に持つ)
+    stock INT NOT NULL DEFAULT 0,
+    INDEX idx_products_sku (sku),
+    -- 一覧の絞り込み (category / brand / 価格帯) と並び替え (価格・登録日時)
+    INDEX idx_products_category_price (category, price),
+    INDEX idx_products_brand_price (brand, price),
+    INDEX idx_products_price (price),
+    INDEX idx_products_created_at (created_at)
 ) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
 
 -- Sample product data (適当なデータ)
@@ -48,3 +81,280 @@ INSERT INTO products (name, category, brand, model, description, price) VALUES
 ('Logitech MX Master 3S', 'マウス', 'Logitech', 'MX Master 3S', '高精度ワイヤレスマウス', 15800.00),
 ('HHKB Professional HYBRID Type-S', 'キーボード', 'PFU', 'PD-KB800WS', '静音設計プログラマー向けキーボード', 36300.00),
 ('Steam Deck', '携帯ゲーム機', 'Valve', '512GB', 'PC向けゲーム対応携帯機', 79800.00);
+
+UPDATE products SET sku = CONCAT('SKU-', LPAD(id, 6, '0'));
+
+-- SKU の一意性。products はパーティション化するとパーティションキーを含まない一意キーを持てないため、
+-- 別テーブルの主キーで重複を拒否する (トリガーで同期し、重複すると products への書き込みごと失敗する)
+CREATE TABLE IF NOT EXISTS product_skus (
+    sku VARCHAR(64) PRIMARY KEY,
+    product_id INT NOT NULL,
+    UNIQUE KEY uk_product_skus_product (product_id)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+INSERT INTO product_skus (sku, product_id)
+SELECT sku, id FROM products WHERE sku <> '';
+
+CREATE TRIGGER products_sku_ai AFTER INSERT ON products FOR EACH ROW
+    INSERT INTO product_skus (sku, product_id)
+    SELECT NEW.sku, NEW.id FROM DUAL WHERE NEW.sku <> '';
+
+CREATE TRIGGER products_sku_au_release AFTER UPDATE ON products FOR EACH ROW
+    DELETE FROM product_skus WHERE product_id = OLD.id AND NEW.sku <> OLD.sku;
+
+CREATE TRIGGER products_sku_au_claim AFTER UPDATE ON products FOR EACH ROW FOLLOWS products_sku_au_release
+    INSERT INTO product_skus (sku, product_id)
+    SELECT NEW.sku, NEW.id FROM DUAL WHERE NEW.sku <> '' AND NEW.sku <> OLD.sku;
+
+CREATE TRIGGER products_sku_ad AFTER DELETE ON products FOR EACH ROW
+    DELETE FROM product_skus WHERE product_id = OLD.id;
+
+-- 一覧・検索用の非正規化サマリーテーブル
+-- products への書き込みはトリガーで反映し、一覧と検索は幅の狭いこのテーブルを走査する
+CREATE TABLE IF NOT EXISTS product_search (
+    id INT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    brand VARCHAR(100) NOT NULL,
+    category_name VARCHAR(100) NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    popularity INT NOT NULL DEFAULT 0,
+    search_text TEXT NOT NULL,
+    -- 全文検索 (GET /api/products/search)。日本語は単語の区切りが無いため ngram で分割する
+    FULLTEXT INDEX ft_product_search_text (search_text) WITH PARSER ngram
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+INSERT INTO product_search (id, name, brand, category_name, price, search_text)
+SELECT id, name, brand, category, price, CONCAT_WS(' ', name, category, brand, model, description)
+FROM products;
+
+CREATE TRIGGER products_search_ai AFTER INSERT ON products FOR EACH ROW
+    INSERT INTO product_search (id, name, brand, category_name, price, search_text)
+    VALUES (NEW.id, NEW.name, NEW.brand, NEW.category, NEW.price,
+            CONCAT_WS(' ', NEW.name, NEW.category, NEW.brand, NEW.model, NEW.description));
+
+-- レビューの集計や在庫だけの更新では全文索引を作り直さない
+CREATE TRIGGER products_search_au AFTER UPDATE ON products FOR EACH ROW
+    UPDATE product_search
+    SET name = NEW.name,
+        brand = NEW.brand,
+        category_name = NEW.category,
+        price = NEW.price,
+        search_text = CONCAT_WS(' ', NEW.name, NEW.category, NEW.brand, NEW.model, NEW.description)
+    WHERE id = NEW.id
+      AND NOT (NEW.name <=> OLD.name AND NEW.category <=> OLD.category AND NEW.brand <=> OLD.brand
+               AND NEW.model <=> OLD.model AND NEW.description <=> OLD.description AND NEW.price <=> OLD.price);
+
+CREATE TRIGGER products_search_ad AFTER DELETE ON products FOR EACH ROW
+    DELETE FROM product_search WHERE id = OLD.id;
+
+-- 製品の変更履歴 (as_of を指定した製品詳細で、過去の時点の内容と価格を返すために使う)
+-- 行はその時点から次の行の valid_from までの内容を表す。削除は deleted = 1 の行として残す
+CREATE TABLE IF NOT EXISTS product_revisions (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    category VARCHAR(100) NOT NULL,
+    brand VARCHAR(100) NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    sku VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    deleted TINYINT(1) NOT NULL DEFAULT 0,
+    valid_from TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX idx_product_revisions_product (product_id, valid_from)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+INSERT INTO product_revisions (product_id, name, category, brand, model, description, price, sku, created_at, valid_from)
+SELECT id, name, category, brand, model, description, price, sku, created_at, created_at
+FROM products;
+
+CREATE TRIGGER products_history_ai AFTER INSERT ON products FOR EACH ROW
+    INSERT INTO product_revisions (product_id, name, category, brand, model, description, price, sku, created_at)
+    VALUES (NEW.id, NEW.name, NEW.category, NEW.brand, NEW.model, NEW.description, NEW.price, NEW.sku, NEW.created_at);
+
+-- 内容が変わらない更新 (同じ値での UPDATE) は履歴に残さない
+CREATE TRIGGER products_history_au AFTER UPDATE ON products FOR EACH ROW
+    INSERT INTO product_revisions (product_id, name, category, brand, model, description, price, sku, created_at)
+    SELECT NEW.id, NEW.name, NEW.category, NEW.brand, NEW.model, NEW.description, NEW.price, NEW.sku, NEW.created_at
+    FROM DUAL
+    WHERE NOT (NEW.name <=> OLD.name AND NEW.category <=> OLD.category AND NEW.brand <=> OLD.brand
+               AND NEW.model <=> OLD.model AND NEW.description <=> OLD.description AND NEW.price <=> OLD.price
+               AND NEW.sku <=> OLD.sku);
+
+CREATE TRIGGER products_history_ad AFTER DELETE ON products FOR EACH ROW
+    INSERT INTO product_revisions (product_id, name, category, brand, model, description, price, sku, created_at, deleted)
+    VALUES (OLD.id, OLD.name, OLD.category, OLD.brand, OLD.model, OLD.description, OLD.price, OLD.sku, OLD.created_at, 1);
+
+-- 仕入れ情報 (機密カラムはバックエンドで AES-GCM により暗号化して保存する)
+CREATE TABLE IF NOT EXISTS product_supplier_info (
+    product_id INT PRIMARY KEY,
+    supplier_cost VARBINARY(128) NOT NULL,
+    partner_contact VARBINARY(2048) NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- 製品詳細の閲覧履歴 (レコメンドの集計元)。visitor_id はフロントエンドが X-Visitor-ID で送る匿名の ID
+CREATE TABLE IF NOT EXISTS product_views (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    visitor_id VARCHAR(64) NOT NULL,
+    product_id INT NOT NULL,
+    viewed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_product_views_viewed_at (viewed_at, visitor_id, product_id)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- 事前計算した「この製品を見た人はこんな製品も見ています」(バックエンドのジョブが定期的に作り直す)
+CREATE TABLE IF NOT EXISTS product_recommendations (
+    product_id INT NOT NULL,
+    recommended_id INT NOT NULL,
+    score DOUBLE NOT NULL,
+    computed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (product_id, recommended_id),
+    INDEX idx_product_recommendations_score (product_id, score)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- 製品への質問 (Q&A)。投稿は pending で受け付け、管理者が approved にしたものだけを公開する
+CREATE TABLE IF NOT EXISTS product_questions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    author VARCHAR(50) NOT NULL,
+    body TEXT NOT NULL,
+    status ENUM('pending', 'approved', 'rejected') NOT NULL DEFAULT 'pending',
+    helpful_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    moderated_at TIMESTAMP NULL,
+    INDEX idx_product_questions_product (product_id, status, helpful_count, id),
+    INDEX idx_product_questions_status (status, id)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- 製品のレビュー。投稿はすぐに公開し、products の review_count / rating_average に集計する
+CREATE TABLE IF NOT EXISTS product_reviews (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    author VARCHAR(50) NOT NULL,
+    rating TINYINT NOT NULL,
+    title VARCHAR(100) NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_product_reviews_product (product_id, id)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- 質問への回答。出品者 (API キーのクライアント) と管理者だけが投稿できる
+CREATE TABLE IF NOT EXISTS product_answers (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    question_id INT NOT NULL,
+    author VARCHAR(50) NOT NULL,
+    role ENUM('seller', 'admin') NOT NULL,
+    body TEXT NOT NULL,
+    helpful_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_product_answers_question (question_id, id)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- 「役に立った」の投票。同じ閲覧者 (X-Visitor-ID) からの重複は数えない
+CREATE TABLE IF NOT EXISTS product_qa_votes (
+    target ENUM('question', 'answer') NOT NULL,
+    target_id INT NOT NULL,
+    visitor_id VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (target, target_id, visitor_id)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- 再入荷・値下がりの通知の購読。条件を満たしたら一度だけ通知して fired にする
+CREATE TABLE IF NOT EXISTS alert_subscriptions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    product_id INT NOT NULL,
+    kind ENUM('restock', 'price_drop') NOT NULL,
+    channel ENUM('email', 'webhook') NOT NULL,
+    target VARCHAR(2048) NOT NULL,
+    -- 購読した時点の価格。target_price が無ければこれを下回ったら値下がりとみなす
+    baseline_price DECIMAL(10, 2) NOT NULL,
+    target_price DECIMAL(10, 2) NULL,
+    status ENUM('active', 'fired', 'unsubscribed') NOT NULL DEFAULT 'active',
+    unsubscribe_token CHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    fired_at TIMESTAMP NULL,
+    UNIQUE KEY uq_alert_subscriptions_token (unsubscribe_token),
+    INDEX idx_alert_subscriptions_product (product_id, kind, status),
+    INDEX idx_alert_subscriptions_status (status, kind)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- 通知の配信記録。失敗したら next_attempt_at まで待って再送する
+CREATE TABLE IF NOT EXISTS alert_deliveries (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    subscription_id INT NOT NULL,
+    event VARCHAR(32) NOT NULL,
+    price DECIMAL(10, 2) NOT NULL,
+    status ENUM('pending', 'sent', 'failed') NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(1000) NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP NULL,
+    INDEX idx_alert_deliveries_subscription (subscription_id),
+    INDEX idx_alert_deliveries_pending (status, next_attempt_at)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- タイムセール。starts_at から ends_at の間だけ対象製品をセール価格で販売する
+CREATE TABLE IF NOT EXISTS flash_sales (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_flash_sales_window (ends_at, starts_at)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- タイムセールの対象製品。sold はセール価格で販売した数で、stock_cap を超えない
+CREATE TABLE IF NOT EXISTS flash_sale_items (
+    sale_id INT NOT NULL,
+    product_id INT NOT NULL,
+    sale_price DECIMAL(10, 2) NOT NULL,
+    stock_cap INT NOT NULL,
+    sold INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (sale_id, product_id),
+    INDEX idx_flash_sale_items_product (product_id)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- 受け取りに対応した店舗。位置での検索は緯度・経度の範囲で候補を絞ってから距離を計算する
+CREATE TABLE IF NOT EXISTS stores (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    address VARCHAR(255) NOT NULL,
+    latitude DECIMAL(9, 6) NOT NULL,
+    longitude DECIMAL(9, 6) NOT NULL,
+    INDEX idx_stores_location (latitude, longitude)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- 店舗ごとの在庫数
+CREATE TABLE IF NOT EXISTS store_stock (
+    store_id INT NOT NULL,
+    product_id INT NOT NULL,
+    quantity INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    PRIMARY KEY (store_id, product_id),
+    INDEX idx_store_stock_product (product_id, store_id)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- JWT でログインするユーザー。パスワードは PBKDF2-HMAC-SHA256 のハッシュだけを保存する。
+-- admin は製品の登録・更新・削除ができる (ユーザーは管理用リスナーの POST /admin/users で登録する)
+CREATE TABLE IF NOT EXISTS users (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    username VARCHAR(64) NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    role ENUM('admin', 'user') NOT NULL DEFAULT 'user',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_users_username (username)
+) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;
+
+-- Sample store data (適当なデータ)
+INSERT INTO stores (name, address, latitude, longitude) VALUES
+('広島駅前店', '広島県広島市南区松原町', 34.397667, 132.475361),
+('紙屋町店', '広島県広島市中区基町', 34.395483, 132.457355),
+('東広島店', '広島県東広島市西条栄町', 34.431139, 132.743417),
+('福山店', '広島県福山市三之丸町', 34.489550, 133.362517),
+('岡山店', '岡山県岡山市北区駅元町', 34.666358, 133.918325);
+
+INSERT INTO store_stock (store_id, product_id, quantity)
+SELECT s.id, p.id, (s.id * 7 + p.id * 3) % 6
+FROM stores s CROSS JOIN products p;
@@ -0,0 +1,17 @@
+-- products の created_at による月次レンジパーティション化
+-- init.sql の後に実行される。既存環境には以下で適用する:
+--   docker compose exec -T db mysql -uroot -pmysql sample_db < mysql/init/products_partition.sql
+USE sample_db;
+
+-- パーティションキーは全ての一意キーに含める必要があるため、主キーを (id, created_at) にする
+ALTER TABLE products
+    MODIFY created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (id, created_at);
+
+-- 月ごとのパーティションはバックエンドのメンテナンスジョブが pmax を分割して作成する
+ALTER TABLE products
+    PARTITION BY RANGE (UNIX_TIMESTAMP(created_at)) (
+        PARTITION p_archive VALUES LESS THAN (UNIX_TIMESTAMP('2025-01-01 00:00:00')),
+        PARTITION pmax VALUES LESS THAN MAXVALUE
+    );